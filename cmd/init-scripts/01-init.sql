@@ -1,160 +0,0 @@
-CREATE TABLE users (
-    id SERIAL PRIMARY KEY,
-    name VARCHAR(255) NOT NULL,
-    username VARCHAR(150) UNIQUE NOT NULL,
-    avatar VARCHAR(300),
-    email VARCHAR(255) UNIQUE NOT NULL,
-    password VARCHAR(255) NOT NULL,
-    address TEXT,
-    designation VARCHAR(255),
-    phone VARCHAR(20),
-    otp VARCHAR(10),
-    otp_expiry TIMESTAMP,
-    is_verified BOOLEAN DEFAULT FALSE,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
-CREATE TABLE helps (
-    id SERIAL PRIMARY KEY,
-    receiver_id INTEGER NOT NULL,
-    sender_id INTEGER NOT NULL,
-    post_id INTEGER NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (receiver_id) REFERENCES users(id),
-    FOREIGN KEY (sender_id) REFERENCES users(id),
-    FOREIGN KEY (post_id) REFERENCES posts(id)
-);
-
-CREATE TABLE feedbacks (
-    id SERIAL PRIMARY KEY,
-    receiver_id INTEGER NOT NULL,
-    sender_id INTEGER NOT NULL,
-    content TEXT,
-    rating INTEGER NOT NULL CHECK(rating >= 1 AND rating <= 5),
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (receiver_id) REFERENCES users(id),
-    FOREIGN KEY (sender_id) REFERENCES users(id)
-);
-
-CREATE TABLE topics(
-    id SERIAL PRIMARY KEY,
-    title VARCHAR(255) NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
-CREATE TABLE badges (
-    id SERIAL PRIMARY KEY,
-    name VARCHAR(255) NOT NULL,
-    description TEXT,
-    image VARCHAR(255) NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
-);
-
--- Button Text - "Partner Up"
-
-CREATE TABLE partners (
-    id SERIAL PRIMARY KEY,
-    sender_id INTEGER NOT NULL,
-    receiver_id INTEGER NOT NULL,
-    status VARCHAR(15) NOT NULL CHECK(status IN ('pending', 'accepted', 'declined')),
-    sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (sender_id) REFERENCES users(id),
-    FOREIGN KEY (receiver_id) REFERENCES users(id),
-    UNIQUE (sender_id, receiver_id)
-);
-
-CREATE TABLE posts (
-    id BIGSERIAL PRIMARY KEY,
-    user_id INTEGER NOT NULL,
-    is_request BOOLEAN NOT NULL DEFAULT FALSE,
-    is_urgent BOOLEAN NOT NULL DEFAULT FALSE,
-    is_deleted BOOLEAN NOT NULL DEFAULT FALSE,
-    assigned_to INTEGER,
-    media_url TEXT,
-    media_type TEXT,
-    caption TEXT NOT NULL,
-    status VARCHAR(10) NOT NULL CHECK(status IN ('accepted', 'completed', 'pending')),
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (user_id) REFERENCES users(id),
-    FOREIGN KEY (assigned_to) REFERENCES users(id)
-);
-
-CREATE TABLE post_reactions (
-    id BIGSERIAL PRIMARY KEY,
-    post_id INTEGER NOT NULL,
-    user_id INTEGER NOT NULL,
-    is_useful BOOLEAN NOT NULL DEFAULT FALSE,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (post_id) REFERENCES posts(id),
-    FOREIGN KEY (user_id) REFERENCES users(id),
-    UNIQUE (post_id, user_id, reaction)
-);
-
-CREATE TABLE comments (
-    id BIGSERIAL PRIMARY KEY,
-    post_id INTEGER NOT NULL,
-    user_id INTEGER NOT NULL,
-    content TEXT NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (post_id) REFERENCES posts(id),
-    FOREIGN KEY (user_id) REFERENCES users(id)
-);
-
-CREATE TABLE conversations (
-    id SERIAL PRIMARY KEY,
-    user1_id INTEGER NOT NULL,
-    user2_id INTEGER NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (user1_id) REFERENCES users(id),
-    FOREIGN KEY (user2_id) REFERENCES users(id),
-    UNIQUE (user1_id, user2_id)
-);
-
-CREATE TABLE messages (
-    id SERIAL PRIMARY KEY,
-    conversation_id INTEGER NOT NULL,
-    sender_id INTEGER NOT NULL,
-    receiver_id INTEGER NOT NULL,
-    content TEXT NOT NULL,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (conversation_id) REFERENCES conversations(id),
-    FOREIGN KEY (sender_id) REFERENCES users(id)
-);
-
-CREATE TABLE notifications (
-    id SERIAL PRIMARY KEY,
-    user_id INTEGER NOT NULL,
-    type VARCHAR(255) NOT NULL,
-    message TEXT NOT NULL,
-    event_link VARCHAR(255) NOT NULL,
-    read BOOLEAN NOT NULL DEFAULT FALSE,
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (user_id) REFERENCES users(id)
-);
-
-CREATE TABLE businesses (
-    id SERIAL PRIMARY KEY,
-    owner_id INTEGER NOT NULL,
-    name VARCHAR(255) NOT NULL,
-    description TEXT,
-    category TEXT,
-    logo VARCHAR(500),
-    cover_image VARCHAR(500),
-    website VARCHAR(255),
-    contact VARCHAR(20),
-    location TEXT,
-    badges INTEGER[],
-    topics INTEGER[],
-    rating NUMERIC(10, 8) DEFAULT 0 CHECK(rating >= 0 AND rating <= 5),
-    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-    FOREIGN KEY (owner_id) REFERENCES users(id)
-);
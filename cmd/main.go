@@ -1,45 +1,99 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"os"
+	"time"
 
 	"cnep-backend/pkg/lib"
 	"cnep-backend/source/config"
 	"cnep-backend/source/database"
-	"cnep-backend/source/routes"
 	"cnep-backend/source/handlers"
+	"cnep-backend/source/middleware"
+	"cnep-backend/source/repository"
+	"cnep-backend/source/routes"
+	"cnep-backend/source/services"
 
 	"github.com/gofiber/fiber/v2"
 	"github.com/joho/godotenv"
 )
 
 func main() {
+	if err := run(); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// run starts the server, or the subcommand given in the arguments. Errors are returned
+// instead of exiting, so the deferred cleanup runs before main exits.
+func run() error {
 	// Load .env file
 	if err := godotenv.Load(); err != nil {
-		log.Fatal("Error loading .env file")
+		log.Println("No .env file loaded, using the environment")
 	}
 
 	// Initialize config
 	cfg := config.New()
 
-	// Initialize SMTP
-	lib.InitSMTP()
+	// Run a subcommand instead of the server, e.g. `main migrate up` or `main seed -reset`
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			return runMigrate(cfg, os.Args[2:])
+		case "seed":
+			return runSeed(cfg, os.Args[2:])
+		default:
+			return fmt.Errorf("unknown command %q", os.Args[1])
+		}
+	}
+
+	// Initialize mailer
+	if err := lib.InitMailer(cfg); err != nil {
+		return err
+	}
+	// Initialize file storage
+	if err := lib.InitStorage(cfg); err != nil {
+		return err
+	}
+	// Initialize OpenID Connect login providers
+	if err := lib.InitOIDC(cfg); err != nil {
+		return err
+	}
 	// Initialize Start time
 	handlers.StatusInit()
 
 	// Initialize database
-	database.Connect(cfg)
+	if err := database.Connect(cfg); err != nil {
+		return err
+	}
 	// Close database connection when the program exits
 	defer database.Close()
 
+	// Apply pending migrations, other instances starting at the same time wait for the lock
+	if cfg.DBAutoMigrate {
+		if _, err := database.MigrateUp(database.DB, 0); err != nil {
+			return fmt.Errorf("failed to apply migrations: %w", err)
+		}
+	}
+
+	// Deliver queued emails in the background
+	lib.StartOutbox(database.DB, time.Duration(cfg.OutboxInterval)*time.Second)
+
 	// Create Fiber app
-	app := fiber.New()
+	app := fiber.New(fiber.Config{
+		BodyLimit:    cfg.BodyLimit(),
+		ErrorHandler: middleware.ErrorHandler,
+	})
+
+	// Build the services on the database
+	svc := services.New(repository.NewStore(database.DB))
 
 	// Setup routes
-	routes.SetupRoutes(app)
+	routes.SetupRoutes(app, cfg, svc)
 
 	// Start server
 	port := cfg.ServerPort
 	log.Printf("Server is starting on port %s", port)
-	log.Fatal(app.Listen(":" + port))
+	return app.Listen(":" + port)
 }
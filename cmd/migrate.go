@@ -0,0 +1,113 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"cnep-backend/source/config"
+	"cnep-backend/source/database"
+)
+
+const migrateUsage = `Usage: main migrate <command>
+
+Commands:
+  up [n]         apply the next n pending migrations, or all of them
+  down [n]       revert the last n applied migrations, 1 by default
+  status         list the migrations and when they were applied
+  baseline [n]   record migrations up to n, 1 by default, as applied without running them
+  create <name>  write empty up and down files for a new migration`
+
+var errMigrateUsage = errors.New(migrateUsage)
+
+// runMigrate runs the migrate subcommand with the arguments following it.
+func runMigrate(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	// Creating a migration only writes files, it does not need the database
+	if args[0] == "create" {
+		if len(args) != 2 {
+			return errMigrateUsage
+		}
+		up, down, err := database.CreateMigration(database.MigrationsDir, args[1])
+		if err != nil {
+			return fmt.Errorf("could not create migration: %w", err)
+		}
+		log.Printf("Created %s and %s", up, down)
+		return nil
+	}
+
+	if err := database.Connect(cfg); err != nil {
+		return err
+	}
+	defer database.Close()
+
+	switch args[0] {
+	case "up":
+		steps, err := migrateSteps(args, 0)
+		if err != nil {
+			return err
+		}
+		applied, err := database.MigrateUp(database.DB, steps)
+		if err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+		log.Printf("Applied %d migrations", applied)
+	case "down":
+		steps, err := migrateSteps(args, 1)
+		if err != nil {
+			return err
+		}
+		reverted, err := database.MigrateDown(database.DB, steps)
+		if err != nil {
+			return fmt.Errorf("migration failed: %w", err)
+		}
+		log.Printf("Reverted %d migrations", reverted)
+	case "baseline":
+		version, err := migrateSteps(args, 1)
+		if err != nil {
+			return err
+		}
+		recorded, err := database.MigrateBaseline(database.DB, version)
+		if err != nil {
+			return fmt.Errorf("baseline failed: %w", err)
+		}
+		log.Printf("Recorded %d migrations as applied", recorded)
+	case "status":
+		states, err := database.MigrationStatus(database.DB)
+		if err != nil {
+			return fmt.Errorf("could not read migration status: %w", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, state := range states {
+			appliedAt := "pending"
+			if state.AppliedAt != nil {
+				appliedAt = state.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\n", state.Version, state.Name, appliedAt)
+		}
+		w.Flush()
+	default:
+		return errMigrateUsage
+	}
+	return nil
+}
+
+// migrateSteps reads the optional step count of up and down, or the version of baseline,
+// or returns the default.
+func migrateSteps(args []string, defaultSteps int) (int, error) {
+	if len(args) < 2 {
+		return defaultSteps, nil
+	}
+	steps, err := strconv.Atoi(args[1])
+	if err != nil || steps < 1 {
+		return 0, errMigrateUsage
+	}
+	return steps, nil
+}
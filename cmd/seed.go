@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"cnep-backend/source/config"
+	"cnep-backend/source/database"
+	"cnep-backend/source/seed"
+)
+
+// runSeed runs the seed subcommand, which migrates the database and fills it with generated data.
+// `main seed -reset` wipes and reloads a local database in one go.
+func runSeed(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("seed", flag.ExitOnError)
+	seedValue := flags.Int64("seed", 1, "seed of the generated data, the same seed generates the same data")
+	users := flags.Int("users", 50, "number of users to generate")
+	reset := flags.Bool("reset", false, "empty every table before seeding")
+	force := flags.Bool("force", false, "allow -reset on a database that is not on this machine")
+	flags.Parse(args)
+
+	// -reset deletes all data, so it only runs on a remote database when asked for explicitly
+	if *reset {
+		log.Printf("Resetting database %s on %s:%s as %s", cfg.DBName, cfg.DBHost, cfg.DBPort, cfg.DBUser)
+		if !isLocalHost(cfg.DBHost) && !*force && !cfg.SeedAllowReset {
+			return fmt.Errorf("refusing to reset %s, it is not a local database. Pass -force or set SEED_ALLOW_RESET=true to reset it anyway", cfg.DBHost)
+		}
+	}
+
+	if err := database.Connect(cfg); err != nil {
+		return err
+	}
+	defer database.Close()
+
+	if _, err := database.MigrateUp(database.DB, 0); err != nil {
+		return fmt.Errorf("migration failed: %w", err)
+	}
+
+	summary, err := seed.Run(database.DB, seed.Options{Seed: *seedValue, Users: *users, Reset: *reset})
+	if err != nil {
+		return fmt.Errorf("seeding failed: %w", err)
+	}
+
+	tables := make([]string, 0, len(summary))
+	for table := range summary {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	for _, table := range tables {
+		log.Printf("Seeded %d rows into %s", summary[table], table)
+	}
+	log.Printf("Sign in as admin@example.com, moderator@example.com or user3@example.com with password %s", seed.Password)
+	return nil
+}
+
+// isLocalHost reports whether the database host is this machine, by name, loopback address or Unix socket directory.
+func isLocalHost(host string) bool {
+	switch host {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return strings.HasPrefix(host, "/")
+}
@@ -0,0 +1,131 @@
+// Package apierror defines the error every API endpoint responds with.
+//
+// Services return these errors instead of writing responses, and the Fiber
+// ErrorHandler turns them into a JSON body:
+//
+//	{"error": "User not found", "code": "not_found", "details": {...}, "request_id": "..."}
+//
+// error is the human readable message, kept under that key so clients that only read it keep working.
+package apierror
+
+import (
+	"net/http"
+)
+
+// Codes of the errors, stable values clients can rely on
+const (
+	CodeBadRequest       = "bad_request"
+	CodeUnauthorized     = "unauthorized"
+	CodeForbidden        = "forbidden"
+	CodeNotFound         = "not_found"
+	CodeConflict         = "conflict"
+	CodePayloadTooLarge  = "payload_too_large"
+	CodeUnsupportedMedia = "unsupported_media_type"
+	CodeTooManyRequests  = "too_many_requests"
+	CodeInternal         = "internal_error"
+	CodeBadGateway       = "bad_gateway"
+	CodeUnavailable      = "service_unavailable"
+	CodeAccountLocked    = "account_locked"
+	CodeAccountSuspended = "account_suspended"
+	CodeEmailNotVerified = "email_not_verified"
+)
+
+// Error is an API error with its HTTP status.
+type Error struct {
+	Status    int                    `json:"-"`
+	Code      string                 `json:"code"`
+	Message   string                 `json:"error"`
+	Details   map[string]interface{} `json:"details,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// New returns an error with the given status, code and message.
+func New(status int, code, message string) *Error {
+	return &Error{Status: status, Code: code, Message: message}
+}
+
+// WithDetails returns a copy of the error with the given details.
+func (e *Error) WithDetails(details map[string]interface{}) *Error {
+	copied := *e
+	copied.Details = details
+	return &copied
+}
+
+// WithCode returns a copy of the error with a more specific code.
+func (e *Error) WithCode(code string) *Error {
+	copied := *e
+	copied.Code = code
+	return &copied
+}
+
+func BadRequest(message string) *Error {
+	return New(http.StatusBadRequest, CodeBadRequest, message)
+}
+
+func Unauthorized(message string) *Error {
+	return New(http.StatusUnauthorized, CodeUnauthorized, message)
+}
+
+func Forbidden(message string) *Error {
+	return New(http.StatusForbidden, CodeForbidden, message)
+}
+
+func NotFound(message string) *Error {
+	return New(http.StatusNotFound, CodeNotFound, message)
+}
+
+func Conflict(message string) *Error {
+	return New(http.StatusConflict, CodeConflict, message)
+}
+
+func PayloadTooLarge(message string) *Error {
+	return New(http.StatusRequestEntityTooLarge, CodePayloadTooLarge, message)
+}
+
+func UnsupportedMediaType(message string) *Error {
+	return New(http.StatusUnsupportedMediaType, CodeUnsupportedMedia, message)
+}
+
+func TooManyRequests(message string) *Error {
+	return New(http.StatusTooManyRequests, CodeTooManyRequests, message)
+}
+
+func Internal(message string) *Error {
+	return New(http.StatusInternalServerError, CodeInternal, message)
+}
+
+func BadGateway(message string) *Error {
+	return New(http.StatusBadGateway, CodeBadGateway, message)
+}
+
+// FromStatus returns an error for a bare HTTP status, used for errors raised by Fiber itself.
+func FromStatus(status int, message string) *Error {
+	code := CodeInternal
+	switch status {
+	case http.StatusBadRequest:
+		code = CodeBadRequest
+	case http.StatusUnauthorized:
+		code = CodeUnauthorized
+	case http.StatusForbidden:
+		code = CodeForbidden
+	case http.StatusNotFound, http.StatusMethodNotAllowed:
+		code = CodeNotFound
+	case http.StatusConflict:
+		code = CodeConflict
+	case http.StatusRequestEntityTooLarge:
+		code = CodePayloadTooLarge
+	case http.StatusUnsupportedMediaType:
+		code = CodeUnsupportedMedia
+	case http.StatusTooManyRequests:
+		code = CodeTooManyRequests
+	case http.StatusBadGateway:
+		code = CodeBadGateway
+	case http.StatusServiceUnavailable:
+		code = CodeUnavailable
+	}
+	return New(status, code, message)
+}
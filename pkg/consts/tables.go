@@ -2,16 +2,33 @@ package consts
 
 // Table Names
 const (
-	USERS_TABLE         = "users"
-	PARTNERS_TABLE      = "partners"
-	FEEDBACK_TABLE      = "feedbacks"
-	BADGES_TABLE        = "badges"
-	POSTS_TABLE         = "posts"
-	COMMENTS_TABLE      = "comments"
-	REACTIONS_TABLE     = "reactions"
-	NOTIFICATIONS_TABLE = "notifications"
-	MESSAGES_TABLE      = "messages"
-	CHAT_TABLE          = "chats"
+	USERS_TABLE            = "users"
+	PARTNERS_TABLE         = "partners"
+	FEEDBACK_TABLE         = "feedbacks"
+	BADGES_TABLE           = "badges"
+	POSTS_TABLE            = "posts"
+	COMMENTS_TABLE         = "comments"
+	REACTIONS_TABLE        = "reactions"
+	NOTIFICATIONS_TABLE    = "notifications"
+	MESSAGES_TABLE         = "messages"
+	CHAT_TABLE             = "chats"
+	SESSIONS_TABLE         = "sessions"
+	REFRESH_TOKENS_TABLE   = "refresh_tokens"
+	ONE_TIME_CODES_TABLE   = "one_time_codes"
+	EMAIL_OUTBOX_TABLE     = "email_outbox"
+	USER_IDENTITIES_TABLE  = "user_identities"
+	OAUTH_STATES_TABLE     = "oauth_states"
+	RECOVERY_CODES_TABLE   = "recovery_codes"
+	AUDIT_LOGS_TABLE       = "audit_logs"
+	BUSINESSES_TABLE       = "businesses"
+	MODERATION_CASES_TABLE = "moderation_cases"
+	REPORTS_TABLE          = "reports"
+	USER_BLOCKS_TABLE      = "user_blocks"
+	TOPICS_TABLE           = "topics"
+	HELPS_TABLE            = "helps"
+	CONVERSATIONS_TABLE    = "conversations"
+	PRODUCTS_TABLE         = "products"
+	PAGE_FOLLOWERS_TABLE   = "page_followers"
 )
 
 // Partner Status
@@ -21,9 +38,104 @@ const (
 	PARTNER_STATUS_DECLINED = "declined"
 )
 
+// Partner Relationships, as seen by one of the two users
+const (
+	PARTNER_RELATION_NONE             = "none"
+	PARTNER_RELATION_PARTNERS         = "partners"
+	PARTNER_RELATION_REQUEST_SENT     = "request_sent"
+	PARTNER_RELATION_REQUEST_RECEIVED = "request_received"
+	PARTNER_RELATION_DECLINED         = "declined"
+)
+
 // Post Status
 const (
 	POST_STATUS_PENDING   = "pending"
 	POST_STATUS_ACCEPTED  = "accepted"
 	POST_STATUS_COMPLETED = "completed"
 )
+
+// Upload Purposes
+const (
+	UPLOAD_PURPOSE_AVATAR  = "avatar"
+	UPLOAD_PURPOSE_POST    = "post"
+	UPLOAD_PURPOSE_LOGO    = "logo"
+	UPLOAD_PURPOSE_COVER   = "cover"
+	UPLOAD_PURPOSE_PRODUCT = "product"
+)
+
+// OTP Purposes
+const (
+	OTP_PURPOSE_SIGNUP       = "signup"
+	OTP_PURPOSE_RESET        = "reset"
+	OTP_PURPOSE_EMAIL_CHANGE = "email_change"
+)
+
+// Audit Log Actions
+const (
+	AUDIT_USER_VIEW        = "user.view"
+	AUDIT_USER_ROLE_CHANGE = "user.role_change"
+	AUDIT_USER_SUSPEND     = "user.suspend"
+	AUDIT_USER_BAN         = "user.ban"
+	AUDIT_USER_REINSTATE   = "user.reinstate"
+	AUDIT_USER_VERIFY      = "user.verify"
+	AUDIT_USER_RESEND_OTP  = "user.resend_otp"
+	AUDIT_USER_IMPERSONATE = "user.impersonate"
+	AUDIT_CASE_DISMISS     = "case.dismiss"
+	AUDIT_CASE_HIDE        = "case.hide"
+	AUDIT_CASE_SUSPEND     = "case.suspend"
+)
+
+// Audit Log Target Types
+const (
+	AUDIT_TARGET_USER = "user"
+	AUDIT_TARGET_CASE = "moderation_case"
+)
+
+// Report Target Types
+const (
+	REPORT_TARGET_POST     = "post"
+	REPORT_TARGET_COMMENT  = "comment"
+	REPORT_TARGET_MESSAGE  = "message"
+	REPORT_TARGET_BUSINESS = "business"
+	REPORT_TARGET_USER     = "user"
+)
+
+// Report Reasons
+const (
+	REPORT_REASON_SPAM           = "spam"
+	REPORT_REASON_HARASSMENT     = "harassment"
+	REPORT_REASON_HATE           = "hate_speech"
+	REPORT_REASON_VIOLENCE       = "violence"
+	REPORT_REASON_NUDITY         = "nudity"
+	REPORT_REASON_SCAM           = "scam"
+	REPORT_REASON_MISINFORMATION = "misinformation"
+	REPORT_REASON_IMPERSONATION  = "impersonation"
+	REPORT_REASON_OTHER          = "other"
+)
+
+// Moderation Case Status
+const (
+	CASE_STATUS_OPEN     = "open"
+	CASE_STATUS_RESOLVED = "resolved"
+)
+
+// Moderation Case Resolutions
+const (
+	CASE_RESOLUTION_DISMISSED = "dismissed"
+	CASE_RESOLUTION_HIDDEN    = "hidden"
+	CASE_RESOLUTION_SUSPENDED = "suspended"
+)
+
+// User Roles, ordered from least to most privileged
+const (
+	ROLE_USER      = "user"
+	ROLE_MODERATOR = "moderator"
+	ROLE_ADMIN     = "admin"
+)
+
+// Profile Field Visibility
+const (
+	VISIBILITY_PUBLIC   = "public"
+	VISIBILITY_PARTNERS = "partners"
+	VISIBILITY_PRIVATE  = "private"
+)
@@ -0,0 +1,70 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"cnep-backend/source/config"
+)
+
+// Mail is a single outgoing email. Text is the plaintext alternative of HTML.
+type Mail struct {
+	To      []string
+	Subject string
+	HTML    string
+	Text    string
+}
+
+// Mailer is implemented by every backend that can deliver emails.
+// Send returns ErrNoRecipients for a mail without recipients.
+type Mailer interface {
+	Send(ctx context.Context, mail Mail) error
+}
+
+var ErrNoRecipients = errors.New("mail has no recipients")
+
+var mailer Mailer
+
+// InitMailer sets up the mailer of the configured driver. It returns an error when the
+// driver is unknown or its settings are missing.
+func InitMailer(cfg *config.Config) error {
+	switch cfg.MailDriver {
+	case "smtp":
+		if cfg.SMTPHost == "" || cfg.MailFrom == "" {
+			return errors.New("SMTP host or from address not set")
+		}
+		if _, err := envelopeFrom(cfg.MailFrom); err != nil {
+			return err
+		}
+		mailer = NewSMTPMailer(cfg.SMTPHost, cfg.SMTPPort, cfg.SMTPUsername, cfg.SMTPPassword, cfg.SMTPTLS, cfg.MailFrom)
+	case "log":
+		mailer = NewLogMailer("", cfg.MailFrom)
+	case "file":
+		mailer = NewLogMailer(cfg.MailDir, cfg.MailFrom)
+	default:
+		return fmt.Errorf("unknown mail driver %q", cfg.MailDriver)
+	}
+
+	log.Printf("Mail driver %s initialized", cfg.MailDriver)
+	return nil
+}
+
+// SetMailer replaces the mailer, e.g. with one that keeps the emails in memory for tests.
+func SetMailer(m Mailer) {
+	mailer = m
+}
+
+// SendEmail delivers an email right away through the configured mailer.
+// Use QueueEmail for anything that must not fail because the mail server is unavailable.
+func SendEmail(mail Mail) error {
+	err := mailer.Send(context.Background(), mail)
+	if err != nil {
+		log.Printf("Error sending email: %v", err)
+		return err
+	}
+
+	log.Printf("Email sent successfully to %v", mail.To)
+	return nil
+}
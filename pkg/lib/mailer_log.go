@@ -0,0 +1,63 @@
+package lib
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// LogMailer is a development sink that never delivers anything.
+// Every email is written to the log and, when Dir is set, saved in Dir as an .eml file
+// that can be opened with any mail client.
+type LogMailer struct {
+	Dir  string
+	From string
+}
+
+func NewLogMailer(dir, from string) *LogMailer {
+	if from == "" {
+		from = "CNEP <no-reply@localhost>"
+	}
+	return &LogMailer{Dir: dir, From: from}
+}
+
+func (m *LogMailer) Send(ctx context.Context, mail Mail) error {
+	if len(mail.To) == 0 {
+		return ErrNoRecipients
+	}
+
+	body := mail.Text
+	if body == "" {
+		body = mail.HTML
+	}
+	log.Printf("[mail] To: %s | Subject: %s\n%s", strings.Join(mail.To, ", "), mail.Subject, body)
+
+	if m.Dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(m.Dir, 0o755); err != nil {
+		return err
+	}
+
+	msg, err := BuildMessage(m.From, mail, time.Now())
+	if err != nil {
+		return err
+	}
+
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102-150405.000000"), sanitizeFileName(mail.To[0]))
+	return os.WriteFile(filepath.Join(m.Dir, name), msg, 0o644)
+}
+
+func sanitizeFileName(s string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == '\\' || r == ':' {
+			return '_'
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,62 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLogMailer(t *testing.T) {
+	dir := t.TempDir()
+	mailer := NewLogMailer(dir, "")
+
+	tests := []struct {
+		name string
+		to   []string
+		err  error
+		// start of the name of the saved file, after the time
+		file string
+	}{
+		{"no recipients", nil, ErrNoRecipients, ""},
+		{"empty recipients", []string{}, ErrNoRecipients, ""},
+		{"recipient", []string{"user@example.com"}, nil, "user@example.com.eml"},
+		{"recipient with a path", []string{"../a/b:c@example.com", "other@example.com"}, nil, ".._a_b_c@example.com.eml"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			before, _ := os.ReadDir(dir)
+			err := mailer.Send(context.Background(), Mail{To: tt.to, Subject: "Hi", HTML: "<p>Hi</p>"})
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected %v, got %v", tt.err, err)
+			}
+
+			after, _ := os.ReadDir(dir)
+			if tt.file == "" {
+				if len(after) != len(before) {
+					t.Fatal("expected no file to be saved")
+				}
+				return
+			}
+			if len(after) != len(before)+1 {
+				t.Fatalf("expected one more file, got %d files", len(after))
+			}
+
+			var saved string
+			for _, entry := range after {
+				if strings.HasSuffix(entry.Name(), "-"+tt.file) {
+					saved = entry.Name()
+				}
+			}
+			if saved == "" {
+				t.Fatalf("expected a file ending in %s in %v", tt.file, after)
+			}
+			if content, err := os.ReadFile(filepath.Join(dir, saved)); err != nil || !strings.Contains(string(content), "Subject: Hi") {
+				t.Fatalf("expected the saved message, got %q (%v)", content, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package lib
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"time"
+)
+
+// SMTPMailer delivers emails through an SMTP server.
+// TLS is one of "starttls" (upgrade a plain connection, usually port 587),
+// "tls" (implicit TLS, usually port 465) or "none".
+type SMTPMailer struct {
+	Host     string
+	Port     string
+	Username string
+	Password string
+	TLS      string
+	From     string
+}
+
+func NewSMTPMailer(host, port, username, password, tlsMode, from string) *SMTPMailer {
+	return &SMTPMailer{
+		Host:     host,
+		Port:     port,
+		Username: username,
+		Password: password,
+		TLS:      tlsMode,
+		From:     from,
+	}
+}
+
+func (m *SMTPMailer) Send(ctx context.Context, mail Mail) error {
+	if len(mail.To) == 0 {
+		return ErrNoRecipients
+	}
+	from, err := envelopeFrom(m.From)
+	if err != nil {
+		return err
+	}
+
+	addr := net.JoinHostPort(m.Host, m.Port)
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	tlsConfig := &tls.Config{ServerName: m.Host}
+
+	var conn net.Conn
+	if m.TLS == "tls" {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return err
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	client, err := smtp.NewClient(conn, m.Host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+
+	if m.TLS == "starttls" {
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+
+	if m.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.Username, m.Password, m.Host)); err != nil {
+			return err
+		}
+	}
+
+	if err := client.Mail(from); err != nil {
+		return err
+	}
+	for _, to := range mail.To {
+		if err := client.Rcpt(to); err != nil {
+			return err
+		}
+	}
+
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	msg, err := BuildMessage(m.From, mail, time.Now())
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+
+	return client.Quit()
+}
+
+// envelopeFrom returns the bare address of a from header such as "CNEP <no-reply@example.com>",
+// which is the only form the MAIL command accepts.
+func envelopeFrom(from string) (string, error) {
+	addr, err := mail.ParseAddress(from)
+	if err != nil {
+		return "", fmt.Errorf("invalid from address %q: %w", from, err)
+	}
+	return addr.Address, nil
+}
@@ -0,0 +1,103 @@
+package lib
+
+import (
+	"context"
+	"net"
+	"net/textproto"
+	"strings"
+	"testing"
+)
+
+// serveSMTP accepts one plain SMTP session on a local port and sends the commands it
+// received to the returned channel once the session ends.
+func serveSMTP(t *testing.T) (host, port string, commands <-chan []string) {
+	t.Helper()
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		var lines []string
+		defer func() { received <- lines }()
+
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		text := textproto.NewConn(conn)
+		text.PrintfLine("220 localhost ESMTP")
+		for {
+			line, err := text.ReadLine()
+			if err != nil {
+				return
+			}
+			lines = append(lines, line)
+
+			switch command := strings.ToUpper(strings.Fields(line + " ")[0]); command {
+			case "EHLO", "HELO", "MAIL", "RCPT":
+				text.PrintfLine("250 OK")
+			case "DATA":
+				text.PrintfLine("354 Go ahead")
+				if _, err := text.ReadDotLines(); err != nil {
+					return
+				}
+				text.PrintfLine("250 OK")
+			case "QUIT":
+				text.PrintfLine("221 Bye")
+				return
+			default:
+				text.PrintfLine("502 Not implemented")
+			}
+		}
+	}()
+
+	host, port, _ = net.SplitHostPort(ln.Addr().String())
+	return host, port, received
+}
+
+func TestSMTPMailerEnvelopeFrom(t *testing.T) {
+	tests := []struct {
+		from string
+		mail string
+	}{
+		{"no-reply@example.com", "MAIL FROM:<no-reply@example.com>"},
+		{"CNEP <no-reply@example.com>", "MAIL FROM:<no-reply@example.com>"},
+		{`"CNEP, Support" <support@example.com>`, "MAIL FROM:<support@example.com>"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.from, func(t *testing.T) {
+			host, port, commands := serveSMTP(t)
+			mailer := NewSMTPMailer(host, port, "", "", "none", tt.from)
+
+			if err := mailer.Send(context.Background(), Mail{To: []string{"user@example.com"}, Subject: "Hi", Text: "Hi"}); err != nil {
+				t.Fatal(err)
+			}
+
+			lines := <-commands
+			found := false
+			for _, line := range lines {
+				if strings.HasPrefix(line, "MAIL ") {
+					found = strings.HasPrefix(line, tt.mail)
+					if !found {
+						t.Fatalf("expected %s, got %s", tt.mail, line)
+					}
+				}
+			}
+			if !found {
+				t.Fatalf("expected a MAIL command in %q", lines)
+			}
+		})
+	}
+
+	mailer := NewSMTPMailer("127.0.0.1", "1", "", "", "none", "not an address")
+	if err := mailer.Send(context.Background(), Mail{To: []string{"user@example.com"}}); err == nil {
+		t.Fatal("expected an invalid from address to be refused before connecting")
+	}
+}
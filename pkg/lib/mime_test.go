@@ -0,0 +1,121 @@
+package lib
+
+import (
+	"bytes"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBuildMessage(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+	long := strings.Repeat("A line longer than a quoted-printable line may be. ", 5)
+
+	tests := []struct {
+		name string
+		mail Mail
+		// content types of the parts, least preferred first
+		parts []string
+	}{
+		{"html and text", Mail{To: []string{"user@example.com"}, Subject: "Your code", HTML: "<p>Code: 1234</p>", Text: "Code: 1234"},
+			[]string{"text/plain", "text/html"}},
+		{"html only", Mail{To: []string{"user@example.com"}, Subject: "Your code", HTML: "<p>Code: 1234</p>"},
+			[]string{"text/html"}},
+		{"several recipients", Mail{To: []string{"a@example.com", "b@example.com"}, Subject: "Hi", HTML: "<p>Hi</p>", Text: "Hi"},
+			[]string{"text/plain", "text/html"}},
+		{"non-ASCII and long lines", Mail{To: []string{"user@example.com"}, Subject: "Código de verificación ✓", HTML: "<p>" + long + "</p>", Text: "Olá, " + long},
+			[]string{"text/plain", "text/html"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := BuildMessage(`"CNEP" <no-reply@cnep.example.com>`, tt.mail, now)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, line := range strings.Split(string(raw), "\r\n") {
+				if len(line) > 998 {
+					t.Fatalf("expected lines of at most 998 characters, got %d", len(line))
+				}
+			}
+
+			msg, err := mail.ReadMessage(bytes.NewReader(raw))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+			if err != nil || subject != tt.mail.Subject {
+				t.Fatalf("expected subject %q, got %q (%v)", tt.mail.Subject, subject, err)
+			}
+			to, err := msg.Header.AddressList("To")
+			if err != nil || len(to) != len(tt.mail.To) {
+				t.Fatalf("expected %d recipients, got %v (%v)", len(tt.mail.To), to, err)
+			}
+			if date, err := msg.Header.Date(); err != nil || !date.Equal(now) {
+				t.Fatalf("expected date %v, got %v (%v)", now, date, err)
+			}
+			if id := msg.Header.Get("Message-ID"); !strings.HasPrefix(id, "<") || !strings.HasSuffix(id, "@cnep.example.com>") {
+				t.Fatalf("expected a message ID in the domain of the sender, got %q", id)
+			}
+
+			mediaType, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+			if err != nil || mediaType != "multipart/alternative" {
+				t.Fatalf("expected a multipart/alternative body, got %q (%v)", mediaType, err)
+			}
+
+			reader := multipart.NewReader(msg.Body, params["boundary"])
+			for i, want := range tt.parts {
+				// The reader decodes quoted-printable parts
+				part, err := reader.NextPart()
+				if err != nil {
+					t.Fatalf("part %d: %v", i, err)
+				}
+				if contentType, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type")); contentType != want {
+					t.Fatalf("part %d: expected %s, got %s", i, want, contentType)
+				}
+
+				content, err := io.ReadAll(part)
+				if err != nil {
+					t.Fatal(err)
+				}
+				wantContent := tt.mail.HTML
+				if want == "text/plain" {
+					wantContent = tt.mail.Text
+				}
+				if string(content) != wantContent {
+					t.Fatalf("part %d: expected %q, got %q", i, wantContent, content)
+				}
+			}
+			if _, err := reader.NextPart(); err != io.EOF {
+				t.Fatalf("expected %d parts, got more (%v)", len(tt.parts), err)
+			}
+		})
+	}
+}
+
+func TestMessageIDIsUnique(t *testing.T) {
+	tests := []struct {
+		from   string
+		domain string
+	}{
+		{"no-reply@cnep.example.com", "cnep.example.com"},
+		{`"CNEP" <no-reply@cnep.example.com>`, "cnep.example.com"},
+		{"", "localhost"},
+		{"not an address", "localhost"},
+	}
+
+	for _, tt := range tests {
+		first, second := messageID(tt.from), messageID(tt.from)
+		if first == second {
+			t.Fatalf("expected unique message IDs, got %s twice", first)
+		}
+		if !strings.HasSuffix(first, "@"+tt.domain+">") {
+			t.Fatalf("expected a message ID in %s for %q, got %s", tt.domain, tt.from, first)
+		}
+	}
+}
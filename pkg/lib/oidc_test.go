@@ -0,0 +1,239 @@
+package lib
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testClientID = "test-client"
+
+// fakeIssuer is an OpenID Connect provider serving its discovery document, its key set
+// and a token endpoint that answers every code with the ID token set by the test.
+type fakeIssuer struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	keys      map[string]*ecdsa.PrivateKey
+	idToken   string
+	jwksFetch int
+}
+
+func newFakeIssuer(t *testing.T) *fakeIssuer {
+	t.Helper()
+
+	f := &fakeIssuer{keys: map[string]*ecdsa.PrivateKey{}}
+	f.addKey(t, "key-1")
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		json.NewEncoder(w).Encode(oidcDiscovery{
+			Issuer:                f.URL,
+			AuthorizationEndpoint: f.URL + "/authorize",
+			TokenEndpoint:         f.URL + "/token",
+			JWKSURI:               f.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		f.mu.Lock()
+		defer f.mu.Unlock()
+
+		f.jwksFetch++
+		var jwks struct {
+			Keys []jsonWebKey `json:"keys"`
+		}
+		for kid, key := range f.keys {
+			jwks.Keys = append(jwks.Keys, jsonWebKey{
+				Kty: "EC",
+				Kid: kid,
+				Use: "sig",
+				Crv: "P-256",
+				X:   base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+				Y:   base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+			})
+		}
+		json.NewEncoder(w).Encode(jwks)
+	})
+	mux.HandleFunc("/token", func(w http.ResponseWriter, r *http.Request) {
+		if r.PostFormValue("grant_type") != "authorization_code" || r.PostFormValue("code_verifier") == "" {
+			http.Error(w, `{"error":"invalid_request"}`, http.StatusBadRequest)
+			return
+		}
+
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		json.NewEncoder(w).Encode(map[string]string{"id_token": f.idToken})
+	})
+
+	f.Server = httptest.NewServer(mux)
+	t.Cleanup(f.Close)
+	return f
+}
+
+// addKey adds a signing key to the key set of the issuer.
+func (f *fakeIssuer) addKey(t *testing.T, kid string) {
+	t.Helper()
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.mu.Lock()
+	f.keys[kid] = key
+	f.mu.Unlock()
+}
+
+// claims returns the claims of a valid ID token for the nonce.
+func (f *fakeIssuer) claims(nonce string) jwt.MapClaims {
+	return jwt.MapClaims{
+		"iss":            f.URL,
+		"aud":            testClientID,
+		"sub":            "subject-1",
+		"iat":            time.Now().Unix(),
+		"exp":            time.Now().Add(time.Hour).Unix(),
+		"nonce":          nonce,
+		"email":          "User@Example.com",
+		"email_verified": true,
+		"name":           "Test User",
+	}
+}
+
+// issue signs the claims with the key kid, or with a key the issuer does not publish
+// when kid is unknown, and makes it the ID token of the next exchange.
+func (f *fakeIssuer) issue(t *testing.T, kid string, claims jwt.MapClaims) {
+	t.Helper()
+
+	f.mu.Lock()
+	key, ok := f.keys[kid]
+	f.mu.Unlock()
+	if !ok {
+		var err error
+		if key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	f.mu.Lock()
+	f.idToken = signed
+	f.mu.Unlock()
+}
+
+// fetches returns how often the key set was fetched.
+func (f *fakeIssuer) fetches() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.jwksFetch
+}
+
+func (f *fakeIssuer) provider() *OIDCProvider {
+	return NewOIDCProvider("test", f.URL, testClientID, "", "https://app.example.com/callback")
+}
+
+func TestOIDCExchange(t *testing.T) {
+	const nonce = "nonce-1"
+
+	tests := []struct {
+		name  string
+		kid   string
+		edit  func(claims jwt.MapClaims)
+		valid bool
+	}{
+		{"valid token", "key-1", nil, true},
+		{"bad nonce", "key-1", func(claims jwt.MapClaims) { claims["nonce"] = "other-nonce" }, false},
+		{"no nonce", "key-1", func(claims jwt.MapClaims) { delete(claims, "nonce") }, false},
+		{"bad audience", "key-1", func(claims jwt.MapClaims) { claims["aud"] = "other-client" }, false},
+		{"bad issuer", "key-1", func(claims jwt.MapClaims) { claims["iss"] = "https://issuer.example.com" }, false},
+		{"expired", "key-1", func(claims jwt.MapClaims) { claims["exp"] = time.Now().Add(-2 * time.Minute).Unix() }, false},
+		{"no expiry", "key-1", func(claims jwt.MapClaims) { delete(claims, "exp") }, false},
+		{"no subject", "key-1", func(claims jwt.MapClaims) { delete(claims, "sub") }, false},
+		{"unknown key", "key-2", nil, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newFakeIssuer(t)
+			claims := issuer.claims(nonce)
+			if tt.edit != nil {
+				tt.edit(claims)
+			}
+			issuer.issue(t, tt.kid, claims)
+
+			identity, err := issuer.provider().Exchange(context.Background(), "code", "verifier", nonce)
+			if !tt.valid {
+				if !errors.Is(err, ErrInvalidIDToken) {
+					t.Fatalf("expected an invalid id token, got %+v (%v)", identity, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := ExternalIdentity{Provider: "test", Subject: "subject-1", Email: "user@example.com", EmailVerified: true, Name: "Test User"}
+			if *identity != want {
+				t.Fatalf("expected %+v, got %+v", want, *identity)
+			}
+		})
+	}
+}
+
+func TestOIDCKeyRefetch(t *testing.T) {
+	const nonce = "nonce-1"
+	issuer := newFakeIssuer(t)
+	provider := issuer.provider()
+
+	exchange := func(kid string) error {
+		issuer.issue(t, kid, issuer.claims(nonce))
+		_, err := provider.Exchange(context.Background(), "code", "verifier", nonce)
+		return err
+	}
+
+	if err := exchange("key-1"); err != nil {
+		t.Fatal(err)
+	}
+	if fetches := issuer.fetches(); fetches != 1 {
+		t.Fatalf("expected the key set to be fetched once, got %d", fetches)
+	}
+
+	// Tokens with unknown key ids do not refetch the key set within a minute
+	for range 3 {
+		if err := exchange("made-up"); !errors.Is(err, ErrInvalidIDToken) {
+			t.Fatalf("expected an unknown key to be refused, got %v", err)
+		}
+	}
+	if fetches := issuer.fetches(); fetches != 1 {
+		t.Fatalf("expected no refetch within a minute, got %d fetches", fetches)
+	}
+
+	// A minute later a rotated key is picked up by a single refetch
+	issuer.addKey(t, "key-2")
+	provider.mu.Lock()
+	provider.fetchedAt = provider.fetchedAt.Add(-jwksRefetchInterval)
+	provider.mu.Unlock()
+	for range 2 {
+		if err := exchange("key-2"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if fetches := issuer.fetches(); fetches != 2 {
+		t.Fatalf("expected one refetch for the rotated key, got %d fetches", fetches)
+	}
+}
@@ -0,0 +1,141 @@
+package lib
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"cnep-backend/pkg/consts"
+	"cnep-backend/source/models"
+
+	"gorm.io/gorm"
+)
+
+const (
+	outboxBatchSize   = 20
+	outboxMaxAttempts = 8
+	outboxLease       = 5 * time.Minute
+	outboxSendTimeout = time.Minute
+)
+
+var (
+	outboxDB   *gorm.DB
+	outboxWake = make(chan struct{}, 1)
+)
+
+// StartOutbox starts the worker that delivers queued emails every interval.
+// Several server instances can run it at once, rows are claimed with SKIP LOCKED.
+func StartOutbox(db *gorm.DB, interval time.Duration) {
+	outboxDB = db
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			processOutbox()
+
+			select {
+			case <-ticker.C:
+			case <-outboxWake:
+			}
+		}
+	}()
+
+	log.Println("Email outbox worker started")
+}
+
+// QueueEmail stores the email in the outbox to be delivered by the worker.
+// It only fails if the email cannot be stored or has no recipients, never because the mail server is down.
+func QueueEmail(mail Mail) error {
+	if len(mail.To) == 0 {
+		return ErrNoRecipients
+	}
+
+	if outboxDB == nil {
+		// No outbox configured, fall back to sending right away
+		return SendEmail(mail)
+	}
+
+	email := models.OutboxEmail{
+		Recipients:    mail.To,
+		Subject:       mail.Subject,
+		Body:          mail.HTML,
+		TextBody:      mail.Text,
+		NextAttemptAt: time.Now(),
+	}
+	if err := outboxDB.Table(consts.EMAIL_OUTBOX_TABLE).Create(&email).Error; err != nil {
+		log.Printf("Error queueing email: %v", err)
+		return err
+	}
+
+	// Deliver right away instead of waiting for the next tick
+	select {
+	case outboxWake <- struct{}{}:
+	default:
+	}
+
+	return nil
+}
+
+// processOutbox claims a batch of due emails and tries to deliver each of them.
+func processOutbox() {
+	var emails []models.OutboxEmail
+
+	// Claiming pushes next_attempt_at forward, so a crashed worker's batch is retried after the lease
+	err := outboxDB.Raw(`
+		UPDATE `+consts.EMAIL_OUTBOX_TABLE+` SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT id FROM `+consts.EMAIL_OUTBOX_TABLE+`
+			WHERE sent_at IS NULL AND attempts < ? AND next_attempt_at <= ?
+			ORDER BY id
+			LIMIT ?
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`,
+		time.Now().Add(outboxLease), outboxMaxAttempts, time.Now(), outboxBatchSize,
+	).Scan(&emails).Error
+	if err != nil {
+		log.Printf("Error claiming outbox emails: %v", err)
+		return
+	}
+
+	for _, email := range emails {
+		deliver(email)
+	}
+}
+
+func deliver(email models.OutboxEmail) {
+	ctx, cancel := context.WithTimeout(context.Background(), outboxSendTimeout)
+	defer cancel()
+
+	err := mailer.Send(ctx, Mail{To: email.Recipients, Subject: email.Subject, HTML: email.Body, Text: email.TextBody})
+
+	updates := map[string]interface{}{"attempts": email.Attempts + 1}
+	if err == nil {
+		updates["sent_at"] = time.Now()
+		updates["last_error"] = ""
+		log.Printf("Email %d sent successfully to %v", email.ID, email.Recipients)
+	} else {
+		updates["last_error"] = err.Error()
+		updates["next_attempt_at"] = time.Now().Add(outboxBackoff(email.Attempts + 1))
+		if email.Attempts+1 >= outboxMaxAttempts {
+			log.Printf("Giving up on email %d to %v: %v", email.ID, email.Recipients, err)
+		} else {
+			log.Printf("Error sending email %d, will retry: %v", email.ID, err)
+		}
+	}
+
+	if err := outboxDB.Table(consts.EMAIL_OUTBOX_TABLE).Where("id = ?", email.ID).Updates(updates).Error; err != nil {
+		log.Printf("Error updating outbox email %d: %v", email.ID, err)
+	}
+}
+
+// outboxBackoff doubles the wait after every failed attempt, starting at 30 seconds and capped at an hour.
+func outboxBackoff(attempts int) time.Duration {
+	wait := 30 * time.Second << (attempts - 1)
+	if wait > time.Hour || wait <= 0 {
+		return time.Hour
+	}
+	return wait
+}
@@ -0,0 +1,58 @@
+package lib
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+
+	"cnep-backend/source/config"
+)
+
+// Storage is implemented by every backend that uploaded files can be written to.
+// Keys are slash separated paths such as "avatar/12/3f9c1a.jpg".
+type Storage interface {
+	// Put writes the object and returns the public URL it can be fetched from.
+	Put(ctx context.Context, key string, body io.Reader, size int64, contentType string) (string, error)
+	// Delete removes the object. Deleting a missing object is not an error.
+	Delete(ctx context.Context, key string) error
+	// URL returns the public URL for the given key.
+	URL(key string) string
+}
+
+var storage Storage
+
+// InitStorage sets up the storage backend of the configured driver. It returns an error when
+// the driver is unknown or its settings are missing.
+func InitStorage(cfg *config.Config) error {
+	switch cfg.StorageDriver {
+	case "local":
+		storage = NewLocalStorage(cfg.StoragePath, cfg.StorageBaseURL)
+	case "s3":
+		if cfg.S3Endpoint == "" || cfg.S3Bucket == "" || cfg.S3AccessKey == "" || cfg.S3SecretKey == "" {
+			return errors.New("S3 storage information not set")
+		}
+		storage = NewS3Storage(cfg.S3Endpoint, cfg.S3Region, cfg.S3Bucket, cfg.S3AccessKey, cfg.S3SecretKey, cfg.S3PublicURL)
+	default:
+		return fmt.Errorf("unknown storage driver %q", cfg.StorageDriver)
+	}
+
+	log.Printf("Storage driver %s initialized", cfg.StorageDriver)
+	return nil
+}
+
+// GetStorage returns the storage backend configured by InitStorage.
+func GetStorage() Storage {
+	return storage
+}
+
+// KeyFromURL returns the storage key for a URL previously returned by the
+// configured backend. ok is false for URLs that point somewhere else.
+func KeyFromURL(url string) (key string, ok bool) {
+	if storage == nil {
+		return "", false
+	}
+	return strings.CutPrefix(url, storage.URL(""))
+}
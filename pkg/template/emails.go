@@ -0,0 +1,127 @@
+package template
+
+// Email names
+const (
+	OTPEmail           = "otp"
+	PasswordResetEmail = "password_reset"
+	EmailChangeEmail   = "email_change"
+	EmailChangedNotice = "email_changed_notice"
+)
+
+type OTPEmailData struct {
+	OTP string
+}
+
+type EmailChangedNoticeData struct {
+	NewEmail string
+}
+
+func init() {
+	// ==== English ====
+
+	Register(OTPEmail, "en", `Verification Code`, `{{define "content"}}
+    <p>Please verify your email using the following code</p>
+    <br>
+    <h2><strong>{{.OTP}}</strong></h2>
+    <br>
+    <p>This OTP will expire in 15 minutes. Please do not share this code with anyone.</p>
+    <p>If you didn't request this OTP, please ignore this email.</p>
+{{end}}`, `{{define "content"}}Please verify your email using the following code
+
+    {{.OTP}}
+
+This OTP will expire in 15 minutes. Please do not share this code with anyone.
+If you didn't request this OTP, please ignore this email.{{end}}`)
+
+	Register(PasswordResetEmail, "en", `Password Reset Code`, `{{define "content"}}
+    <p>We received a request to reset the password of your account. Use the following code to choose a new password</p>
+    <br>
+    <h2><strong>{{.OTP}}</strong></h2>
+    <br>
+    <p>This code will expire in 15 minutes and can only be used once. Please do not share this code with anyone.</p>
+    <p>If you didn't request a password reset, you can safely ignore this email, your password will not change.</p>
+{{end}}`, `{{define "content"}}We received a request to reset the password of your account. Use the following code to choose a new password
+
+    {{.OTP}}
+
+This code will expire in 15 minutes and can only be used once. Please do not share this code with anyone.
+If you didn't request a password reset, you can safely ignore this email, your password will not change.{{end}}`)
+
+	Register(EmailChangeEmail, "en", `Confirm Your New Email`, `{{define "content"}}
+    <p>Please confirm this as the new email of your account using the following code</p>
+    <br>
+    <h2><strong>{{.OTP}}</strong></h2>
+    <br>
+    <p>This OTP will expire in 15 minutes. Please do not share this code with anyone.</p>
+    <p>If you didn't request this change, please ignore this email.</p>
+{{end}}`, `{{define "content"}}Please confirm this as the new email of your account using the following code
+
+    {{.OTP}}
+
+This OTP will expire in 15 minutes. Please do not share this code with anyone.
+If you didn't request this change, please ignore this email.{{end}}`)
+
+	Register(EmailChangedNotice, "en", `Your Email Was Changed`, `{{define "content"}}
+    <p>The email of your account was changed to <strong>{{.NewEmail}}</strong>.</p>
+    <p>From now on you will need to use the new email to log in.</p>
+    <br>
+    <p>If you didn't make this change, please contact us immediately.</p>
+{{end}}`, `{{define "content"}}The email of your account was changed to {{.NewEmail}}.
+From now on you will need to use the new email to log in.
+
+If you didn't make this change, please contact us immediately.{{end}}`)
+
+	// ==== Hindi ====
+
+	Register(OTPEmail, "hi", `सत्यापन कोड`, `{{define "content"}}
+    <p>कृपया नीचे दिए गए कोड से अपना ईमेल सत्यापित करें</p>
+    <br>
+    <h2><strong>{{.OTP}}</strong></h2>
+    <br>
+    <p>यह OTP 15 मिनट में समाप्त हो जाएगा। कृपया यह कोड किसी के साथ साझा न करें।</p>
+    <p>यदि आपने यह OTP नहीं माँगा है, तो कृपया इस ईमेल को अनदेखा करें।</p>
+{{end}}`, `{{define "content"}}कृपया नीचे दिए गए कोड से अपना ईमेल सत्यापित करें
+
+    {{.OTP}}
+
+यह OTP 15 मिनट में समाप्त हो जाएगा। कृपया यह कोड किसी के साथ साझा न करें।
+यदि आपने यह OTP नहीं माँगा है, तो कृपया इस ईमेल को अनदेखा करें।{{end}}`)
+
+	Register(PasswordResetEmail, "hi", `पासवर्ड रीसेट कोड`, `{{define "content"}}
+    <p>हमें आपके खाते का पासवर्ड रीसेट करने का अनुरोध मिला है। नया पासवर्ड चुनने के लिए नीचे दिए गए कोड का उपयोग करें</p>
+    <br>
+    <h2><strong>{{.OTP}}</strong></h2>
+    <br>
+    <p>यह कोड 15 मिनट में समाप्त हो जाएगा और केवल एक बार उपयोग किया जा सकता है। कृपया यह कोड किसी के साथ साझा न करें।</p>
+    <p>यदि आपने पासवर्ड रीसेट का अनुरोध नहीं किया है, तो इस ईमेल को अनदेखा करें, आपका पासवर्ड नहीं बदलेगा।</p>
+{{end}}`, `{{define "content"}}हमें आपके खाते का पासवर्ड रीसेट करने का अनुरोध मिला है। नया पासवर्ड चुनने के लिए नीचे दिए गए कोड का उपयोग करें
+
+    {{.OTP}}
+
+यह कोड 15 मिनट में समाप्त हो जाएगा और केवल एक बार उपयोग किया जा सकता है। कृपया यह कोड किसी के साथ साझा न करें।
+यदि आपने पासवर्ड रीसेट का अनुरोध नहीं किया है, तो इस ईमेल को अनदेखा करें, आपका पासवर्ड नहीं बदलेगा।{{end}}`)
+
+	Register(EmailChangeEmail, "hi", `अपने नए ईमेल की पुष्टि करें`, `{{define "content"}}
+    <p>कृपया नीचे दिए गए कोड से इसे अपने खाते के नए ईमेल के रूप में पुष्टि करें</p>
+    <br>
+    <h2><strong>{{.OTP}}</strong></h2>
+    <br>
+    <p>यह OTP 15 मिनट में समाप्त हो जाएगा। कृपया यह कोड किसी के साथ साझा न करें।</p>
+    <p>यदि आपने यह बदलाव नहीं माँगा है, तो कृपया इस ईमेल को अनदेखा करें।</p>
+{{end}}`, `{{define "content"}}कृपया नीचे दिए गए कोड से इसे अपने खाते के नए ईमेल के रूप में पुष्टि करें
+
+    {{.OTP}}
+
+यह OTP 15 मिनट में समाप्त हो जाएगा। कृपया यह कोड किसी के साथ साझा न करें।
+यदि आपने यह बदलाव नहीं माँगा है, तो कृपया इस ईमेल को अनदेखा करें।{{end}}`)
+
+	Register(EmailChangedNotice, "hi", `आपका ईमेल बदल दिया गया है`, `{{define "content"}}
+    <p>आपके खाते का ईमेल <strong>{{.NewEmail}}</strong> में बदल दिया गया है।</p>
+    <p>अब से लॉग इन करने के लिए आपको नए ईमेल का उपयोग करना होगा।</p>
+    <br>
+    <p>यदि यह बदलाव आपने नहीं किया है, तो कृपया तुरंत हमसे संपर्क करें।</p>
+{{end}}`, `{{define "content"}}आपके खाते का ईमेल {{.NewEmail}} में बदल दिया गया है।
+अब से लॉग इन करने के लिए आपको नए ईमेल का उपयोग करना होगा।
+
+यदि यह बदलाव आपने नहीं किया है, तो कृपया तुरंत हमसे संपर्क करें।{{end}}`)
+}
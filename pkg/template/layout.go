@@ -0,0 +1,59 @@
+package template
+
+type layout struct {
+	html string
+	text string
+}
+
+// layouts holds the shared frame of every email by locale.
+// Email bodies fill in the "content" block.
+var layouts = map[string]layout{
+	"en": {
+		html: `<!DOCTYPE html>
+<html>
+<body>
+    <br>
+    <p>Hey,</p>
+    {{template "content" .}}
+    <br>
+    <p>This is an automated message, please do not reply.</p>
+    <p>Best Regards,</p>
+    <p>CNEP Team</p>
+</body>
+</html>
+`,
+		text: `Hey,
+
+{{template "content" .}}
+
+This is an automated message, please do not reply.
+
+Best Regards,
+CNEP Team
+`,
+	},
+	"hi": {
+		html: `<!DOCTYPE html>
+<html>
+<body>
+    <br>
+    <p>नमस्ते,</p>
+    {{template "content" .}}
+    <br>
+    <p>यह एक स्वचालित संदेश है, कृपया इसका उत्तर न दें।</p>
+    <p>धन्यवाद,</p>
+    <p>CNEP टीम</p>
+</body>
+</html>
+`,
+		text: `नमस्ते,
+
+{{template "content" .}}
+
+यह एक स्वचालित संदेश है, कृपया इसका उत्तर न दें।
+
+धन्यवाद,
+CNEP टीम
+`,
+	},
+}
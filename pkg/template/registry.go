@@ -0,0 +1,82 @@
+package template
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	texttemplate "text/template"
+)
+
+// DefaultLocale is used when an email has no variant for the requested locale.
+const DefaultLocale = "en"
+
+// Email is a rendered email ready to be sent.
+type Email struct {
+	Subject string
+	HTML    string
+	Text    string
+}
+
+type emailTemplate struct {
+	subject *texttemplate.Template
+	html    *htmltemplate.Template
+	text    *texttemplate.Template
+}
+
+// registry maps an email name to its variants by locale.
+var registry = map[string]map[string]*emailTemplate{}
+
+// Register adds a localized variant of an email. The html and text bodies only define
+// the content, they are rendered inside the shared layout of the locale.
+// It panics on invalid templates, since they are registered at startup.
+func Register(name, locale, subject, html, text string) {
+	layout, ok := layouts[locale]
+	if !ok {
+		panic(fmt.Sprintf("template: no layout for locale %q", locale))
+	}
+
+	tmpl := &emailTemplate{
+		subject: texttemplate.Must(texttemplate.New(name + ".subject").Parse(subject)),
+		html:    htmltemplate.Must(htmltemplate.Must(htmltemplate.New(name + ".html").Parse(layout.html)).Parse(html)),
+		text:    texttemplate.Must(texttemplate.Must(texttemplate.New(name + ".text").Parse(layout.text)).Parse(text)),
+	}
+
+	if registry[name] == nil {
+		registry[name] = map[string]*emailTemplate{}
+	}
+	registry[name][locale] = tmpl
+}
+
+// Render renders the email in the given locale, falling back to DefaultLocale.
+func Render(name, locale string, data interface{}) (*Email, error) {
+	variants, ok := registry[name]
+	if !ok {
+		return nil, fmt.Errorf("template: unknown email %q", name)
+	}
+
+	tmpl, ok := variants[locale]
+	if !ok {
+		if tmpl, ok = variants[DefaultLocale]; !ok {
+			return nil, fmt.Errorf("template: email %q has no %q variant", name, DefaultLocale)
+		}
+	}
+
+	var subject, html, text bytes.Buffer
+	if err := tmpl.subject.Execute(&subject, data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.html.Execute(&html, data); err != nil {
+		return nil, err
+	}
+	if err := tmpl.text.Execute(&text, data); err != nil {
+		return nil, err
+	}
+
+	return &Email{Subject: subject.String(), HTML: html.String(), Text: text.String()}, nil
+}
+
+// HasLocale reports whether emails can be rendered in the given locale.
+func HasLocale(locale string) bool {
+	_, ok := layouts[locale]
+	return ok
+}
@@ -1,9 +1,11 @@
 package template
 
-import "github.com/gofiber/fiber/v2"
+import (
+	"cnep-backend/pkg/apierror"
+
+	"github.com/gofiber/fiber/v2"
+)
 
 func Unauthenticated(c *fiber.Ctx) error {
-	return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-		"error": "Unauthorized: Missing or invalid Authorization header",
-	})
-}
\ No newline at end of file
+	return apierror.Unauthorized("Unauthorized: Missing or invalid Authorization header")
+}
@@ -0,0 +1,47 @@
+package utils
+
+import "strings"
+
+// DeviceFromUserAgent derives a short, human readable device description
+// such as "Chrome on Android" from a User-Agent header.
+func DeviceFromUserAgent(ua string) string {
+	if ua == "" {
+		return "Unknown device"
+	}
+
+	os := "Unknown OS"
+	switch {
+	case strings.Contains(ua, "iPhone"):
+		os = "iPhone"
+	case strings.Contains(ua, "iPad"):
+		os = "iPad"
+	case strings.Contains(ua, "Android"):
+		os = "Android"
+	case strings.Contains(ua, "Windows"):
+		os = "Windows"
+	case strings.Contains(ua, "Mac OS X") || strings.Contains(ua, "Macintosh"):
+		os = "macOS"
+	case strings.Contains(ua, "Linux"):
+		os = "Linux"
+	}
+
+	client := "Unknown client"
+	switch {
+	case strings.Contains(ua, "Edg/"):
+		client = "Edge"
+	case strings.Contains(ua, "OPR/"):
+		client = "Opera"
+	case strings.Contains(ua, "Firefox/"):
+		client = "Firefox"
+	case strings.Contains(ua, "Chrome/"):
+		client = "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		client = "Safari"
+	case strings.Contains(ua, "Dart/"):
+		client = "App"
+	case strings.Contains(ua, "okhttp") || strings.Contains(ua, "CFNetwork"):
+		client = "App"
+	}
+
+	return client + " on " + os
+}
@@ -0,0 +1,222 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"image/png"
+
+	xdraw "golang.org/x/image/draw"
+)
+
+// ImageVariant is a standard size every uploaded image is rendered to.
+// MaxSide bounds the longest side; smaller images are never upscaled.
+type ImageVariant struct {
+	Name    string
+	MaxSide int
+}
+
+var ImageVariants = []ImageVariant{
+	{Name: "thumb", MaxSide: 160},
+	{Name: "medium", MaxSide: 720},
+	{Name: "full", MaxSide: 2048},
+}
+
+// ProcessedImage is a re-encoded image variant ready to be stored.
+type ProcessedImage struct {
+	Variant     string
+	Data        []byte
+	ContentType string
+	Ext         string
+}
+
+const jpegQuality = 85
+
+// ProcessImage decodes an uploaded image, applies its EXIF orientation and renders
+// every ImageVariant. Re-encoding drops all metadata (EXIF, GPS, comments), so none
+// of it reaches the stored files.
+// PNG and GIF input is encoded as PNG to keep transparency, everything else as JPEG.
+// Animated GIFs are reduced to their first frame.
+func ProcessImage(data []byte, contentType string) ([]ProcessedImage, error) {
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+
+	if contentType == "image/jpeg" {
+		src = applyOrientation(src, jpegOrientation(data))
+	}
+
+	usePNG := contentType == "image/png" || contentType == "image/gif"
+
+	var variants []ProcessedImage
+	for _, variant := range ImageVariants {
+		img := resizeToFit(src, variant.MaxSide)
+
+		var buf bytes.Buffer
+		processed := ProcessedImage{Variant: variant.Name}
+		if usePNG {
+			err = png.Encode(&buf, img)
+			processed.ContentType, processed.Ext = "image/png", ".png"
+		} else {
+			err = jpeg.Encode(&buf, flatten(img), &jpeg.Options{Quality: jpegQuality})
+			processed.ContentType, processed.Ext = "image/jpeg", ".jpg"
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		processed.Data = buf.Bytes()
+		variants = append(variants, processed)
+	}
+
+	return variants, nil
+}
+
+// resizeToFit scales img down so that its longest side is at most maxSide.
+func resizeToFit(img image.Image, maxSide int) image.Image {
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w <= maxSide && h <= maxSide {
+		return img
+	}
+
+	if w >= h {
+		h = h * maxSide / w
+		w = maxSide
+	} else {
+		w = w * maxSide / h
+		h = maxSide
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, b, xdraw.Src, nil)
+	return dst
+}
+
+// flatten draws img over a white background since JPEG has no alpha channel.
+func flatten(img image.Image) image.Image {
+	b := img.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, b.Min, draw.Over)
+	return dst
+}
+
+// jpegOrientation returns the EXIF orientation tag of a JPEG file, or 1 if there is none.
+func jpegOrientation(data []byte) int {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return 1
+	}
+
+	i := 2
+	for i+4 <= len(data) {
+		if data[i] != 0xFF {
+			return 1
+		}
+		marker := data[i+1]
+		// Start of scan, image data follows
+		if marker == 0xDA {
+			return 1
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		end := i + 2 + size
+		if size < 2 || end > len(data) {
+			return 1
+		}
+		if marker == 0xE1 && size > 8 && string(data[i+4:i+10]) == "Exif\x00\x00" {
+			return exifOrientation(data[i+10 : end])
+		}
+		i = end
+	}
+
+	return 1
+}
+
+// exifOrientation reads the orientation tag (0x0112) from IFD0 of a TIFF structure.
+func exifOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 1
+	}
+
+	offset := int(order.Uint32(tiff[4:]))
+	if offset+2 > len(tiff) {
+		return 1
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	for n := 0; n < count; n++ {
+		entry := offset + 2 + n*12
+		if entry+12 > len(tiff) {
+			return 1
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			orientation := int(order.Uint16(tiff[entry+8:]))
+			if orientation < 1 || orientation > 8 {
+				return 1
+			}
+			return orientation
+		}
+	}
+
+	return 1
+}
+
+// applyOrientation rotates and flips img so that it displays upright without EXIF.
+func applyOrientation(img image.Image, orientation int) image.Image {
+	if orientation <= 1 || orientation > 8 {
+		return img
+	}
+
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+
+	// Orientations 5-8 swap width and height
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			var dx, dy int
+			switch orientation {
+			case 2: // flip horizontal
+				dx, dy = w-1-x, y
+			case 3: // rotate 180
+				dx, dy = w-1-x, h-1-y
+			case 4: // flip vertical
+				dx, dy = x, h-1-y
+			case 5: // transpose
+				dx, dy = y, x
+			case 6: // rotate 90 clockwise
+				dx, dy = h-1-y, x
+			case 7: // transverse
+				dx, dy = h-1-y, w-1-x
+			case 8: // rotate 90 counter-clockwise
+				dx, dy = y, w-1-x
+			}
+			dst.Set(dx, dy, img.At(b.Min.X+x, b.Min.Y+y))
+		}
+	}
+
+	return dst
+}
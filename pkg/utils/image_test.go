@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+var (
+	red   = color.NRGBA{R: 255, A: 255}
+	green = color.NRGBA{G: 255, A: 255}
+)
+
+// markedImage returns a w by h image, red in the top left corner and green in the top right one.
+func markedImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for i := range img.Pix {
+		img.Pix[i] = 255
+	}
+	img.Set(0, 0, red)
+	img.Set(w-1, 0, green)
+	return img
+}
+
+// withOrientation returns a JPEG of img with an EXIF segment holding the orientation tag.
+func withOrientation(t *testing.T, img image.Image, order binary.ByteOrder, orientation int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, nil); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+
+	// TIFF header, then IFD0 with a single SHORT entry
+	tiff := make([]byte, 26)
+	copy(tiff, "II")
+	if order == binary.BigEndian {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8)
+	order.PutUint16(tiff[8:], 1)
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3)
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], uint16(orientation))
+
+	segment := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(segment[2:], uint16(2+6+len(tiff)))
+	segment = append(append(segment, "Exif\x00\x00"...), tiff...)
+
+	return append(append(append([]byte{}, data[:2]...), segment...), data[2:]...)
+}
+
+func TestJPEGOrientation(t *testing.T) {
+	img := markedImage(3, 2)
+	var plain bytes.Buffer
+	if err := jpeg.Encode(&plain, img, nil); err != nil {
+		t.Fatal(err)
+	}
+
+	for orientation := 1; orientation <= 8; orientation++ {
+		for _, order := range []binary.ByteOrder{binary.LittleEndian, binary.BigEndian} {
+			if got := jpegOrientation(withOrientation(t, img, order, orientation)); got != orientation {
+				t.Fatalf("expected orientation %d in %v order, got %d", orientation, order, got)
+			}
+		}
+	}
+
+	tagged := withOrientation(t, img, binary.BigEndian, 6)
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"no EXIF", plain.Bytes()},
+		{"out of range", withOrientation(t, img, binary.BigEndian, 9)},
+		{"zero", withOrientation(t, img, binary.BigEndian, 0)},
+		{"not a JPEG", []byte("GIF89a")},
+		{"empty", nil},
+		{"truncated EXIF", tagged[:20]},
+		{"segment longer than the file", append(append([]byte{}, tagged[:4]...), 0xFF, 0xFF)},
+	}
+	for _, tt := range tests {
+		if got := jpegOrientation(tt.data); got != 1 {
+			t.Errorf("%s: expected orientation 1, got %d", tt.name, got)
+		}
+	}
+}
+
+func TestApplyOrientation(t *testing.T) {
+	// Where the red and green corners of a 3x2 image end up once displayed upright
+	tests := []struct {
+		orientation   int
+		width, height int
+		red, green    image.Point
+	}{
+		{1, 3, 2, image.Pt(0, 0), image.Pt(2, 0)},
+		{2, 3, 2, image.Pt(2, 0), image.Pt(0, 0)},
+		{3, 3, 2, image.Pt(2, 1), image.Pt(0, 1)},
+		{4, 3, 2, image.Pt(0, 1), image.Pt(2, 1)},
+		{5, 2, 3, image.Pt(0, 0), image.Pt(0, 2)},
+		{6, 2, 3, image.Pt(1, 0), image.Pt(1, 2)},
+		{7, 2, 3, image.Pt(1, 2), image.Pt(1, 0)},
+		{8, 2, 3, image.Pt(0, 2), image.Pt(0, 0)},
+	}
+
+	for _, tt := range tests {
+		img := applyOrientation(markedImage(3, 2), tt.orientation)
+
+		if b := img.Bounds(); b.Dx() != tt.width || b.Dy() != tt.height {
+			t.Fatalf("orientation %d: expected %dx%d, got %dx%d", tt.orientation, tt.width, tt.height, b.Dx(), b.Dy())
+		}
+		if got := color.NRGBAModel.Convert(img.At(tt.red.X, tt.red.Y)); got != red {
+			t.Fatalf("orientation %d: expected red at %v, got %v", tt.orientation, tt.red, got)
+		}
+		if got := color.NRGBAModel.Convert(img.At(tt.green.X, tt.green.Y)); got != green {
+			t.Fatalf("orientation %d: expected green at %v, got %v", tt.orientation, tt.green, got)
+		}
+	}
+}
+
+func TestProcessImage(t *testing.T) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, markedImage(100, 50)); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		data        []byte
+		contentType string
+		// size of every variant, smallest first
+		sizes []image.Point
+		ext   string
+	}{
+		{"rotated JPEG", withOrientation(t, markedImage(400, 200), binary.BigEndian, 6), "image/jpeg",
+			[]image.Point{{80, 160}, {200, 400}, {200, 400}}, ".jpg"},
+		{"small PNG is not upscaled", pngData.Bytes(), "image/png",
+			[]image.Point{{100, 50}, {100, 50}, {100, 50}}, ".png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			variants, err := ProcessImage(tt.data, tt.contentType)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(variants) != len(ImageVariants) {
+				t.Fatalf("expected %d variants, got %d", len(ImageVariants), len(variants))
+			}
+
+			for i, variant := range variants {
+				if variant.Variant != ImageVariants[i].Name || variant.Ext != tt.ext {
+					t.Fatalf("unexpected variant %s%s", variant.Variant, variant.Ext)
+				}
+				// Re-encoding drops the EXIF segment
+				if bytes.Contains(variant.Data, []byte("Exif")) {
+					t.Fatalf("expected the %s variant to have no EXIF data", variant.Variant)
+				}
+
+				img, _, err := image.Decode(bytes.NewReader(variant.Data))
+				if err != nil {
+					t.Fatal(err)
+				}
+				if size := img.Bounds().Size(); size != tt.sizes[i] {
+					t.Fatalf("expected the %s variant to be %v, got %v", variant.Variant, tt.sizes[i], size)
+				}
+			}
+		})
+	}
+
+	if _, err := ProcessImage([]byte("not an image"), "image/jpeg"); err == nil {
+		t.Fatal("expected data that is not an image to be refused")
+	}
+}
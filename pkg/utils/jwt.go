@@ -7,32 +7,110 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func GenerateJWT(userID uint) (string, error) {
+// TokenClaims are the claims carried by an access token.
+// Scope is empty for full access tokens. Partial tokens issued during a two-factor login
+// carry TwoFactorScope and are only accepted by the two-factor verify endpoint.
+// ImpersonatorID is the admin acting as the user in an impersonation session.
+type TokenClaims struct {
+	UserID         uint   `json:"user_id"`
+	SessionID      uint   `json:"sid"`
+	Role           string `json:"role"`
+	Scope          string `json:"scope,omitempty"`
+	ImpersonatorID uint   `json:"imp,omitempty"`
+	jwt.RegisteredClaims
+}
+
+const (
+	TwoFactorScope = "2fa"
+	// TwoFactorTokenTTL is how long the user has to enter their code after the password step
+	TwoFactorTokenTTL = 5 * time.Minute
+)
+
+// GenerateJWT issues a short-lived access token for the given user, session and role.
+// The lifetime is configured with ACCESS_TOKEN_TTL (minutes).
+func GenerateJWT(userID, sessionID uint, role string) (string, error) {
+	cfg := config.New()
+	claims := TokenClaims{
+		UserID:    userID,
+		SessionID: sessionID,
+		Role:      role,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL())),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(cfg.JWTSecret))
+}
+
+// GenerateImpersonationJWT issues an access token for an admin acting as the given user.
+// It expires like any access token and comes without a refresh token.
+func GenerateImpersonationJWT(userID, sessionID uint, role string, impersonatorID uint) (string, error) {
+	cfg := config.New()
+	claims := TokenClaims{
+		UserID:         userID,
+		SessionID:      sessionID,
+		Role:           role,
+		ImpersonatorID: impersonatorID,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(AccessTokenTTL())),
+		},
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	return token.SignedString([]byte(cfg.JWTSecret))
+}
+
+// GenerateTwoFactorToken issues the partial token returned by a password login when the
+// user has two-factor authentication enabled. It belongs to no session.
+func GenerateTwoFactorToken(userID uint) (string, error) {
 	cfg := config.New()
-	claims := jwt.MapClaims{
-		"user_id": userID,
-		"exp":     jwt.NewNumericDate(time.Now().Add(time.Hour * 24 * 30)), // Token expires in 30 days
+	claims := TokenClaims{
+		UserID: userID,
+		Scope:  TwoFactorScope,
+		RegisteredClaims: jwt.RegisteredClaims{
+			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(TwoFactorTokenTTL)),
+		},
 	}
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(cfg.JWTSecret))
 }
 
-func ValidateJWT(tokenString string) (uint, error) {
+// ValidateJWT validates a full access token. Partial two-factor tokens are rejected.
+func ValidateJWT(tokenString string) (*TokenClaims, error) {
+	return validateToken(tokenString, "")
+}
+
+// ValidateTwoFactorToken validates a partial token issued by GenerateTwoFactorToken.
+func ValidateTwoFactorToken(tokenString string) (*TokenClaims, error) {
+	return validateToken(tokenString, TwoFactorScope)
+}
+
+func validateToken(tokenString, scope string) (*TokenClaims, error) {
 	cfg := config.New()
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+	claims := &TokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
 		return []byte(cfg.JWTSecret), nil
-	})
+	}, jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}))
 
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	if claims, ok := token.Claims.(jwt.MapClaims); ok && token.Valid {
-		if userID, ok := claims["user_id"].(float64); ok {
-			return uint(userID), nil
-		}
-		return 0, jwt.ErrInvalidKey
+	if !token.Valid || claims.UserID == 0 || claims.Scope != scope {
+		return nil, jwt.ErrTokenInvalidClaims
 	}
 
-	return 0, jwt.ErrSignatureInvalid
+	return claims, nil
+}
+
+// AccessTokenTTL returns the configured lifetime of access tokens.
+func AccessTokenTTL() time.Duration {
+	return time.Duration(config.New().AccessTokenTTL) * time.Minute
+}
+
+// RefreshTokenTTL returns the configured lifetime of refresh tokens.
+func RefreshTokenTTL() time.Duration {
+	return time.Duration(config.New().RefreshTokenTTL) * 24 * time.Hour
 }
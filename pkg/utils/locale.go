@@ -0,0 +1,20 @@
+package utils
+
+import (
+	"strings"
+
+	"cnep-backend/pkg/template"
+)
+
+// LocaleFromHeader picks the first language of an Accept-Language header that emails
+// can be rendered in, or template.DefaultLocale.
+func LocaleFromHeader(header string) string {
+	for _, part := range strings.Split(header, ",") {
+		tag := strings.TrimSpace(strings.SplitN(part, ";", 2)[0])
+		lang := strings.ToLower(strings.SplitN(tag, "-", 2)[0])
+		if template.HasLocale(lang) {
+			return lang
+		}
+	}
+	return template.DefaultLocale
+}
@@ -15,16 +15,20 @@ import (
 //   - UserResponse: The converted UserResponse model
 func ConvertToUserResponse(user *models.User) models.UserResponse {
 	return models.UserResponse{
-		ID:                user.ID,
-		Name:              user.Name,
-		Username:          user.Username,
-		Avatar:            user.Avatar,
-		Email:             user.Email,
-		Phone:             user.Phone,
-		Address:           user.Address,
-		Designation:       user.Designation,
-		IsVerified:        user.IsVerified,
-		CreatedAt:         user.CreatedAt,
-		UpdatedAt:         user.UpdatedAt,
+		ID:               user.ID,
+		Name:             user.Name,
+		Username:         user.Username,
+		Avatar:           user.Avatar,
+		AvatarVariants:   user.AvatarVariants,
+		Email:            user.Email,
+		Phone:            user.Phone,
+		Address:          user.Address,
+		Designation:      user.Designation,
+		Locale:           user.Locale,
+		Role:             user.Role,
+		TwoFactorEnabled: user.TwoFactorEnabled,
+		IsVerified:       user.IsVerified,
+		CreatedAt:        user.CreatedAt,
+		UpdatedAt:        user.UpdatedAt,
 	}
 }
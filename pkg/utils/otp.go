@@ -1,29 +1,33 @@
 package utils
 
 import (
-	"math/rand"
-	"time"
-
-	"fmt"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"log"
+	"math/big"
 
+	"cnep-backend/pkg/consts"
 	"cnep-backend/pkg/lib"
 	"cnep-backend/pkg/template"
+	"cnep-backend/source/config"
 )
 
 const otpChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
 const otpLength = 8
 
+// GenerateOTP returns a random 8 character code with at least 3 digits,
+// drawn from crypto/rand.
 func GenerateOTP() string {
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
 	otp := make([]byte, otpLength)
 
 	// Ensure at least 3 digits
 	digitCount := 0
 	for digitCount < 3 {
-		pos := r.Intn(otpLength)
+		pos := randomInt(otpLength)
 		if otp[pos] == 0 { // Ensure we don't overwrite already set digits
-			otp[pos] = otpChars[r.Intn(10)+26] // Digits are in the last 10 characters of otpChars
+			otp[pos] = otpChars[randomInt(10)+26] // Digits are in the last 10 characters of otpChars
 			digitCount++
 		}
 	}
@@ -31,37 +35,84 @@ func GenerateOTP() string {
 	// Fill the remaining positions
 	for i := range otp {
 		if otp[i] == 0 {
-			otp[i] = otpChars[r.Intn(len(otpChars))]
+			otp[i] = otpChars[randomInt(len(otpChars))]
 		}
 	}
 
 	return string(otp)
 }
 
-func SendOTPEmail(to, otp string) error {
-	// Prepare the email data
-	data := template.OTPEmailData{
-		OTP: otp,
+// HashOTP returns the keyed hash of an OTP that is stored instead of the code itself.
+// Keying the hash with a key derived from the server secret keeps the small code space from
+// being brute-forced offline if the table leaks. The key is derived for this purpose only,
+// so the hashes are never made with the key that signs access tokens.
+func HashOTP(otp string) string {
+	return otpHMAC(otpHashKey(), otp)
+}
+
+// CheckOTPHash compares an OTP against a stored hash in constant time.
+func CheckOTPHash(otp, hash string) bool {
+	return hmac.Equal([]byte(HashOTP(otp)), []byte(hash))
+}
+
+func otpHashKey() []byte {
+	key := sha256.Sum256([]byte("otp-hash:" + config.New().JWTSecret))
+	return key[:]
+}
+
+func otpHMAC(key []byte, otp string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(otp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// randomInt returns a uniform random number in [0, n) from crypto/rand.
+func randomInt(n int) int {
+	v, err := rand.Int(rand.Reader, big.NewInt(int64(n)))
+	if err != nil {
+		log.Panic("crypto/rand failed: ", err)
 	}
+	return int(v.Int64())
+}
 
-	// Generate the email body
-	body, err := template.GenerateOTPEmail(data)
+// SendOTPEmail sends the OTP to the given address using the email template of the purpose
+// (consts.OTP_PURPOSE_SIGNUP, consts.OTP_PURPOSE_RESET or consts.OTP_PURPOSE_EMAIL_CHANGE)
+// in the locale of the user.
+func SendOTPEmail(to, otp, purpose, locale string) error {
+	name := template.OTPEmail
+	switch purpose {
+	case consts.OTP_PURPOSE_RESET:
+		name = template.PasswordResetEmail
+	case consts.OTP_PURPOSE_EMAIL_CHANGE:
+		name = template.EmailChangeEmail
+	}
+
+	return queueTemplateEmail(to, name, locale, template.OTPEmailData{OTP: otp})
+}
+
+// SendEmailChangedNotice tells the previous address of an account that its email was changed.
+func SendEmailChangedNotice(to, newEmail, locale string) error {
+	return queueTemplateEmail(to, template.EmailChangedNotice, locale, template.EmailChangedNoticeData{NewEmail: newEmail})
+}
+
+// queueTemplateEmail renders a registered email and queues it, the outbox worker delivers and retries it.
+func queueTemplateEmail(to, name, locale string, data interface{}) error {
+	email, err := template.Render(name, locale, data)
 	if err != nil {
-		log.Printf("Error generating OTP email: %v", err)
+		log.Printf("Error generating %s email: %v", name, err)
 		return err
 	}
 
-	// Set up email subject and recipient
-	subject := "Verification Code"
-	recipient := []string{to}
-
-	// Send the email
-	err = lib.SendEmail(recipient, subject, body)
+	err = lib.QueueEmail(lib.Mail{
+		To:      []string{to},
+		Subject: email.Subject,
+		HTML:    email.HTML,
+		Text:    email.Text,
+	})
 	if err != nil {
-		log.Printf("Error sending OTP email: %v", err)
+		log.Printf("Error queueing %s email: %v", name, err)
 		return err
 	}
 
-	fmt.Printf("OTP email sent successfully to %s\n", to)
 	return nil
 }
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateOTP(t *testing.T) {
+	seen := make(map[string]bool)
+	for range 1000 {
+		otp := GenerateOTP()
+		if len(otp) != otpLength {
+			t.Fatalf("expected %d characters, got %q", otpLength, otp)
+		}
+
+		digits := 0
+		for _, c := range otp {
+			if !strings.ContainsRune(otpChars, c) {
+				t.Fatalf("unexpected character %q in %q", c, otp)
+			}
+			if c >= '0' && c <= '9' {
+				digits++
+			}
+		}
+		if digits < 3 {
+			t.Fatalf("expected at least 3 digits in %q", otp)
+		}
+
+		if seen[otp] {
+			t.Fatalf("generated %q twice", otp)
+		}
+		seen[otp] = true
+	}
+}
+
+func TestCheckOTPHash(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	hash := HashOTP("ABC12345")
+
+	tests := []struct {
+		name  string
+		otp   string
+		hash  string
+		valid bool
+	}{
+		{"same code", "ABC12345", hash, true},
+		{"other code", "ABC12346", hash, false},
+		{"lower case code", "abc12345", hash, false},
+		{"empty code", "", hash, false},
+		{"hash made with the raw secret", "ABC12345", otpHMAC([]byte("test-secret"), "ABC12345"), false},
+		{"hash made with another secret", "ABC12345", otpHMAC([]byte("other-secret"), "ABC12345"), false},
+		{"truncated hash", "ABC12345", hash[:len(hash)-1], false},
+		{"empty hash", "ABC12345", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if valid := CheckOTPHash(tt.otp, tt.hash); valid != tt.valid {
+				t.Fatalf("expected %v, got %v", tt.valid, valid)
+			}
+		})
+	}
+}
+
+func TestHashOTP(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+	hash := HashOTP("ABC12345")
+
+	if len(hash) != 64 || strings.Contains(hash, "ABC12345") {
+		t.Fatalf("expected a hex SHA-256 HMAC, got %q", hash)
+	}
+	if again := HashOTP("ABC12345"); again != hash {
+		t.Fatalf("expected the same hash for the same code, got %q and %q", hash, again)
+	}
+	// The key is derived from the secret, so the hash is not the HMAC keyed with the secret itself
+	if hash == otpHMAC([]byte("test-secret"), "ABC12345") {
+		t.Fatal("expected the hash not to be keyed with the raw secret")
+	}
+
+	t.Setenv("JWT_SECRET", "other-secret")
+	if other := HashOTP("ABC12345"); other == hash {
+		t.Fatal("expected another secret to give another hash")
+	}
+}
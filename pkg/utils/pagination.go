@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"time"
+)
+
+const (
+	DefaultPageLimit = 20
+	MaxPageLimit     = 100
+)
+
+// Kinds of sort columns, used to decode the value stored in a cursor
+const (
+	SortTime   = "time"
+	SortString = "string"
+	SortInt    = "int"
+	SortFloat  = "float"
+)
+
+var (
+	ErrInvalidCursor = errors.New("cursor is malformed or was made with another sort")
+	ErrInvalidSort   = errors.New("unknown sort field")
+	ErrInvalidOrder  = errors.New("order must be asc or desc")
+	ErrInvalidLimit  = errors.New("limit must be between 1 and 100")
+)
+
+// SortField is a field a list can be sorted on.
+type SortField struct {
+	Name   string // name of the field in the sort query parameter
+	Column string // column to sort on, qualified when the query joins tables
+	Kind   string
+	Desc   bool // default direction
+}
+
+// ListSpec describes the sort fields and filters a list endpoint accepts.
+// The first sort field is the default one. Ties are broken on IDColumn,
+// which must be unique within the list.
+type ListSpec struct {
+	Sorts    []SortField
+	Filters  []string
+	IDColumn string
+}
+
+// Page is a page request of a list endpoint.
+type Page struct {
+	Limit    int
+	Sort     SortField
+	Desc     bool
+	IDColumn string
+	Cursor   *Cursor
+	Filters  map[string]string
+}
+
+// Cursor points after the last item of the previous page. It is tied to the sort
+// it was made with so it cannot be used with another one.
+type Cursor struct {
+	Sort  string `json:"s"`
+	Desc  bool   `json:"d"`
+	Value string `json:"v"`
+	ID    uint   `json:"id"`
+}
+
+/*
+The ParsePage function reads a page request from the query parameters of a list endpoint.
+
+Parameters:
+  - limit is the page size, 20 by default and at most 100.
+  - cursor is the next_cursor returned with the previous page.
+  - sort is one of the sort fields of the spec, and order is asc or desc.
+  - every filter of the spec is read from the parameter of the same name.
+*/
+func ParsePage(spec ListSpec, query func(key string) string) (Page, error) {
+	page := Page{
+		Limit:    DefaultPageLimit,
+		Sort:     spec.Sorts[0],
+		IDColumn: spec.IDColumn,
+		Filters:  map[string]string{},
+	}
+
+	if limit := query("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxPageLimit {
+			return page, ErrInvalidLimit
+		}
+		page.Limit = n
+	}
+
+	if sort := query("sort"); sort != "" {
+		found := false
+		for _, field := range spec.Sorts {
+			if field.Name == sort {
+				page.Sort, found = field, true
+				break
+			}
+		}
+		if !found {
+			return page, ErrInvalidSort
+		}
+	}
+
+	page.Desc = page.Sort.Desc
+	switch query("order") {
+	case "":
+	case "asc":
+		page.Desc = false
+	case "desc":
+		page.Desc = true
+	default:
+		return page, ErrInvalidOrder
+	}
+
+	if cursor := query("cursor"); cursor != "" {
+		decoded, err := decodeCursor(cursor)
+		if err != nil || decoded.Sort != page.Sort.Name || decoded.Desc != page.Desc {
+			return page, ErrInvalidCursor
+		}
+		page.Cursor = decoded
+	}
+
+	for _, filter := range spec.Filters {
+		if value := query(filter); value != "" {
+			page.Filters[filter] = value
+		}
+	}
+
+	return page, nil
+}
+
+// CursorValue returns the sort value stored in the cursor, typed for the sort column.
+func (p Page) CursorValue() (interface{}, error) {
+	value := p.Cursor.Value
+	switch p.Sort.Kind {
+	case SortTime:
+		return time.Parse(time.RFC3339Nano, value)
+	case SortInt:
+		return strconv.ParseInt(value, 10, 64)
+	case SortFloat:
+		return strconv.ParseFloat(value, 64)
+	}
+	return value, nil
+}
+
+// NextCursor returns the cursor of the page after the item with the given sort value and ID.
+func (p Page) NextCursor(value interface{}, id uint) string {
+	cursor := Cursor{Sort: p.Sort.Name, Desc: p.Desc, ID: id}
+	switch v := value.(type) {
+	case time.Time:
+		cursor.Value = v.Format(time.RFC3339Nano)
+	case string:
+		cursor.Value = v
+	case int:
+		cursor.Value = strconv.Itoa(v)
+	case int64:
+		cursor.Value = strconv.FormatInt(v, 10)
+	case uint8:
+		cursor.Value = strconv.Itoa(int(v))
+	case float64:
+		cursor.Value = strconv.FormatFloat(v, 'g', -1, 64)
+	}
+
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeCursor(value string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
@@ -0,0 +1,163 @@
+package utils
+
+import (
+	"encoding/base64"
+	"errors"
+	"net/url"
+	"testing"
+	"time"
+)
+
+var testSpec = ListSpec{
+	Sorts: []SortField{
+		{Name: "created", Column: "created_at", Kind: SortTime, Desc: true},
+		{Name: "name", Column: "name", Kind: SortString},
+		{Name: "rating", Column: "rating", Kind: SortFloat, Desc: true},
+		{Name: "age", Column: "age", Kind: SortInt},
+	},
+	Filters:  []string{"status"},
+	IDColumn: "id",
+}
+
+// parse reads a page from a raw query string.
+func parse(raw string) (Page, error) {
+	values, err := url.ParseQuery(raw)
+	if err != nil {
+		panic(err)
+	}
+	return ParsePage(testSpec, values.Get)
+}
+
+func TestParsePage(t *testing.T) {
+	tests := []struct {
+		query string
+		limit int
+		sort  string
+		desc  bool
+		err   error
+	}{
+		{"", DefaultPageLimit, "created", true, nil},
+		{"limit=1", 1, "created", true, nil},
+		{"limit=100", 100, "created", true, nil},
+		{"limit=0", 0, "", false, ErrInvalidLimit},
+		{"limit=101", 0, "", false, ErrInvalidLimit},
+		{"limit=ten", 0, "", false, ErrInvalidLimit},
+		{"sort=name", DefaultPageLimit, "name", false, nil},
+		{"sort=name&order=desc", DefaultPageLimit, "name", true, nil},
+		{"sort=created&order=asc", DefaultPageLimit, "created", false, nil},
+		{"sort=email", 0, "", false, ErrInvalidSort},
+		{"order=up", 0, "", false, ErrInvalidOrder},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			page, err := parse(tt.query)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("expected error %v, got %v", tt.err, err)
+			}
+			if err != nil {
+				return
+			}
+			if page.Limit != tt.limit || page.Sort.Name != tt.sort || page.Desc != tt.desc || page.IDColumn != "id" || page.Cursor != nil {
+				t.Fatalf("expected limit %d sorted on %s desc %v, got %+v", tt.limit, tt.sort, tt.desc, page)
+			}
+		})
+	}
+}
+
+func TestParsePageFilters(t *testing.T) {
+	page, err := parse("status=open&owner=7")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the filters of the spec are read
+	if len(page.Filters) != 1 || page.Filters["status"] != "open" {
+		t.Fatalf("expected only the status filter, got %+v", page.Filters)
+	}
+}
+
+func TestCursorRoundTrip(t *testing.T) {
+	created := time.Date(2024, 5, 1, 12, 30, 0, 123456789, time.UTC)
+
+	tests := []struct {
+		query string
+		value interface{}
+		want  interface{}
+	}{
+		{"sort=created", created, created},
+		{"sort=name", "Zoë, \"quoted\"", "Zoë, \"quoted\""},
+		{"sort=rating", 4.25, 4.25},
+		{"sort=age", 42, int64(42)},
+		{"sort=age&order=desc", int64(-3), int64(-3)},
+		{"sort=age", uint8(5), int64(5)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.query, func(t *testing.T) {
+			first, err := parse(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			next, err := parse(tt.query + "&cursor=" + first.NextCursor(tt.value, 9))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if next.Cursor.ID != 9 {
+				t.Fatalf("expected the cursor to point after ID 9, got %+v", next.Cursor)
+			}
+
+			value, err := next.CursorValue()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if at, ok := value.(time.Time); ok {
+				if !at.Equal(tt.want.(time.Time)) {
+					t.Fatalf("expected %v, got %v", tt.want, at)
+				}
+			} else if value != tt.want {
+				t.Fatalf("expected %v (%T), got %v (%T)", tt.want, tt.want, value, value)
+			}
+		})
+	}
+}
+
+func TestTamperedCursors(t *testing.T) {
+	created, err := parse("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cursor := created.NextCursor(time.Now(), 9)
+	encode := func(json string) string { return base64.RawURLEncoding.EncodeToString([]byte(json)) }
+
+	tests := []struct {
+		name  string
+		query string
+	}{
+		{"not base64", "cursor=not+base64!"},
+		{"not JSON", "cursor=" + encode("created|2024")},
+		{"wrong field types", "cursor=" + encode(`{"s":"created","d":true,"v":"x","id":"1"}`)},
+		{"negative ID", "cursor=" + encode(`{"s":"created","d":true,"v":"x","id":-1}`)},
+		{"made for another sort", "sort=name&cursor=" + cursor},
+		{"made for the other order", "order=asc&cursor=" + cursor},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if page, err := parse(tt.query); !errors.Is(err, ErrInvalidCursor) {
+				t.Fatalf("expected an invalid cursor, got %+v (%v)", page.Cursor, err)
+			}
+		})
+	}
+
+	// A cursor whose value was edited decodes, but its value does not parse for the sort column
+	for _, sort := range []string{"created", "rating", "age"} {
+		page, err := parse("sort=" + sort + "&order=desc&cursor=" + encode(`{"s":"`+sort+`","d":true,"v":"1; DROP TABLE users","id":1}`))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if value, err := page.CursorValue(); err == nil {
+			t.Fatalf("expected the edited %s value to be refused, got %v", sort, value)
+		}
+	}
+}
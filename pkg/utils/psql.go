@@ -11,3 +11,8 @@ func IsDuplicateEntryError(err error) bool {
 func IsNoRowsError(err error) bool {
 	return strings.Contains(err.Error(), "no rows in result set")
 }
+
+// EscapeLike escapes the LIKE wildcards in user input, so it is matched literally.
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
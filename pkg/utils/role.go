@@ -0,0 +1,20 @@
+package utils
+
+import "cnep-backend/pkg/consts"
+
+// roleRank orders the roles. A role has every permission of the roles ranked below it.
+var roleRank = map[string]int{
+	consts.ROLE_USER:      1,
+	consts.ROLE_MODERATOR: 2,
+	consts.ROLE_ADMIN:     3,
+}
+
+func IsValidRole(role string) bool {
+	_, ok := roleRank[role]
+	return ok
+}
+
+// HasRole reports whether role is required or ranked above it.
+func HasRole(role, required string) bool {
+	return IsValidRole(role) && roleRank[role] >= roleRank[required]
+}
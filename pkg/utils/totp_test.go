@@ -0,0 +1,128 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+// rfcSecret is the SHA1 key of the test vectors of RFC 6238 Appendix B, base32 encoded.
+var rfcSecret = totpEncoding.EncodeToString([]byte("12345678901234567890"))
+
+func TestTOTPVectors(t *testing.T) {
+	// RFC 6238 Appendix B gives 8 digit codes, a 6 digit code is their last 6 digits
+	tests := []struct {
+		unix int64
+		code string
+	}{
+		{59, "94287082"},
+		{1111111109, "07081804"},
+		{1111111111, "14050471"},
+		{1234567890, "89005924"},
+		{2000000000, "69279037"},
+		{20000000000, "65353130"},
+	}
+
+	key, err := totpEncoding.DecodeString(rfcSecret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tt := range tests {
+		want := tt.code[2:]
+		if code := totpCode(key, tt.unix/totpPeriod); code != want {
+			t.Errorf("at %d: expected %s, got %s", tt.unix, want, code)
+		}
+
+		step, ok := ValidateTOTP(rfcSecret, want, time.Unix(tt.unix, 0))
+		if !ok || step != tt.unix/totpPeriod {
+			t.Errorf("at %d: expected %s to be valid for step %d, got %d, %v", tt.unix, want, tt.unix/totpPeriod, step, ok)
+		}
+	}
+}
+
+func TestValidateTOTPWindow(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, _ := totpEncoding.DecodeString(secret)
+
+	now := time.Unix(1700000000, 0)
+	current := now.Unix() / totpPeriod
+
+	tests := []struct {
+		name  string
+		step  int64
+		valid bool
+	}{
+		{"current step", current, true},
+		{"previous step", current - 1, true},
+		{"next step", current + 1, true},
+		{"two steps ago", current - 2, false},
+		{"two steps ahead", current + 2, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step, ok := ValidateTOTP(secret, totpCode(key, tt.step), now)
+			if ok != tt.valid || (ok && step != tt.step) {
+				t.Fatalf("expected valid %v for step %d, got %v for step %d", tt.valid, tt.step, ok, step)
+			}
+		})
+	}
+
+	// The secret is accepted in lower case, as some apps show it
+	if _, ok := ValidateTOTP(strings.ToLower(secret), totpCode(key, current), now); !ok {
+		t.Fatal("expected a lower case secret to be accepted")
+	}
+	for _, code := range []string{"", "12345", "1234567", "abcdef"} {
+		if _, ok := ValidateTOTP(secret, code, now); ok {
+			t.Fatalf("expected %q to be refused", code)
+		}
+	}
+	if _, ok := ValidateTOTP("not base32!", totpCode(key, current), now); ok {
+		t.Fatal("expected an invalid secret to be refused")
+	}
+}
+
+func TestEncryptSecret(t *testing.T) {
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted, err := EncryptSecret(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(encrypted, secret) {
+		t.Fatal("expected the secret not to appear in the ciphertext")
+	}
+	if again, _ := EncryptSecret(secret); again == encrypted {
+		t.Fatal("expected every encryption to use a new nonce")
+	}
+
+	decrypted, err := DecryptSecret(encrypted)
+	if err != nil || decrypted != secret {
+		t.Fatalf("expected %q back, got %q (%v)", secret, decrypted, err)
+	}
+
+	// A tampered ciphertext or another server secret does not decrypt
+	tampered := []byte(encrypted)
+	tampered[len(tampered)/2] ^= 1
+	if _, err := DecryptSecret(string(tampered)); err == nil {
+		t.Fatal("expected a tampered ciphertext to be refused")
+	}
+	for _, ciphertext := range []string{"", "not base64!", "c2hvcnQ="} {
+		if _, err := DecryptSecret(ciphertext); err == nil {
+			t.Fatalf("expected %q to be refused", ciphertext)
+		}
+	}
+	t.Setenv("JWT_SECRET", "other-secret")
+	if _, err := DecryptSecret(encrypted); err == nil {
+		t.Fatal("expected a secret encrypted under another key to be refused")
+	}
+}
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	_ "image/png"
+	"net/http"
+	"strings"
+
+	"cnep-backend/pkg/consts"
+	_ "golang.org/x/image/webp"
+)
+
+// UploadRule describes what is accepted for a given upload purpose.
+// Dimension limits only apply to images; a zero value means no limit.
+type UploadRule struct {
+	MaxSize   int64
+	Types     map[string]string // content type -> file extension
+	MinWidth  int
+	MinHeight int
+	MaxWidth  int
+	MaxHeight int
+}
+
+var (
+	ErrUnsupportedType   = errors.New("unsupported file type")
+	ErrFileTooLarge      = errors.New("file too large")
+	ErrInvalidDimensions = errors.New("invalid image dimensions")
+)
+
+var imageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+}
+
+var mediaTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+	"image/webp": ".webp",
+	"video/mp4":  ".mp4",
+	"video/webm": ".webm",
+}
+
+// UploadRules maps every upload purpose to its validation rule.
+var UploadRules = map[string]UploadRule{
+	consts.UPLOAD_PURPOSE_AVATAR: {
+		MaxSize: 5 << 20, Types: imageTypes,
+		MinWidth: 64, MinHeight: 64, MaxWidth: 6000, MaxHeight: 6000,
+	},
+	consts.UPLOAD_PURPOSE_POST: {
+		MaxSize: 10 << 20, Types: mediaTypes,
+		MaxWidth: 8000, MaxHeight: 8000,
+	},
+	consts.UPLOAD_PURPOSE_LOGO: {
+		MaxSize: 5 << 20, Types: imageTypes,
+		MinWidth: 64, MinHeight: 64, MaxWidth: 4000, MaxHeight: 4000,
+	},
+	consts.UPLOAD_PURPOSE_COVER: {
+		MaxSize: 10 << 20, Types: imageTypes,
+		MinWidth: 400, MinHeight: 150, MaxWidth: 8000, MaxHeight: 8000,
+	},
+	consts.UPLOAD_PURPOSE_PRODUCT: {
+		MaxSize: 10 << 20, Types: imageTypes,
+		MinWidth: 100, MinHeight: 100, MaxWidth: 8000, MaxHeight: 8000,
+	},
+}
+
+// InspectUpload sniffs the content type of data, checks it against the rule and,
+// for images, validates the dimensions. The declared client content type is ignored.
+//
+// Returns:
+//   - contentType: the sniffed content type
+//   - ext: the file extension to store the file with
+func InspectUpload(data []byte, rule UploadRule) (contentType string, ext string, err error) {
+	if int64(len(data)) > rule.MaxSize {
+		return "", "", ErrFileTooLarge
+	}
+
+	contentType = http.DetectContentType(data)
+	if i := strings.Index(contentType, ";"); i >= 0 {
+		contentType = contentType[:i]
+	}
+
+	ext, ok := rule.Types[contentType]
+	if !ok {
+		return "", "", ErrUnsupportedType
+	}
+
+	if strings.HasPrefix(contentType, "image/") {
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return "", "", ErrUnsupportedType
+		}
+		if cfg.Width < rule.MinWidth || cfg.Height < rule.MinHeight ||
+			(rule.MaxWidth > 0 && cfg.Width > rule.MaxWidth) ||
+			(rule.MaxHeight > 0 && cfg.Height > rule.MaxHeight) {
+			return "", "", ErrInvalidDimensions
+		}
+	}
+
+	return contentType, ext, nil
+}
@@ -0,0 +1,29 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"regexp"
+	"strings"
+)
+
+var usernameChars = regexp.MustCompile(`[^a-z0-9._]+`)
+
+// GenerateUsername returns a username for a new account, built from the local part of the
+// email and a random suffix, since the username is required and unique but not asked at signup.
+func GenerateUsername(email string) (string, error) {
+	b := make([]byte, 3)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+
+	name, _, _ := strings.Cut(strings.ToLower(email), "@")
+	name = usernameChars.ReplaceAllString(name, "")
+	if len(name) > 40 {
+		name = name[:40]
+	}
+	if name == "" {
+		name = "user"
+	}
+	return name + "_" + hex.EncodeToString(b), nil
+}
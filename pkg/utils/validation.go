@@ -2,9 +2,16 @@ package utils
 
 import (
 	"regexp"
+	"strings"
 	"unicode"
 )
 
+// NormalizeEmail returns the form emails are stored and looked up in, trimmed and lower case,
+// so the same address typed differently always finds the same account.
+func NormalizeEmail(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
 func IsValidEmail(email string) bool {
 	emailRegex := regexp.MustCompile(`^[a-z0-9._%+\-]+@[a-z0-9.\-]+\.[a-z]{2,4}$`)
 	return emailRegex.MatchString(email)
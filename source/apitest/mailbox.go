@@ -0,0 +1,64 @@
+package apitest
+
+import (
+	"context"
+	"regexp"
+	"sync"
+	"testing"
+
+	"cnep-backend/pkg/lib"
+)
+
+// otpLine matches a code on its own line of the plaintext OTP emails.
+var otpLine = regexp.MustCompile(`(?m)^\s*([A-Z0-9]{8})\s*$`)
+
+// Mailbox is a lib.Mailer keeping every email sent, so tests can read the codes in them.
+type Mailbox struct {
+	mu    sync.Mutex
+	mails []lib.Mail
+}
+
+func (m *Mailbox) Send(ctx context.Context, mail lib.Mail) error {
+	if len(mail.To) == 0 {
+		return lib.ErrNoRecipients
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.mails = append(m.mails, mail)
+	return nil
+}
+
+// To returns the emails sent to the address, oldest first.
+func (m *Mailbox) To(address string) []lib.Mail {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	var mails []lib.Mail
+	for _, mail := range m.mails {
+		for _, to := range mail.To {
+			if to == address {
+				mails = append(mails, mail)
+				break
+			}
+		}
+	}
+	return mails
+}
+
+// OTP returns the code of the last email sent to the address, failing the test if there is none.
+func (m *Mailbox) OTP(t testing.TB, address string) string {
+	t.Helper()
+
+	mails := m.To(address)
+	if len(mails) == 0 {
+		t.Fatalf("no email was sent to %s", address)
+	}
+
+	match := otpLine.FindStringSubmatch(mails[len(mails)-1].Text)
+	if match == nil {
+		t.Fatalf("the last email to %s has no code: %s", address, mails[len(mails)-1].Text)
+	}
+	return match[1]
+}
@@ -0,0 +1,267 @@
+// Package apitest runs the API against a disposable Postgres schema, so tests can drive it
+// over HTTP the way a client does.
+//
+// Tests using it need a database given by TEST_DATABASE_URL, as a URL or a key=value DSN,
+// and are skipped without one. Every server migrates its own schema, which is dropped when
+// the test ends. Emails are kept in memory, and the mailer is global, so tests using a
+// server must not run in parallel.
+package apitest
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"strings"
+	"testing"
+
+	"cnep-backend/pkg/lib"
+	"cnep-backend/source/config"
+	"cnep-backend/source/database"
+	"cnep-backend/source/middleware"
+	"cnep-backend/source/repository"
+	"cnep-backend/source/routes"
+	"cnep-backend/source/services"
+
+	"github.com/gofiber/fiber/v2"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// DatabaseEnv is the environment variable holding the DSN of the test database.
+const DatabaseEnv = "TEST_DATABASE_URL"
+
+// Server is the API running on its own schema of the test database.
+type Server struct {
+	App   *fiber.App
+	DB    *gorm.DB
+	Store repository.Store
+	Mail  *Mailbox
+
+	t     testing.TB
+	users int
+}
+
+/*
+The New function starts the API for a test on a new schema of the test database.
+
+Steps:
+ 1. Skips the test when TEST_DATABASE_URL is not set.
+ 2. Creates a schema with a random name and connects with it as the search path, so the
+    tables of the test are kept apart from other tests and from the public schema.
+ 3. Applies every migration to the schema.
+ 4. Builds the services and routes like the server does, with a mailer keeping the emails in memory.
+ 5. Registers a cleanup dropping the schema when the test ends.
+
+Returns:
+
+	The server, ready to take requests.
+*/
+func New(t testing.TB) *Server {
+	t.Helper()
+
+	dsn := os.Getenv(DatabaseEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set, skipping the API tests", DatabaseEnv)
+	}
+
+	admin, err := open(dsn)
+	if err != nil {
+		t.Fatalf("could not connect to the test database: %v", err)
+	}
+
+	schema := "test_" + randomHex(8)
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		closeDB(admin)
+		t.Fatalf("could not create schema %s: %v", schema, err)
+	}
+
+	db, err := open(withParam(dsn, "search_path", schema))
+	if err != nil {
+		dropSchema(t, admin, schema)
+		t.Fatalf("could not connect to schema %s: %v", schema, err)
+	}
+	t.Cleanup(func() {
+		closeDB(db)
+		dropSchema(t, admin, schema)
+	})
+
+	if _, err := database.MigrateUp(db, 0); err != nil {
+		t.Fatalf("could not migrate schema %s: %v", schema, err)
+	}
+
+	mail := &Mailbox{}
+	lib.SetMailer(mail)
+
+	// Same app as the server builds in cmd/main.go
+	cfg := config.New()
+	app := fiber.New(fiber.Config{
+		BodyLimit:    cfg.BodyLimit(),
+		ErrorHandler: middleware.ErrorHandler,
+	})
+	store := repository.NewStore(db)
+	routes.SetupRoutes(app, cfg, services.New(store))
+
+	return &Server{App: app, DB: db, Store: store, Mail: mail, t: t}
+}
+
+// Get sends a GET request, authenticated with the access token unless it is empty.
+func (s *Server) Get(path, token string) *Response {
+	return s.Do(http.MethodGet, path, token, nil)
+}
+
+// Post sends a POST request with body encoded as JSON.
+func (s *Server) Post(path, token string, body interface{}) *Response {
+	return s.Do(http.MethodPost, path, token, body)
+}
+
+// Put sends a PUT request with body encoded as JSON.
+func (s *Server) Put(path, token string, body interface{}) *Response {
+	return s.Do(http.MethodPut, path, token, body)
+}
+
+// Delete sends a DELETE request.
+func (s *Server) Delete(path, token string) *Response {
+	return s.Do(http.MethodDelete, path, token, nil)
+}
+
+// Do sends a request to the app and reads the whole response. A nil body sends no body.
+func (s *Server) Do(method, path, token string, body interface{}) *Response {
+	s.t.Helper()
+
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			s.t.Fatalf("could not encode the body of %s %s: %v", method, path, err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+
+	req := httptest.NewRequest(method, path, reader)
+	if body != nil {
+		req.Header.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	}
+	if token != "" {
+		req.Header.Set(fiber.HeaderAuthorization, "Bearer "+token)
+	}
+
+	return s.Send(req)
+}
+
+// Send sends a request built by the test, e.g. one carrying cookies, and reads the whole response.
+func (s *Server) Send(req *http.Request) *Response {
+	s.t.Helper()
+
+	// No timeout, password hashing is slow on purpose
+	res, err := s.App.Test(req, -1)
+	if err != nil {
+		s.t.Fatalf("%s %s failed: %v", req.Method, req.URL, err)
+	}
+	defer res.Body.Close()
+
+	content, err := io.ReadAll(res.Body)
+	if err != nil {
+		s.t.Fatalf("could not read the response of %s %s: %v", req.Method, req.URL, err)
+	}
+
+	return &Response{Status: res.StatusCode, Body: content, Cookies: res.Cookies(), t: s.t, request: req.Method + " " + req.URL.String()}
+}
+
+// Response is a response of the app, read in full.
+type Response struct {
+	Status  int
+	Body    []byte
+	Cookies []*http.Cookie
+
+	t       testing.TB
+	request string
+}
+
+// Expect fails the test unless the response has the given status.
+func (r *Response) Expect(status int) *Response {
+	r.t.Helper()
+	if r.Status != status {
+		r.t.Fatalf("%s: expected status %d, got %d: %s", r.request, status, r.Status, r.Body)
+	}
+	return r
+}
+
+// Decode decodes the JSON body of the response into v.
+func (r *Response) Decode(v interface{}) *Response {
+	r.t.Helper()
+	if err := json.Unmarshal(r.Body, v); err != nil {
+		r.t.Fatalf("%s: could not decode %s: %v", r.request, r.Body, err)
+	}
+	return r
+}
+
+// Map decodes the JSON body of the response as an object.
+func (r *Response) Map() map[string]interface{} {
+	r.t.Helper()
+	var m map[string]interface{}
+	r.Decode(&m)
+	return m
+}
+
+// ErrorCode returns the code of an apierror body, e.g. "email_not_verified".
+func (r *Response) ErrorCode() string {
+	r.t.Helper()
+	var body struct {
+		Code string `json:"code"`
+	}
+	r.Decode(&body)
+	return body.Code
+}
+
+// open connects to the database like database.Connect, without logging every failed lookup.
+func open(dsn string) (*gorm.DB, error) {
+	return gorm.Open(postgres.Open(withParam(dsn, "TimeZone", "UTC")), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+}
+
+// withParam adds a connection parameter to a URL or key=value DSN. Parameters that are not
+// connection settings, like search_path, are sent to the server as session settings.
+func withParam(dsn, key, value string) string {
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err == nil {
+			q := u.Query()
+			q.Set(key, value)
+			u.RawQuery = q.Encode()
+			return u.String()
+		}
+	}
+	return fmt.Sprintf("%s %s=%s", dsn, key, value)
+}
+
+// dropSchema drops the schema of a test with everything in it and closes the connection used for it.
+func dropSchema(t testing.TB, admin *gorm.DB, schema string) {
+	if err := admin.Exec("DROP SCHEMA IF EXISTS " + schema + " CASCADE").Error; err != nil {
+		t.Errorf("could not drop schema %s: %v", schema, err)
+	}
+	closeDB(admin)
+}
+
+func closeDB(db *gorm.DB) {
+	if sqlDB, err := db.DB(); err == nil {
+		sqlDB.Close()
+	}
+}
+
+// randomHex returns n random bytes as hex, lower case so it can be used in identifiers.
+func randomHex(n int) string {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		panic(err)
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,81 @@
+package apitest
+
+import (
+	"fmt"
+	"net/http"
+
+	"cnep-backend/source/models"
+	"cnep-backend/source/services"
+)
+
+// Password is the password of the users created by the server, it meets the complexity rules.
+const Password = "Password@123"
+
+// User is a signed up user with the tokens of its session.
+type User struct {
+	ID           uint
+	Email        string
+	Password     string
+	Token        string
+	RefreshToken string
+}
+
+// CreateUser signs up a new verified user with a unique email and returns it signed in.
+func (s *Server) CreateUser() *User {
+	s.t.Helper()
+
+	s.users++
+	return s.SignUp(fmt.Sprintf("user%d@example.com", s.users))
+}
+
+/*
+The SignUp method goes through the signup flow of a client for the email.
+
+Steps:
+ 1. Registers the email with Password.
+ 2. Verifies it with the OTP of the signup email, which starts a session.
+ 3. Reads the profile to learn the ID of the user.
+
+Returns:
+
+	The verified user with the tokens of its session.
+*/
+func (s *Server) SignUp(email string) *User {
+	s.t.Helper()
+
+	s.Post("/api/auth/continue", "", map[string]interface{}{
+		"email":    email,
+		"password": Password,
+		"is_new":   true,
+	}).Expect(http.StatusCreated)
+
+	var result services.LoginResult
+	s.Post("/api/otp/verify", "", map[string]interface{}{
+		"email": email,
+		"otp":   s.Mail.OTP(s.t, email),
+	}).Expect(http.StatusOK).Decode(&result)
+
+	user := &User{Email: email, Password: Password, Token: result.Token, RefreshToken: result.RefreshToken}
+
+	var profile models.UserResponse
+	s.Get("/api/users/profile", user.Token).Expect(http.StatusOK).Decode(&profile)
+	user.ID = profile.ID
+
+	return user
+}
+
+// Login signs in with the email and password of the user and returns the tokens of the new session.
+func (s *Server) Login(user *User) *User {
+	s.t.Helper()
+
+	var result services.LoginResult
+	s.Post("/api/auth/continue", "", map[string]interface{}{
+		"email":    user.Email,
+		"password": user.Password,
+	}).Expect(http.StatusOK).Decode(&result)
+
+	signedIn := *user
+	signedIn.Token = result.Token
+	signedIn.RefreshToken = result.RefreshToken
+	return &signedIn
+}
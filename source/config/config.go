@@ -3,6 +3,7 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -13,9 +14,70 @@ type Config struct {
 	DBPort     string
 	JWTSecret  string
 	ServerPort string
+
+	// Migrations
+	DBAutoMigrate bool // apply pending migrations when the server starts
+
+	// Seed
+	SeedAllowReset bool // allow `seed -reset` on a database that is not on this machine
+
+	// Tokens
+	AccessTokenTTL  int // minutes
+	RefreshTokenTTL int // days
+
+	// OTP
+	OTPMaxAttempts    int // guesses per emailed code, and wrong two-factor codes before the account is locked
+	OTPMaxFailures    int // wrong emailed codes of a user, across resends, before codes are locked
+	OTPLockMinutes    int // how long wrong codes lock the account
+	OTPResendCooldown int // seconds
+	OTPDailyLimit     int
+
+	// Partners
+	PartnerRequestCooldown int // days before a declined partner request can be sent again
+
+	// Mail
+	MailDriver     string // smtp, log or file
+	MailFrom       string
+	MailDir        string
+	SMTPHost       string
+	SMTPPort       string
+	SMTPUsername   string
+	SMTPPassword   string
+	SMTPTLS        string // starttls, tls or none
+	OutboxInterval int    // seconds
+
+	// Storage
+	StorageDriver  string
+	StoragePath    string
+	StorageBaseURL string
+	S3Endpoint     string
+	S3Region       string
+	S3Bucket       string
+	S3AccessKey    string
+	S3SecretKey    string
+	S3PublicURL    string
+	UploadMaxSize  int // largest file accepted in a request, the upload rules set the limit of each purpose
+
+	// OpenID Connect login providers
+	OIDCProviders []OIDCProvider
+}
+
+type OIDCProvider struct {
+	Name         string
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
 }
 
 func New() *Config {
+	// Fall back to logging emails when no SMTP credentials are configured,
+	// so the server can run locally without a mail account
+	mailDriver := "log"
+	if os.Getenv("SMTP_PASSWORD") != "" {
+		mailDriver = "smtp"
+	}
+
 	return &Config{
 		DBHost:     getEnv("DB_HOST", "localhost"),
 		DBUser:     getEnv("DB_USER", "postgres"),
@@ -24,7 +86,78 @@ func New() *Config {
 		DBPort:     getEnv("DB_PORT", "5432"),
 		JWTSecret:  getEnv("JWT_SECRET", "your-secret-key"),
 		ServerPort: getEnv("PORT", "8080"),
+
+		DBAutoMigrate: getEnvAsBool("DB_AUTO_MIGRATE", false),
+
+		SeedAllowReset: getEnvAsBool("SEED_ALLOW_RESET", false),
+
+		AccessTokenTTL:  getEnvAsInt("ACCESS_TOKEN_TTL", 15),
+		RefreshTokenTTL: getEnvAsInt("REFRESH_TOKEN_TTL", 30),
+
+		OTPMaxAttempts:    getEnvAsInt("OTP_MAX_ATTEMPTS", 5),
+		OTPMaxFailures:    getEnvAsInt("OTP_MAX_FAILURES", 10),
+		OTPLockMinutes:    getEnvAsInt("OTP_LOCK_MINUTES", 30),
+		OTPResendCooldown: getEnvAsInt("OTP_RESEND_COOLDOWN", 60),
+		OTPDailyLimit:     getEnvAsInt("OTP_DAILY_LIMIT", 10),
+
+		PartnerRequestCooldown: getEnvAsInt("PARTNER_REQUEST_COOLDOWN", 7),
+
+		MailDriver:     getEnv("MAIL_DRIVER", mailDriver),
+		MailFrom:       getEnv("MAIL_FROM", os.Getenv("SENDER_EMAIL")),
+		MailDir:        getEnv("MAIL_DIR", "./mail"),
+		SMTPHost:       getEnv("SMTP_HOST", "smtp.gmail.com"),
+		SMTPPort:       getEnv("SMTP_PORT", "587"),
+		SMTPUsername:   getEnv("SMTP_USERNAME", os.Getenv("SENDER_EMAIL")),
+		SMTPPassword:   getEnv("SMTP_PASSWORD", ""),
+		SMTPTLS:        getEnv("SMTP_TLS", "starttls"),
+		OutboxInterval: getEnvAsInt("MAIL_OUTBOX_INTERVAL", 10),
+
+		StorageDriver:  getEnv("STORAGE_DRIVER", "local"),
+		StoragePath:    getEnv("STORAGE_PATH", "./uploads"),
+		StorageBaseURL: getEnv("STORAGE_BASE_URL", "/uploads"),
+		S3Endpoint:     getEnv("S3_ENDPOINT", ""),
+		S3Region:       getEnv("S3_REGION", "us-east-1"),
+		S3Bucket:       getEnv("S3_BUCKET", ""),
+		S3AccessKey:    getEnv("S3_ACCESS_KEY", ""),
+		S3SecretKey:    getEnv("S3_SECRET_KEY", ""),
+		S3PublicURL:    getEnv("S3_PUBLIC_URL", ""),
+		UploadMaxSize:  getEnvAsInt("UPLOAD_MAX_SIZE", 10*1024*1024),
+
+		OIDCProviders: getOIDCProviders(),
+	}
+}
+
+// bodyLimitHeadroom is what a request body may hold besides the uploaded file:
+// the multipart boundaries and headers and the other form fields.
+const bodyLimitHeadroom = 1 << 20
+
+// BodyLimit returns the largest request body the server reads. A file of UploadMaxSize
+// still fits, so the upload service checks its size and answers with its own error.
+func (c *Config) BodyLimit() int {
+	return c.UploadMaxSize + bodyLimitHeadroom
+}
+
+// getOIDCProviders reads the providers listed in OIDC_PROVIDERS, e.g. "google,microsoft".
+// Each provider is configured with OIDC_<NAME>_ISSUER, OIDC_<NAME>_CLIENT_ID,
+// OIDC_<NAME>_CLIENT_SECRET and OIDC_<NAME>_REDIRECT_URL.
+func getOIDCProviders() []OIDCProvider {
+	var providers []OIDCProvider
+	for _, name := range strings.Split(getEnv("OIDC_PROVIDERS", ""), ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+
+		prefix := "OIDC_" + strings.ToUpper(name) + "_"
+		providers = append(providers, OIDCProvider{
+			Name:         name,
+			Issuer:       getEnv(prefix+"ISSUER", ""),
+			ClientID:     getEnv(prefix+"CLIENT_ID", ""),
+			ClientSecret: getEnv(prefix+"CLIENT_SECRET", ""),
+			RedirectURL:  getEnv(prefix+"REDIRECT_URL", ""),
+		})
 	}
+	return providers
 }
 
 func getEnv(key, defaultValue string) string {
@@ -43,3 +176,11 @@ func getEnvAsInt(name string, defaultVal int) int {
 	}
 	return defaultVal
 }
+
+// Helper function to get boolean environment variables
+func getEnvAsBool(name string, defaultVal bool) bool {
+	if value, err := strconv.ParseBool(getEnv(name, "")); err == nil {
+		return value
+	}
+	return defaultVal
+}
@@ -12,17 +12,19 @@ import (
 
 var DB *gorm.DB
 
-func Connect(cfg *config.Config) {
+// Connect opens the database connection in DB.
+func Connect(cfg *config.Config) error {
 	dsn := fmt.Sprintf("host=%s user=%s password=%s dbname=%s port=%s sslmode=disable TimeZone=UTC",
 		cfg.DBHost, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBPort)
 
 	var err error
 	DB, err = gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
-		log.Panic("Failed to connect to database: ", err)
+		return fmt.Errorf("failed to connect to database: %w", err)
 	}
 
 	log.Println("DATABASE is connected on port " + cfg.DBPort)
+	return nil
 }
 
 func Close() {
@@ -0,0 +1,369 @@
+package database
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// MigrationsDir is where `migrate create` writes new migrations, relative to the repository root.
+// The files are embedded into the binary, so it has to be rebuilt to apply them.
+const MigrationsDir = "source/database/migrations"
+
+// migrationLockKey is the Postgres advisory lock held while migrating, so that server
+// instances deployed at the same time apply every migration exactly once.
+const migrationLockKey int64 = 4_823_091_177
+
+var (
+	migrationName = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+	nonNameChars  = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Migration is a versioned schema change with the SQL to apply and to revert it.
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// MigrationState is a migration and when it was applied, nil if it is pending.
+type MigrationState struct {
+	Migration
+	AppliedAt *time.Time
+}
+
+// Migrations returns the embedded migrations ordered by version.
+func Migrations() ([]Migration, error) {
+	entries, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := migrationName.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("invalid migration file name %q", entry.Name())
+		}
+
+		content, err := migrationFiles.ReadFile("migrations/" + entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, migration.Name, match[2])
+		}
+
+		if match[3] == "up" {
+			migration.Up = string(content)
+		} else {
+			migration.Down = string(content)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+
+	return migrations, nil
+}
+
+/*
+The MigrateUp function applies pending migrations in version order.
+
+Steps:
+ 1. Takes the migration advisory lock, waiting while another instance is migrating.
+ 2. Reads the applied versions only once the lock is held, so migrations another instance
+    just applied are skipped.
+ 3. Applies each migration and records its version in one transaction, so a failed
+    migration leaves nothing behind.
+
+Returns:
+
+	The number of migrations applied. A steps of 0 applies every pending migration.
+*/
+func MigrateUp(db *gorm.DB, steps int) (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	applied := 0
+	err = withMigrationLock(db, func(conn *sql.Conn) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if _, ok := done[migration.Version]; ok {
+				continue
+			}
+			if steps > 0 && applied == steps {
+				break
+			}
+
+			log.Printf("Applying migration %04d_%s", migration.Version, migration.Name)
+			if err := runMigration(conn, migration.Up,
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name); err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			applied++
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// MigrateDown reverts the given number of applied migrations, newest first.
+// It returns the number of migrations reverted.
+func MigrateDown(db *gorm.DB, steps int) (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	reverted := 0
+	err = withMigrationLock(db, func(conn *sql.Conn) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && reverted < steps; i-- {
+			migration := migrations[i]
+			if _, ok := done[migration.Version]; !ok {
+				continue
+			}
+
+			log.Printf("Reverting migration %04d_%s", migration.Version, migration.Name)
+			if err := runMigration(conn, migration.Down,
+				"DELETE FROM schema_migrations WHERE version = $1", migration.Version); err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			reverted++
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+/*
+The MigrateBaseline function records migrations as applied without running them, for a database
+whose schema was created before the migrations existed, e.g. by GORM's AutoMigrate. Running
+0001_init on such a database fails, as its tables already exist.
+
+Steps:
+ 1. Checks that version is one of the embedded migrations.
+ 2. Takes the migration advisory lock and reads the applied versions.
+ 3. Records every migration up to and including version that is not recorded yet, without running its SQL.
+
+Returns:
+
+	The number of migrations recorded.
+*/
+func MigrateBaseline(db *gorm.DB, version int) (int, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, err
+	}
+
+	if !slices.ContainsFunc(migrations, func(m Migration) bool { return m.Version == version }) {
+		return 0, fmt.Errorf("there is no migration %d", version)
+	}
+
+	recorded := 0
+	err = withMigrationLock(db, func(conn *sql.Conn) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			if _, ok := done[migration.Version]; ok || migration.Version > version {
+				continue
+			}
+
+			log.Printf("Recording migration %04d_%s as applied", migration.Version, migration.Name)
+			if _, err := conn.ExecContext(context.Background(),
+				"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)", migration.Version, migration.Name); err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", migration.Version, migration.Name, err)
+			}
+			recorded++
+		}
+		return nil
+	})
+
+	return recorded, err
+}
+
+// MigrationStatus returns every embedded migration with the time it was applied.
+func MigrationStatus(db *gorm.DB) ([]MigrationState, error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var states []MigrationState
+	err = withMigrationLock(db, func(conn *sql.Conn) error {
+		done, err := appliedVersions(conn)
+		if err != nil {
+			return err
+		}
+
+		for _, migration := range migrations {
+			state := MigrationState{Migration: migration}
+			if appliedAt, ok := done[migration.Version]; ok {
+				state.AppliedAt = &appliedAt
+			}
+			states = append(states, state)
+		}
+		return nil
+	})
+
+	return states, err
+}
+
+// CreateMigration writes empty up and down files for a new migration into dir, numbered after
+// the highest existing version. It returns the paths of the two files.
+func CreateMigration(dir, name string) (string, string, error) {
+	name = strings.Trim(nonNameChars.ReplaceAllString(strings.ToLower(name), "_"), "_")
+	if name == "" {
+		return "", "", fmt.Errorf("invalid migration name")
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", "", err
+	}
+
+	version := 0
+	for _, entry := range entries {
+		if match := migrationName.FindStringSubmatch(entry.Name()); match != nil {
+			if v, _ := strconv.Atoi(match[1]); v > version {
+				version = v
+			}
+		}
+	}
+
+	base := filepath.Join(dir, fmt.Sprintf("%04d_%s", version+1, name))
+	up, down := base+".up.sql", base+".down.sql"
+	if err := os.WriteFile(up, []byte("-- Write the schema change here\n"), 0o644); err != nil {
+		return "", "", err
+	}
+	if err := os.WriteFile(down, []byte("-- Revert the schema change of the up migration here\n"), 0o644); err != nil {
+		return "", "", err
+	}
+
+	return up, down, nil
+}
+
+// withMigrationLock runs fn on a connection holding the migration advisory lock, after making sure
+// the schema_migrations table exists. The lock belongs to the session, so every statement runs on
+// that one connection, and it is released even if fn fails.
+func withMigrationLock(db *gorm.DB, fn func(conn *sql.Conn) error) error {
+	ctx := context.Background()
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockKey); err != nil {
+		return fmt.Errorf("could not take the migration lock: %w", err)
+	}
+	defer func() {
+		if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_unlock($1)", migrationLockKey); err != nil {
+			log.Println("Error releasing the migration lock:", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, `
+		CREATE TABLE IF NOT EXISTS schema_migrations (
+			version INTEGER PRIMARY KEY,
+			name VARCHAR(255) NOT NULL,
+			applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+		)`); err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// appliedVersions returns the applied migration versions with the time they were applied.
+func appliedVersions(conn *sql.Conn) (map[int]time.Time, error) {
+	rows, err := conn.QueryContext(context.Background(), "SELECT version, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[int]time.Time)
+	for rows.Next() {
+		var version int
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+		versions[version] = appliedAt
+	}
+	return versions, rows.Err()
+}
+
+// runMigration runs the SQL of a migration and the statement recording it in one transaction.
+func runMigration(conn *sql.Conn, migration, record string, args ...interface{}) error {
+	ctx := context.Background()
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Without arguments the SQL is sent as one simple query, so a file can hold many statements
+	if _, err := tx.ExecContext(ctx, migration); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, record, args...); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
@@ -0,0 +1,24 @@
+DROP TABLE IF EXISTS reports;
+DROP TABLE IF EXISTS moderation_cases;
+DROP TABLE IF EXISTS businesses;
+DROP TABLE IF EXISTS notifications;
+DROP TABLE IF EXISTS messages;
+DROP TABLE IF EXISTS conversations;
+DROP TABLE IF EXISTS comments;
+DROP TABLE IF EXISTS reactions;
+DROP TABLE IF EXISTS helps;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS partners;
+DROP TABLE IF EXISTS badges;
+DROP TABLE IF EXISTS topics;
+DROP TABLE IF EXISTS feedbacks;
+DROP TABLE IF EXISTS user_blocks;
+DROP TABLE IF EXISTS audit_logs;
+DROP TABLE IF EXISTS oauth_states;
+DROP TABLE IF EXISTS user_identities;
+DROP TABLE IF EXISTS refresh_tokens;
+DROP TABLE IF EXISTS sessions;
+DROP TABLE IF EXISTS email_outbox;
+DROP TABLE IF EXISTS recovery_codes;
+DROP TABLE IF EXISTS one_time_codes;
+DROP TABLE IF EXISTS users;
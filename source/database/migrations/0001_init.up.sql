@@ -0,0 +1,330 @@
+CREATE TABLE users (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    username VARCHAR(150) UNIQUE NOT NULL,
+    avatar VARCHAR(300),
+    avatar_variants JSONB,
+    email VARCHAR(255) UNIQUE NOT NULL,
+    password VARCHAR(255) NOT NULL,
+    address TEXT,
+    designation VARCHAR(255),
+    phone VARCHAR(20),
+    locale VARCHAR(10) DEFAULT 'en',
+    role VARCHAR(20) NOT NULL DEFAULT 'user' CHECK(role IN ('user', 'moderator', 'admin')),
+    topics INTEGER[],
+    latitude DOUBLE PRECISION CHECK(latitude >= -90 AND latitude <= 90),
+    longitude DOUBLE PRECISION CHECK(longitude >= -180 AND longitude <= 180),
+    otp_failed_attempts INTEGER DEFAULT 0,
+    locked_until TIMESTAMP,
+    otp_last_sent_at TIMESTAMP,
+    otp_send_count INTEGER DEFAULT 0,
+    otp_send_window_start TIMESTAMP,
+    two_factor_enabled BOOLEAN DEFAULT FALSE,
+    totp_secret VARCHAR(255),
+    totp_last_step BIGINT DEFAULT 0,
+    suspended_at TIMESTAMP,
+    suspended_until TIMESTAMP,
+    suspension_reason TEXT,
+    email_visibility VARCHAR(10) NOT NULL DEFAULT 'partners' CHECK(email_visibility IN ('public', 'partners', 'private')),
+    phone_visibility VARCHAR(10) NOT NULL DEFAULT 'private' CHECK(phone_visibility IN ('public', 'partners', 'private')),
+    address_visibility VARCHAR(10) NOT NULL DEFAULT 'partners' CHECK(address_visibility IN ('public', 'partners', 'private')),
+    is_verified BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE one_time_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    purpose VARCHAR(20) NOT NULL CHECK(purpose IN ('signup', 'reset', 'email_change')),
+    code_hash VARCHAR(64) NOT NULL,
+    target VARCHAR(255),
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX one_time_codes_user_purpose_idx ON one_time_codes (user_id, purpose);
+
+CREATE TABLE recovery_codes (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE email_outbox (
+    id SERIAL PRIMARY KEY,
+    recipients TEXT[] NOT NULL,
+    subject TEXT NOT NULL,
+    body TEXT NOT NULL,
+    text_body TEXT,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    sent_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX email_outbox_pending_idx ON email_outbox (next_attempt_at) WHERE sent_at IS NULL;
+
+CREATE TABLE sessions (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    device VARCHAR(255),
+    ip VARCHAR(64),
+    user_agent TEXT,
+    impersonator_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    last_seen_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE TABLE refresh_tokens (
+    id SERIAL PRIMARY KEY,
+    session_id INTEGER NOT NULL,
+    token_hash VARCHAR(64) UNIQUE NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (session_id) REFERENCES sessions(id) ON DELETE CASCADE
+);
+
+CREATE TABLE user_identities (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    subject VARCHAR(255) NOT NULL,
+    email VARCHAR(255),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (provider, subject)
+);
+
+CREATE TABLE oauth_states (
+    id SERIAL PRIMARY KEY,
+    state_hash VARCHAR(64) UNIQUE NOT NULL,
+    provider VARCHAR(50) NOT NULL,
+    code_verifier VARCHAR(128) NOT NULL,
+    nonce VARCHAR(64) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE audit_logs (
+    id SERIAL PRIMARY KEY,
+    actor_id INTEGER,
+    action VARCHAR(50) NOT NULL,
+    target_type VARCHAR(50) NOT NULL,
+    target_id INTEGER,
+    details JSONB,
+    ip VARCHAR(64),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (actor_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX audit_logs_target_idx ON audit_logs (target_type, target_id);
+
+CREATE TABLE user_blocks (
+    id SERIAL PRIMARY KEY,
+    blocker_id INTEGER NOT NULL,
+    blocked_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (blocker_id) REFERENCES users(id) ON DELETE CASCADE,
+    FOREIGN KEY (blocked_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (blocker_id, blocked_id),
+    CHECK (blocker_id <> blocked_id)
+);
+
+CREATE INDEX user_blocks_blocked_idx ON user_blocks (blocked_id);
+
+CREATE TABLE feedbacks (
+    id SERIAL PRIMARY KEY,
+    receiver_id INTEGER NOT NULL,
+    sender_id INTEGER NOT NULL,
+    content TEXT,
+    rating INTEGER NOT NULL CHECK(rating >= 1 AND rating <= 5),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (receiver_id) REFERENCES users(id),
+    FOREIGN KEY (sender_id) REFERENCES users(id)
+);
+
+CREATE TABLE topics(
+    id SERIAL PRIMARY KEY,
+    title VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE badges (
+    id SERIAL PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    image VARCHAR(255) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Button Text - "Partner Up"
+
+CREATE TABLE partners (
+    id SERIAL PRIMARY KEY,
+    sender_id INTEGER NOT NULL,
+    receiver_id INTEGER NOT NULL,
+    status VARCHAR(15) NOT NULL CHECK(status IN ('pending', 'accepted', 'declined')),
+    sent_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (sender_id) REFERENCES users(id),
+    FOREIGN KEY (receiver_id) REFERENCES users(id),
+    UNIQUE (sender_id, receiver_id)
+);
+
+-- A pair of users has a single partner row, whichever of them sent the request
+CREATE UNIQUE INDEX partners_pair_idx ON partners (LEAST(sender_id, receiver_id), GREATEST(sender_id, receiver_id));
+
+CREATE TABLE posts (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    is_request BOOLEAN NOT NULL DEFAULT FALSE,
+    is_urgent BOOLEAN NOT NULL DEFAULT FALSE,
+    is_deleted BOOLEAN NOT NULL DEFAULT FALSE,
+    assigned_to INTEGER,
+    media_url TEXT,
+    media_variants JSONB,
+    media_type TEXT,
+    caption TEXT NOT NULL,
+    status VARCHAR(10) NOT NULL CHECK(status IN ('accepted', 'completed', 'pending')),
+    hidden_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    FOREIGN KEY (assigned_to) REFERENCES users(id)
+);
+
+-- Helps reference the post they were given on, so they are created after posts
+CREATE TABLE helps (
+    id SERIAL PRIMARY KEY,
+    receiver_id INTEGER NOT NULL,
+    sender_id INTEGER NOT NULL,
+    post_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (receiver_id) REFERENCES users(id),
+    FOREIGN KEY (sender_id) REFERENCES users(id),
+    FOREIGN KEY (post_id) REFERENCES posts(id)
+);
+
+CREATE TABLE reactions (
+    id BIGSERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    reaction VARCHAR(10) NOT NULL CHECK(reaction IN ('like', 'wow', 'love', 'angry', 'sad')),
+    is_useful BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (post_id) REFERENCES posts(id),
+    FOREIGN KEY (user_id) REFERENCES users(id),
+    UNIQUE (post_id, user_id, reaction)
+);
+
+CREATE TABLE comments (
+    id BIGSERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    hidden_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (post_id) REFERENCES posts(id),
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE conversations (
+    id SERIAL PRIMARY KEY,
+    user1_id INTEGER NOT NULL,
+    user2_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user1_id) REFERENCES users(id),
+    FOREIGN KEY (user2_id) REFERENCES users(id),
+    UNIQUE (user1_id, user2_id)
+);
+
+CREATE TABLE messages (
+    id SERIAL PRIMARY KEY,
+    conversation_id INTEGER NOT NULL,
+    sender_id INTEGER NOT NULL,
+    receiver_id INTEGER NOT NULL,
+    content TEXT NOT NULL,
+    hidden_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (conversation_id) REFERENCES conversations(id),
+    FOREIGN KEY (sender_id) REFERENCES users(id)
+);
+
+CREATE TABLE notifications (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    type VARCHAR(255) NOT NULL,
+    message TEXT NOT NULL,
+    event_link VARCHAR(255) NOT NULL,
+    read BOOLEAN NOT NULL DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (user_id) REFERENCES users(id)
+);
+
+CREATE TABLE businesses (
+    id SERIAL PRIMARY KEY,
+    owner_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    category TEXT,
+    logo VARCHAR(500),
+    cover_image VARCHAR(500),
+    website VARCHAR(255),
+    contact VARCHAR(20),
+    location TEXT,
+    badges INTEGER[],
+    topics INTEGER[],
+    rating NUMERIC(10, 8) DEFAULT 0 CHECK(rating >= 0 AND rating <= 5),
+    hidden_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (owner_id) REFERENCES users(id)
+);
+
+CREATE TABLE moderation_cases (
+    id SERIAL PRIMARY KEY,
+    target_type VARCHAR(20) NOT NULL CHECK(target_type IN ('post', 'comment', 'message', 'business', 'user')),
+    target_id INTEGER NOT NULL,
+    author_id INTEGER,
+    status VARCHAR(20) NOT NULL DEFAULT 'open' CHECK(status IN ('open', 'resolved')),
+    resolution VARCHAR(20) CHECK(resolution IN ('dismissed', 'hidden', 'suspended')),
+    report_count INTEGER NOT NULL DEFAULT 0,
+    note TEXT,
+    resolved_by INTEGER,
+    resolved_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (author_id) REFERENCES users(id) ON DELETE SET NULL,
+    FOREIGN KEY (resolved_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+-- Only one open case per item, new reports on the item are added to it
+CREATE UNIQUE INDEX moderation_cases_open_target_idx ON moderation_cases (target_type, target_id) WHERE status = 'open';
+
+CREATE TABLE reports (
+    id SERIAL PRIMARY KEY,
+    case_id INTEGER NOT NULL,
+    reporter_id INTEGER NOT NULL,
+    reason VARCHAR(30) NOT NULL,
+    details TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (case_id) REFERENCES moderation_cases(id) ON DELETE CASCADE,
+    FOREIGN KEY (reporter_id) REFERENCES users(id) ON DELETE CASCADE,
+    UNIQUE (case_id, reporter_id)
+);
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS page_followers;
+DROP TABLE IF EXISTS products;
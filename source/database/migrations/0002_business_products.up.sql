@@ -0,0 +1,25 @@
+CREATE TABLE products (
+    id SERIAL PRIMARY KEY,
+    business_page_id INTEGER NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    price NUMERIC(12, 2) CHECK(price >= 0),
+    images TEXT[],
+    category TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    FOREIGN KEY (business_page_id) REFERENCES businesses(id) ON DELETE CASCADE
+);
+
+CREATE INDEX products_business_page_idx ON products (business_page_id);
+
+-- Join table of the many2many Followers relation of business pages
+CREATE TABLE page_followers (
+    business_page_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    PRIMARY KEY (business_page_id, user_id),
+    FOREIGN KEY (business_page_id) REFERENCES businesses(id) ON DELETE CASCADE,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX page_followers_user_idx ON page_followers (user_id);
@@ -0,0 +1,3 @@
+-- Emails are stored lower case, the index keeps two accounts from differing only in case
+UPDATE users SET email = LOWER(TRIM(email)) WHERE email <> LOWER(TRIM(email));
+CREATE UNIQUE INDEX users_email_lower_idx ON users (LOWER(email));
@@ -0,0 +1,2 @@
+-- Guesses are counted on each code instead of on the account, so nobody can lock another user out
+ALTER TABLE one_time_codes ADD COLUMN attempts INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,2 @@
+ALTER TABLE products DROP COLUMN IF EXISTS image_variants;
+ALTER TABLE businesses DROP COLUMN IF EXISTS logo_variants, DROP COLUMN IF EXISTS cover_image_variants;
@@ -0,0 +1,3 @@
+-- Size variants of the images of business pages and products, like users.avatar_variants
+ALTER TABLE businesses ADD COLUMN logo_variants JSONB, ADD COLUMN cover_image_variants JSONB;
+ALTER TABLE products ADD COLUMN image_variants JSONB;
@@ -0,0 +1 @@
+ALTER TABLE users DROP COLUMN IF EXISTS otp_code_failures, DROP COLUMN IF EXISTS otp_locked_until;
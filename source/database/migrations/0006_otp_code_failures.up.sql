@@ -0,0 +1,2 @@
+-- Wrong emailed codes are also counted on the user, so resending a code does not reset the guesses
+ALTER TABLE users ADD COLUMN otp_code_failures INTEGER DEFAULT 0, ADD COLUMN otp_locked_until TIMESTAMP;
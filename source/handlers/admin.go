@@ -0,0 +1,293 @@
+package handlers
+
+import (
+	"strconv"
+	"time"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/template"
+	"cnep-backend/source/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+/*
+The `SetUserRole` function is a handler function that lets an admin change the role of a user.
+
+Returns:
+
+	A JSON response with the updated user, or an error message if the role or user is not valid.
+*/
+func SetUserRole(admin *services.AdminService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Get the user ID from the context (set by the AuthMiddleware)
+		adminID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		userID, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return apierror.BadRequest("Invalid user ID format")
+		}
+
+		var input struct {
+			Role string `json:"role"`
+		}
+
+		if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		user, err := admin.SetRole(c.UserContext(), adminID, uint(userID), input.Role)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(user)
+	}
+}
+
+/*
+The `SearchUsers` function is a handler function that lets admins and moderators search users.
+It takes the `q`, `role` and `status` filters and the list query parameters.
+
+Returns:
+
+	A JSON response with the matching users and the total count.
+*/
+func SearchUsers(admin *services.AdminService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, err := listPage(c, services.UsersList)
+		if err != nil {
+			return invalidPage(err)
+		}
+
+		users, total, next, err := admin.SearchUsers(page)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":      "ok",
+			"users":       users,
+			"total":       total,
+			"next_cursor": next,
+		})
+	}
+}
+
+/*
+The `GetUserForAdmin` function is a handler function that returns the full profile of a user
+for admins and moderators, including the verification and suspension state.
+*/
+func GetUserForAdmin(admin *services.AdminService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		actorID, userID, err := adminTarget(c)
+		if err != nil {
+			return err
+		}
+
+		user, err := admin.GetUser(c.UserContext(), actorID, userID)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(user)
+	}
+}
+
+/*
+The `SuspendUser` function is a handler function that suspends a user until the given time.
+The body takes a `reason` and an `until` timestamp in RFC 3339 format.
+*/
+func SuspendUser(admin *services.AdminService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Get the user ID from the context (set by the AuthMiddleware)
+		actorID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+		actorRole, _ := c.Locals("role").(string)
+
+		userID, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return apierror.BadRequest("Invalid user ID format")
+		}
+
+		var input struct {
+			Reason string    `json:"reason"`
+			Until  time.Time `json:"until"`
+		}
+
+		if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		if input.Until.IsZero() {
+			return apierror.BadRequest("Suspension end is required")
+		}
+
+		user, err := admin.Suspend(c.UserContext(), actorID, actorRole, uint(userID), input.Reason, &input.Until)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(user)
+	}
+}
+
+/*
+The `BanUser` function is a handler function that bans a user indefinitely.
+The body takes a `reason`.
+*/
+func BanUser(admin *services.AdminService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Get the user ID from the context (set by the AuthMiddleware)
+		actorID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+		actorRole, _ := c.Locals("role").(string)
+
+		userID, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return apierror.BadRequest("Invalid user ID format")
+		}
+
+		var input struct {
+			Reason string `json:"reason"`
+		}
+
+		if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		user, err := admin.Suspend(c.UserContext(), actorID, actorRole, uint(userID), input.Reason, nil)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(user)
+	}
+}
+
+/*
+The `ReinstateUser` function is a handler function that lifts the suspension or ban of a user.
+*/
+func ReinstateUser(admin *services.AdminService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		actorID, userID, err := adminTarget(c)
+		if err != nil {
+			return err
+		}
+
+		user, err := admin.Reinstate(c.UserContext(), actorID, userID)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(user)
+	}
+}
+
+/*
+The `VerifyUserEmail` function is a handler function that marks the email of a user as verified.
+*/
+func VerifyUserEmail(admin *services.AdminService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		actorID, userID, err := adminTarget(c)
+		if err != nil {
+			return err
+		}
+
+		user, err := admin.VerifyEmail(c.UserContext(), actorID, userID)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(user)
+	}
+}
+
+/*
+The `ResendUserOTP` function is a handler function that sends a new signup OTP to an unverified user.
+*/
+func ResendUserOTP(admin *services.AdminService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		actorID, userID, err := adminTarget(c)
+		if err != nil {
+			return err
+		}
+
+		if err := admin.ResendOTP(c.UserContext(), actorID, userID); err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "OTP sent"})
+	}
+}
+
+/*
+The `ImpersonateUser` function is a handler function that starts a session as another user.
+
+Returns:
+
+	A JSON response with an access token for the user. It cannot be refreshed.
+*/
+func ImpersonateUser(admin *services.AdminService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		actorID, userID, err := adminTarget(c)
+		if err != nil {
+			return err
+		}
+
+		result, err := admin.Impersonate(c.UserContext(), actorID, userID)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(result)
+	}
+}
+
+/*
+The `GetAuditLogs` function is a handler function that lists the audit log.
+It takes the `actor_id`, `action`, `target_type` and `target_id` filters and the list query parameters.
+*/
+func GetAuditLogs(admin *services.AdminService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, err := listPage(c, services.AuditLogList)
+		if err != nil {
+			return invalidPage(err)
+		}
+
+		entries, total, next, err := admin.AuditLogs(page)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":      "ok",
+			"entries":     entries,
+			"total":       total,
+			"next_cursor": next,
+		})
+	}
+}
+
+// adminTarget returns the acting user and the user in the `id` parameter of an admin action.
+func adminTarget(c *fiber.Ctx) (uint, uint, error) {
+	// Get the user ID from the context (set by the AuthMiddleware)
+	actorID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return 0, 0, template.Unauthenticated(c)
+	}
+
+	userID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return 0, 0, apierror.BadRequest("Invalid user ID format")
+	}
+
+	return actorID, uint(userID), nil
+}
@@ -1,6 +1,10 @@
 package handlers
 
 import (
+	"crypto/subtle"
+	"time"
+
+	"cnep-backend/pkg/apierror"
 	"cnep-backend/source/services"
 
 	"github.com/gofiber/fiber/v2"
@@ -14,11 +18,16 @@ Returns:
 
 	A JSON response with a success message if the user exists, or an error message if the user does not exist.
 */
-func CheckEmailExistence() fiber.Handler {
+func CheckEmailExistence(auth *services.AuthService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		email := c.Query("email")
 
-		return services.CheckEmailExistence(c, email)
+		exists, err := auth.EmailExists(email)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(fiber.Map{"exists": exists})
 	}
 }
 
@@ -29,7 +38,7 @@ Returns:
 
 	A JSON response with a success message if the user is registered, or an error message if the user cannot be registered.
 */
-func Authentication() fiber.Handler {
+func Authentication(auth *services.AuthService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var input struct {
 			Email    string `json:"email"`
@@ -38,13 +47,161 @@ func Authentication() fiber.Handler {
 		}
 
 		if err := c.BodyParser(&input); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return apierror.BadRequest("Cannot parse JSON")
 		}
 
 		if input.IsNew {
-			return services.RegisterService(c, input.Email, input.Password)
+			if err := auth.Register(c.UserContext(), input.Email, input.Password); err != nil {
+				return err
+			}
+
+			return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+				"message": "User registered. Please verify your email with the OTP sent.",
+			})
+		}
+
+		result, err := auth.Login(c.UserContext(), input.Email, input.Password)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(result)
+	}
+}
+
+/*
+The `RefreshToken` function is a handler function that exchanges a refresh token for a new token pair.
+
+Returns:
+
+	A JSON response with the new access and refresh tokens, or an error message if the refresh token is not valid.
+*/
+func RefreshToken(sessions *services.SessionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+
+		if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		pair, err := sessions.Refresh(c.UserContext(), input.RefreshToken)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(pair)
+	}
+}
+
+/*
+The `Logout` function is a handler function that ends the session the given refresh token belongs to.
+
+Returns:
+
+	A JSON response with a success message if the session is ended, or an error message if the refresh token is not valid.
+*/
+func Logout(sessions *services.SessionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input struct {
+			RefreshToken string `json:"refresh_token"`
+		}
+
+		if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		if err := sessions.Logout(input.RefreshToken); err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Logged out successfully",
+		})
+	}
+}
+
+// oidcStateCookie binds a login with a provider to the browser that started it.
+const oidcStateCookie = "oidc_state"
+
+// setOIDCStateCookie sets the state cookie, or clears it when state is empty.
+// It is sent back on the redirect from the provider, which is a cross-site navigation, so it is SameSite Lax.
+func setOIDCStateCookie(c *fiber.Ctx, state string) {
+	expires := time.Now().Add(services.OAuthStateTTL)
+	if state == "" {
+		expires = time.Unix(0, 0)
+	}
+
+	c.Cookie(&fiber.Cookie{
+		Name:     oidcStateCookie,
+		Value:    state,
+		Path:     "/api/auth/oidc",
+		Expires:  expires,
+		Secure:   c.Protocol() == "https",
+		HTTPOnly: true,
+		SameSite: fiber.CookieSameSiteLaxMode,
+	})
+}
+
+/*
+The `StartOIDCLogin` function is a handler function that starts a login with an external OpenID Connect provider.
+The state is also set in an HttpOnly cookie, which the callback checks, so a login link
+started by someone else cannot be completed in another browser.
+
+Returns:
+
+	A JSON response with the provider authorization URL to open, or an error message if the provider is unknown.
+*/
+func StartOIDCLogin(oidc *services.OIDCService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		login, err := oidc.Start(c.Params("provider"))
+		if err != nil {
+			return err
+		}
+
+		setOIDCStateCookie(c, login.State)
+		return c.Status(fiber.StatusOK).JSON(login)
+	}
+}
+
+/*
+The `OIDCCallback` function is a handler function that completes a login with an external OpenID Connect provider.
+The code and state can be sent as query parameters, when the provider redirects straight to the API,
+or as a JSON body, when a frontend receives the redirect and forwards them.
+Either way the state must match the cookie set when the login started.
+
+Returns:
+
+	A JSON response with the access and refresh tokens, or an error message if the login cannot be verified.
+*/
+func OIDCCallback(oidc *services.OIDCService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input struct {
+			Code  string `json:"code" query:"code"`
+			State string `json:"state" query:"state"`
+		}
+
+		if c.Method() == fiber.MethodGet {
+			if err := c.QueryParser(&input); err != nil {
+				return apierror.BadRequest("Cannot parse query")
+			}
+		} else if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		// The state can only be used once, so the cookie is cleared whatever the outcome
+		bound := c.Cookies(oidcStateCookie)
+		setOIDCStateCookie(c, "")
+		if bound == "" || subtle.ConstantTimeCompare([]byte(bound), []byte(input.State)) != 1 {
+			return apierror.BadRequest("Login was started in another browser")
+		}
+
+		result, err := oidc.Complete(c.UserContext(), c.Params("provider"), input.Code, input.State)
+		if err != nil {
+			return err
 		}
 
-		return services.LoginService(c, input.Email, input.Password)
+		return c.JSON(result)
 	}
 }
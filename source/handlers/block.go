@@ -0,0 +1,99 @@
+package handlers
+
+import (
+	"strconv"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/template"
+	"cnep-backend/source/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+/*
+The `BlockUser` function is a handler function that blocks the user given in the request body.
+It also cancels any partner link with that user.
+*/
+func BlockUser(blocks *services.BlockService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input struct {
+			UserID uint `json:"user_id"`
+		}
+
+		// Get the user ID from the context (set by the AuthMiddleware)
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		if err := blocks.Block(userID, input.UserID); err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":  "ok",
+			"message": "User blocked",
+		})
+	}
+}
+
+/*
+The `UnblockUser` function is a handler function that removes the block of the user given in the URL.
+*/
+func UnblockUser(blocks *services.BlockService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Validate that the user ID is an integer
+		blockedID, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return apierror.BadRequest("Invalid user ID format")
+		}
+
+		// Get the user ID from the context (set by the AuthMiddleware)
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		if err := blocks.Unblock(userID, uint(blockedID)); err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":  "ok",
+			"message": "User unblocked",
+		})
+	}
+}
+
+/*
+The `GetBlockedUsers` function is a handler function that lists the users blocked by the authenticated user.
+*/
+func GetBlockedUsers(blocks *services.BlockService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Get the user ID from the context (set by the AuthMiddleware)
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		page, err := listPage(c, services.BlocksList)
+		if err != nil {
+			return invalidPage(err)
+		}
+
+		users, next, err := blocks.List(userID, page)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":      "ok",
+			"blocked":     users,
+			"next_cursor": next,
+		})
+	}
+}
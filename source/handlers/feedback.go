@@ -1,8 +1,10 @@
 package handlers
 
 import (
+	"cnep-backend/pkg/apierror"
 	"cnep-backend/source/services"
 	"cnep-backend/pkg/template"
+	"cnep-backend/pkg/utils"
 	"github.com/gofiber/fiber/v2"
 	"strconv"
 )
@@ -13,7 +15,7 @@ It takes the user IDs from the context, the feedback content, and the rating fro
 If the user is not found or any other error occurs, it returns an appropriate error message.
 The function returns a JSON response with the feedback.
 */
-func AddFeedback() fiber.Handler {
+func AddFeedback(feedback *services.FeedbackService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var input struct {
 			UserID  uint   `json:"user_id"`
@@ -27,14 +29,22 @@ func AddFeedback() fiber.Handler {
 		}
 
 		if err := c.BodyParser(&input); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return apierror.BadRequest("Cannot parse JSON")
 		}
 
-		return services.AddFeedback(c, userID, input.UserID, input.Content, input.Rating)
+		feedback, err := feedback.Add(userID, input.UserID, input.Content, input.Rating)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message":  "Feedback created successfully",
+			"feedback": feedback,
+		})
 	}
 }
 
-func GetFeedback() fiber.Handler {
+func GetFeedback(feedback *services.FeedbackService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 
 		userID, ok := c.Locals("userID").(uint)
@@ -42,23 +52,52 @@ func GetFeedback() fiber.Handler {
 			return template.Unauthenticated(c)
 		}
 
-		return services.GetFeedbackByUserID(c, userID)
+		page, err := listPage(c, services.FeedbackList)
+		if err != nil {
+			return invalidPage(err)
+		}
+
+		return feedbackList(c, feedback, userID, userID, page)
 	}
 }
 
-func GetFeedbackByID() fiber.Handler {
+func GetFeedbackByID(feedback *services.FeedbackService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		UserID := c.Params("id")
 
 		if UserID == "" {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "User ID not provided in query"})
+			return apierror.BadRequest("User ID not provided in query")
 		}
 
 		IntUserID, err := strconv.Atoi(UserID)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID format"})
+			return apierror.BadRequest("Invalid user ID format")
 		}
 		UintUserID := uint(IntUserID)
-		return services.GetFeedbackByUserID(c, UintUserID)
+
+		viewerID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		page, err := listPage(c, services.FeedbackList)
+		if err != nil {
+			return invalidPage(err)
+		}
+		return feedbackList(c, feedback, viewerID, UintUserID, page)
 	}
 }
+
+// feedbackList writes the page of feedback received by the user, as seen by the viewer.
+func feedbackList(c *fiber.Ctx, feedback *services.FeedbackService, viewerID, userID uint, page utils.Page) error {
+	feedbacks, next, err := feedback.List(viewerID, userID, page)
+	if err != nil {
+		return err
+	}
+
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"status":      "success",
+		"feedbacks":   feedbacks,
+		"next_cursor": next,
+	})
+}
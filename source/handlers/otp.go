@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"cnep-backend/pkg/apierror"
 	"cnep-backend/source/services"
 
 	"github.com/gofiber/fiber/v2"
@@ -18,17 +19,23 @@ Returns:
 
 	A JSON response with a success message if the OTP is regenerated, or an error message if the OTP cannot be regenerated.
 */
-func RegenerateOTP() fiber.Handler {
+func RegenerateOTP(auth *services.AuthService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var input struct {
 			Email string `json:"email"`
 		}
 
 		if err := c.BodyParser(&input); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return apierror.BadRequest("Cannot parse JSON")
 		}
 
-		return services.OTPRegenerate(c, input.Email)
+		if err := auth.RegenerateOTP(input.Email); err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "If the email belongs to an unverified account, a new OTP has been sent.",
+		})
 	}
 }
 
@@ -49,7 +56,7 @@ Returns:
 
 	A JSON response with a success message if the OTP is verified, or an error message if the OTP is invalid or expired.
 */
-func VerifyOTP() fiber.Handler {
+func VerifyOTP(auth *services.AuthService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var input struct {
 			Email string `json:"email"`
@@ -57,9 +64,14 @@ func VerifyOTP() fiber.Handler {
 		}
 
 		if err := c.BodyParser(&input); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		result, err := auth.ValidateOTP(c.UserContext(), input.OTP, input.Email)
+		if err != nil {
+			return err
 		}
 
-		return services.ValidateOTP(c, input.OTP, input.Email)
+		return c.JSON(result)
 	}
 }
@@ -0,0 +1,21 @@
+package handlers
+
+import (
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/utils"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+// listPage reads the `limit`, `cursor`, `sort` and `order` query parameters
+// and the filters of a list endpoint.
+func listPage(c *fiber.Ctx, spec utils.ListSpec) (utils.Page, error) {
+	return utils.ParsePage(spec, func(key string) string {
+		return c.Query(key)
+	})
+}
+
+// invalidPage returns the error of a request whose list query parameters are not valid.
+func invalidPage(err error) error {
+	return apierror.BadRequest("Invalid list parameters: " + err.Error())
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"cnep-backend/pkg/apierror"
 	"cnep-backend/pkg/template"
 	"cnep-backend/source/services"
 
@@ -8,7 +9,7 @@ import (
 	"strconv"
 )
 
-func AddPartner() fiber.Handler {
+func AddPartner(partners *services.PartnerService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		var input struct {
 			UserID uint `json:"user_id"`
@@ -21,14 +22,29 @@ func AddPartner() fiber.Handler {
 		}
 
 		if err := c.BodyParser(&input); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return apierror.BadRequest("Cannot parse JSON")
 		}
 
-		return services.AddPartner(c, userID, input.UserID)
+		accepted, err := partners.Request(userID, input.UserID)
+		if err != nil {
+			return err
+		}
+
+		if accepted {
+			return c.Status(fiber.StatusOK).JSON(fiber.Map{
+				"status":  "ok",
+				"message": "Partner request accepted",
+			})
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"status":  "ok",
+			"message": "Partner Request Created",
+		})
 	}
 }
 
-func UpdatePartnerStatus() fiber.Handler {
+func UpdatePartnerStatus(partners *services.PartnerService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Get the user ID from the URL parameter
 		PartnerIDParam := c.Params("id")
@@ -36,7 +52,7 @@ func UpdatePartnerStatus() fiber.Handler {
 		// Validate that the user ID is an integer
 		partnerID, err := strconv.Atoi(PartnerIDParam)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID format"})
+			return apierror.BadRequest("Invalid user ID format")
 		}
 
 		var input struct {
@@ -50,14 +66,21 @@ func UpdatePartnerStatus() fiber.Handler {
 		}
 
 		if err := c.BodyParser(&input); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		if err := partners.Respond(userID, uint(partnerID), input.Accept); err != nil {
+			return err
 		}
 
-		return services.UpdatePartnerStatus(c, userID, uint(partnerID), input.Accept)
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":  "ok",
+			"message": "Partner status updated",
+		})
 	}
 }
 
-func CancelPartnerRequest() fiber.Handler {
+func CancelPartnerRequest(partners *services.PartnerService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Get the user ID from the URL parameter
 		PartnerIDParam := c.Params("id")
@@ -65,7 +88,7 @@ func CancelPartnerRequest() fiber.Handler {
 		// Validate that the user ID is an integer
 		partnerID, err := strconv.Atoi(PartnerIDParam)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID format"})
+			return apierror.BadRequest("Invalid user ID format")
 		}
 
 		// Get the user ID from the context (set by the AuthMiddleware)
@@ -74,30 +97,179 @@ func CancelPartnerRequest() fiber.Handler {
 			return template.Unauthenticated(c)
 		}
 
-		return services.CancelPartnerRequest(c, userID, uint(partnerID))
+		if err := partners.Cancel(userID, uint(partnerID)); err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":  "ok",
+			"message": "Partner request cancelled",
+		})
 	}
 }
 
-func GetPartners() fiber.Handler {
+func RemovePartner(partners *services.PartnerService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		// Validate that the user ID is an integer
+		partnerID, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return apierror.BadRequest("Invalid user ID format")
+		}
+
 		// Get the user ID from the context (set by the AuthMiddleware)
 		userID, ok := c.Locals("userID").(uint)
 		if !ok {
 			return template.Unauthenticated(c)
 		}
 
-		return services.GetPartners(c, userID)
+		if err := partners.Remove(userID, uint(partnerID)); err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":  "ok",
+			"message": "Partner removed",
+		})
 	}
 }
 
-func GetPendingPartners() fiber.Handler {
+func GetPartnerStatus(partners *services.PartnerService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		// Validate that the user ID is an integer
+		otherID, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return apierror.BadRequest("Invalid user ID format")
+		}
+
 		// Get the user ID from the context (set by the AuthMiddleware)
 		userID, ok := c.Locals("userID").(uint)
 		if !ok {
 			return template.Unauthenticated(c)
 		}
 
-		return services.GetPendingPartners(c, userID)
+		status, err := partners.Status(userID, uint(otherID))
+		if err != nil {
+			return err
+		}
+
+		response := fiber.Map{
+			"status":       "ok",
+			"relationship": status.Relationship,
+			"can_request":  status.CanRequest,
+		}
+		if status.RetryAfter > 0 {
+			response["retry_after"] = status.RetryAfter
+		}
+
+		return c.Status(fiber.StatusOK).JSON(response)
+	}
+}
+
+func GetPartners(partners *services.PartnerService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Get the user ID from the context (set by the AuthMiddleware)
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		page, err := listPage(c, services.PartnersList)
+		if err != nil {
+			return invalidPage(err)
+		}
+
+		users, next, err := partners.List(userID, page)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":      "ok",
+			"partners":    users,
+			"next_cursor": next,
+		})
+	}
+}
+
+func GetPendingPartners(partners *services.PartnerService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Get the user ID from the context (set by the AuthMiddleware)
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		page, err := listPage(c, services.PartnerRequestsList)
+		if err != nil {
+			return invalidPage(err)
+		}
+
+		users, next, err := partners.ListIncoming(userID, page)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":      "ok",
+			"partners":    users,
+			"next_cursor": next,
+		})
+	}
+}
+
+func GetOutgoingPartnerRequests(partners *services.PartnerService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Get the user ID from the context (set by the AuthMiddleware)
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		page, err := listPage(c, services.PartnerRequestsList)
+		if err != nil {
+			return invalidPage(err)
+		}
+
+		users, next, err := partners.ListOutgoing(userID, page)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":      "ok",
+			"partners":    users,
+			"next_cursor": next,
+		})
+	}
+}
+
+/*
+The `GetPartnerSuggestions` function is a handler function that returns a page of people the authenticated user may know.
+It takes the limit and cursor from the query string.
+*/
+func GetPartnerSuggestions(partners *services.PartnerService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Get the user ID from the context (set by the AuthMiddleware)
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		page, err := listPage(c, services.SuggestionsList)
+		if err != nil {
+			return invalidPage(err)
+		}
+
+		suggestions, total, next, err := partners.Suggestions(userID, page)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":      "ok",
+			"suggestions": suggestions,
+			"total":       total,
+			"next_cursor": next,
+		})
 	}
 }
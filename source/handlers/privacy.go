@@ -0,0 +1,66 @@
+package handlers
+
+import (
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/template"
+	"cnep-backend/source/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+/*
+The `GetPrivacySettings` function is a handler function that returns the profile privacy settings of the authenticated user.
+*/
+func GetPrivacySettings(users *services.UserService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Get the user ID from the context (set by the AuthMiddleware)
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		privacy, err := users.GetPrivacySettings(userID)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":  "ok",
+			"privacy": privacy,
+		})
+	}
+}
+
+/*
+The `UpdatePrivacySettings` function is a handler function that changes who can see the email, phone and address of the authenticated user.
+Each setting is one of public, partners or private. Settings left out of the request body are not changed.
+*/
+func UpdatePrivacySettings(users *services.UserService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input struct {
+			EmailVisibility   *string `json:"email_visibility"`
+			PhoneVisibility   *string `json:"phone_visibility"`
+			AddressVisibility *string `json:"address_visibility"`
+		}
+
+		// Get the user ID from the context (set by the AuthMiddleware)
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		privacy, err := users.UpdatePrivacySettings(userID, input.EmailVisibility, input.PhoneVisibility, input.AddressVisibility)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":  "ok",
+			"privacy": privacy,
+		})
+	}
+}
@@ -0,0 +1,188 @@
+package handlers
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/consts"
+	"cnep-backend/pkg/template"
+	"cnep-backend/source/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+/*
+The `ReportContent` function is a handler function that lets users report a post, comment, message, business page or user.
+The body takes the `target_type`, `target_id`, a `reason` category and optional `details`.
+*/
+func ReportContent(moderation *services.ModerationService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Get the user ID from the context (set by the AuthMiddleware)
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		var input struct {
+			TargetType string `json:"target_type"`
+			TargetID   uint   `json:"target_id"`
+			Reason     string `json:"reason"`
+			Details    string `json:"details"`
+		}
+
+		if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		if err := moderation.Report(userID, input.TargetType, input.TargetID, input.Reason, input.Details); err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"message": "Report received. Thank you for helping keep the community safe.",
+		})
+	}
+}
+
+/*
+The `GetModerationQueue` function is a handler function that lists moderation cases.
+It takes the `status` and `target_type` filters and the list query parameters.
+*/
+func GetModerationQueue(moderation *services.ModerationService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		page, err := listPage(c, services.ModerationQueueList(c.Query("status")))
+		if err != nil {
+			return invalidPage(err)
+		}
+
+		cases, total, next, err := moderation.Queue(page)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":      "ok",
+			"cases":       cases,
+			"total":       total,
+			"next_cursor": next,
+		})
+	}
+}
+
+/*
+The `GetModerationCase` function is a handler function that returns a moderation case with its reports and the reported item.
+*/
+func GetModerationCase(moderation *services.ModerationService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		caseID, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return apierror.BadRequest("Invalid case ID format")
+		}
+
+		moderationCase, err := moderation.Case(uint(caseID))
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(moderationCase)
+	}
+}
+
+/*
+The `DismissCase` function is a handler function that closes a moderation case without action.
+The body takes an optional `note`.
+*/
+func DismissCase(moderation *services.ModerationService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return caseAction(c, consts.CASE_RESOLUTION_DISMISSED, moderation.Dismiss)
+	}
+}
+
+/*
+The `HideCaseContent` function is a handler function that hides the reported item and closes the case.
+The body takes an optional `note`.
+*/
+func HideCaseContent(moderation *services.ModerationService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		return caseAction(c, consts.CASE_RESOLUTION_HIDDEN, moderation.Hide)
+	}
+}
+
+/*
+The `SuspendCaseAuthor` function is a handler function that suspends the author of the reported item and closes the case.
+The body takes a `reason`, an `until` timestamp in RFC 3339 format, an optional `note`,
+and `hide_content` to also hide the item. Admins may leave out `until` to ban the author.
+*/
+func SuspendCaseAuthor(moderation *services.ModerationService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Get the user ID from the context (set by the AuthMiddleware)
+		actorID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+		actorRole, _ := c.Locals("role").(string)
+
+		caseID, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return apierror.BadRequest("Invalid case ID format")
+		}
+
+		var input struct {
+			Note        string     `json:"note"`
+			Reason      string     `json:"reason"`
+			Until       *time.Time `json:"until"`
+			HideContent bool       `json:"hide_content"`
+		}
+
+		if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		if err := moderation.SuspendAuthor(c.UserContext(), actorID, actorRole, uint(caseID), input.Note, input.Reason, input.Until, input.HideContent); err != nil {
+			return err
+		}
+
+		return caseResolved(c, consts.CASE_RESOLUTION_SUSPENDED)
+	}
+}
+
+// caseAction runs a moderation action that takes the acting user, the case in the `id` parameter and a note,
+// and closes the case with the given resolution.
+func caseAction(c *fiber.Ctx, resolution string, action func(ctx context.Context, actorID, caseID uint, note string) error) error {
+	// Get the user ID from the context (set by the AuthMiddleware)
+	actorID, ok := c.Locals("userID").(uint)
+	if !ok {
+		return template.Unauthenticated(c)
+	}
+
+	caseID, err := strconv.Atoi(c.Params("id"))
+	if err != nil {
+		return apierror.BadRequest("Invalid case ID format")
+	}
+
+	var input struct {
+		Note string `json:"note"`
+	}
+
+	// The note is optional, so an empty body is fine
+	if len(c.Body()) > 0 {
+		if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+	}
+
+	if err := action(c.UserContext(), actorID, uint(caseID), input.Note); err != nil {
+		return err
+	}
+
+	return caseResolved(c, resolution)
+}
+
+func caseResolved(c *fiber.Ctx, resolution string) error {
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{
+		"message":    "Case resolved",
+		"resolution": resolution,
+	})
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"cnep-backend/pkg/apierror"
 	"cnep-backend/source/services"
 	"cnep-backend/pkg/template"
 	"github.com/gofiber/fiber/v2"
@@ -9,10 +10,11 @@ import (
 /*
 The `ChangePassword` function is a handler function that allows users to change their password.
 It takes the user ID from the context, the updated data from the request body, and the database connection.
+Setting `logout_other_sessions` in the body signs the user out of every other session.
 If the user is not found or any other error occurs, it returns an appropriate error message.
 The function returns a JSON response with the updated user profile.
 */
-func ChangePassword() fiber.Handler {
+func ChangePassword(account *services.AccountService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Get the user ID from the context (set by the AuthMiddleware)
 		userID, ok := c.Locals("userID").(uint)
@@ -20,15 +22,135 @@ func ChangePassword() fiber.Handler {
 			return template.Unauthenticated(c)
 		}
 
+		sessionID, _ := c.Locals("sessionID").(uint)
+
+		var input struct {
+			OldPassword         string `json:"old_password"`
+			NewPassword         string `json:"new_password"`
+			LogoutOtherSessions bool   `json:"logout_other_sessions"`
+		}
+
+		if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		revoked, err := account.ChangePassword(userID, sessionID, input.OldPassword, input.NewPassword, input.LogoutOtherSessions)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message":          "Password successfully updated",
+			"sessions_revoked": revoked,
+		})
+	}
+}
+
+/*
+The `ForgotPassword` function is a handler function that sends a password reset code to the given email.
+*/
+func ForgotPassword(auth *services.AuthService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
 		var input struct {
-			OldPassword string `json:"old_password"`
+			Email string `json:"email"`
+		}
+
+		if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		if err := auth.RequestPasswordReset(input.Email); err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "If an account exists for this email, a password reset code has been sent.",
+		})
+	}
+}
+
+/*
+The `ResetPassword` function is a handler function that sets a new password using the emailed reset code.
+*/
+func ResetPassword(auth *services.AuthService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input struct {
+			Email       string `json:"email"`
+			OTP         string `json:"otp"`
 			NewPassword string `json:"new_password"`
 		}
 
 		if err := c.BodyParser(&input); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Cannot parse JSON"})
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		if err := auth.ResetPassword(input.Email, input.OTP, input.NewPassword); err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Password reset successfully. Please log in with your new password.",
+		})
+	}
+}
+
+/*
+The `ChangeEmail` function is a handler function that starts changing the email of the authenticated user.
+The current password must be provided, and an OTP is sent to the new email.
+*/
+func ChangeEmail(account *services.AccountService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		var input struct {
+			NewEmail string `json:"new_email"`
+			Password string `json:"password"`
+		}
+
+		if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		if err := account.RequestEmailChange(userID, input.NewEmail, input.Password); err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "OTP sent. Please verify your new email with the OTP sent.",
+		})
+	}
+}
+
+/*
+The `VerifyEmailChange` function is a handler function that completes an email change with the OTP
+sent to the new email.
+*/
+func VerifyEmailChange(account *services.AccountService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		var input struct {
+			OTP string `json:"otp"`
+		}
+
+		if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		email, err := account.ConfirmEmailChange(userID, input.OTP)
+		if err != nil {
+			return err
 		}
 
-		return services.ChangePassword(c, userID, input.OldPassword, input.NewPassword)
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message": "Email changed successfully",
+			"email":   email,
+		})
 	}
 }
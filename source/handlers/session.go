@@ -0,0 +1,89 @@
+package handlers
+
+import (
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/template"
+	"cnep-backend/source/services"
+
+	"github.com/gofiber/fiber/v2"
+	"strconv"
+)
+
+/*
+The `GetSessions` function is a handler function that lists the active sessions of the authenticated user
+with device, IP, user agent and last seen time. The session of the current request is marked as `current`.
+*/
+func GetSessions(sessions *services.SessionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+		sessionID, _ := c.Locals("sessionID").(uint)
+
+		sessions, err := sessions.List(userID, sessionID)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":   "ok",
+			"sessions": sessions,
+		})
+	}
+}
+
+/*
+The `RevokeSession` function is a handler function that signs the authenticated user out of a single session.
+*/
+func RevokeSession(sessions *services.SessionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Get the session ID from the URL parameter
+		sessionIDParam := c.Params("id")
+
+		// Validate that the session ID is an integer
+		sessionID, err := strconv.Atoi(sessionIDParam)
+		if err != nil {
+			return apierror.BadRequest("Invalid session ID format")
+		}
+
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		if err := sessions.Revoke(userID, uint(sessionID)); err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":  "ok",
+			"message": "Session revoked",
+		})
+	}
+}
+
+/*
+The `RevokeOtherSessions` function is a handler function that signs the authenticated user out of
+every session except the one the request was made with.
+*/
+func RevokeOtherSessions(sessions *services.SessionService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+		sessionID, _ := c.Locals("sessionID").(uint)
+
+		revoked, err := sessions.RevokeOthers(userID, sessionID)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":  "ok",
+			"message": "Other sessions revoked",
+			"revoked": revoked,
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/template"
+	"cnep-backend/source/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+/*
+The `SetupTwoFactor` function is a handler function that starts TOTP enrollment for the authenticated user.
+
+Returns:
+
+	A JSON response with the TOTP secret and the otpauth:// URI to show as a QR code.
+*/
+func SetupTwoFactor(twoFactor *services.TwoFactorService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Get the user ID from the context (set by the AuthMiddleware)
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		setup, err := twoFactor.Setup(userID)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(setup)
+	}
+}
+
+/*
+The `ConfirmTwoFactor` function is a handler function that enables two-factor authentication
+once the user enters a code from their authenticator app.
+
+Returns:
+
+	A JSON response with the one-time recovery codes, or an error message if the code is wrong.
+*/
+func ConfirmTwoFactor(twoFactor *services.TwoFactorService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Get the user ID from the context (set by the AuthMiddleware)
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		var input struct {
+			Code string `json:"code"`
+		}
+
+		if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		recoveryCodes, err := twoFactor.Confirm(userID, input.Code)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"message":        "Two-factor authentication enabled",
+			"recovery_codes": recoveryCodes,
+		})
+	}
+}
+
+/*
+The `DisableTwoFactor` function is a handler function that turns two-factor authentication off.
+The current password must be provided.
+*/
+func DisableTwoFactor(twoFactor *services.TwoFactorService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Get the user ID from the context (set by the AuthMiddleware)
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		var input struct {
+			Password string `json:"password"`
+		}
+
+		if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		if err := twoFactor.Disable(userID, input.Password); err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"message": "Two-factor authentication disabled"})
+	}
+}
+
+/*
+The `RegenerateRecoveryCodes` function is a handler function that replaces the recovery codes of the authenticated user.
+The current password must be provided.
+*/
+func RegenerateRecoveryCodes(twoFactor *services.TwoFactorService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		// Get the user ID from the context (set by the AuthMiddleware)
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		var input struct {
+			Password string `json:"password"`
+		}
+
+		if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		recoveryCodes, err := twoFactor.RegenerateRecoveryCodes(userID, input.Password)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{"recovery_codes": recoveryCodes})
+	}
+}
+
+/*
+The `VerifyTwoFactor` function is a handler function that exchanges the partial token of a two-factor login
+and a TOTP or recovery code for a full token pair.
+
+Returns:
+
+	A JSON response with the access and refresh tokens, or an error message if the code is wrong.
+*/
+func VerifyTwoFactor(twoFactor *services.TwoFactorService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var input struct {
+			TwoFactorToken string `json:"two_factor_token"`
+			Code           string `json:"code"`
+		}
+
+		if err := c.BodyParser(&input); err != nil {
+			return apierror.BadRequest("Cannot parse JSON")
+		}
+
+		result, err := twoFactor.VerifyLogin(c.UserContext(), input.TwoFactorToken, input.Code)
+		if err != nil {
+			return err
+		}
+
+		return c.JSON(result)
+	}
+}
@@ -0,0 +1,141 @@
+package handlers
+
+import (
+	"strconv"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/template"
+	"cnep-backend/source/services"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+/*
+The `UploadMedia` function is a handler function that uploads a file for the given purpose
+(`post`, `logo`, `cover` or `product`) and returns the URL it can be fetched from.
+The file is read from the `file` multipart field and the purpose from the `purpose` field.
+*/
+func UploadMedia(uploads *services.UploadService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		file, err := c.FormFile("file")
+		if err != nil {
+			return apierror.BadRequest("File not provided")
+		}
+
+		variants, contentType, err := uploads.UploadMedia(c.UserContext(), userID, c.FormValue("purpose"), file)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"status":       "ok",
+			"url":          variants.Full,
+			"variants":     variants,
+			"content_type": contentType,
+		})
+	}
+}
+
+/*
+The `UploadAvatar` function is a handler function that replaces the avatar of the authenticated user.
+The image is read from the `file` multipart field.
+*/
+func UploadAvatar(uploads *services.UploadService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		file, err := c.FormFile("file")
+		if err != nil {
+			return apierror.BadRequest("File not provided")
+		}
+
+		variants, err := uploads.UploadAvatar(c.UserContext(), userID, file)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":          "ok",
+			"avatar":          variants.Full,
+			"avatar_variants": variants,
+		})
+	}
+}
+
+/*
+The `UploadBusinessImage` function is a handler function that replaces the logo or the cover image,
+as given by purpose, of a business page of the authenticated user.
+The image is read from the `file` multipart field.
+*/
+func UploadBusinessImage(uploads *services.UploadService, purpose string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		pageID, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return apierror.BadRequest("Invalid business page ID format")
+		}
+
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		file, err := c.FormFile("file")
+		if err != nil {
+			return apierror.BadRequest("File not provided")
+		}
+
+		variants, err := uploads.UploadBusinessImage(c.UserContext(), userID, uint(pageID), purpose, file)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusOK).JSON(fiber.Map{
+			"status":   "ok",
+			"url":      variants.Full,
+			"variants": variants,
+		})
+	}
+}
+
+/*
+The `AddProductImage` function is a handler function that adds an image to a product
+of a business page of the authenticated user.
+The image is read from the `file` multipart field.
+*/
+func AddProductImage(uploads *services.UploadService) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		productID, err := strconv.Atoi(c.Params("id"))
+		if err != nil {
+			return apierror.BadRequest("Invalid product ID format")
+		}
+
+		userID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
+		file, err := c.FormFile("file")
+		if err != nil {
+			return apierror.BadRequest("File not provided")
+		}
+
+		variants, err := uploads.AddProductImage(c.UserContext(), userID, uint(productID), file)
+		if err != nil {
+			return err
+		}
+
+		return c.Status(fiber.StatusCreated).JSON(fiber.Map{
+			"status":   "ok",
+			"url":      variants.Full,
+			"variants": variants,
+		})
+	}
+}
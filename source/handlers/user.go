@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"cnep-backend/pkg/apierror"
 	"cnep-backend/source/services"
 	"cnep-backend/pkg/template"
 
@@ -14,7 +15,7 @@ It takes the user ID from the context and fetches the user profile from the data
 If the user is not found or any other error occurs, it returns an appropriate error message.
 The function returns a JSON response with the user profile.
 */
-func GetUserProfile() fiber.Handler {
+func GetUserProfile(users *services.UserService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Get the user ID from the context (set by the AuthMiddleware)
 		userID, ok := c.Locals("userID").(uint)
@@ -22,12 +23,9 @@ func GetUserProfile() fiber.Handler {
 			return template.Unauthenticated(c)
 		}
 
-		user, err := services.GetUserProfileByID(uint(userID))
+		user, err := users.GetProfile(userID, userID)
 		if err != nil {
-			if fiberErr, ok := err.(*fiber.Error); ok {
-				return c.Status(fiberErr.Code).JSON(fiber.Map{"error": fiberErr.Message})
-			}
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Internal Server Error"})
+			return err
 		}
 
 		// Return the user profile
@@ -41,23 +39,26 @@ It takes the user ID from the URL parameter and fetches the user profile from th
 If the user is not found or any other error occurs, it returns an appropriate error message.
 The function returns a JSON response with the user profile.
 */
-func GetUserProfileByID() fiber.Handler {
+func GetUserProfileByID(users *services.UserService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
+		// Get the viewing user ID from the context (set by the AuthMiddleware)
+		viewerID, ok := c.Locals("userID").(uint)
+		if !ok {
+			return template.Unauthenticated(c)
+		}
+
 		// Get the user ID from the URL parameter
 		userIDParam := c.Params("id")
 
 		// Validate that the user ID is an integer
 		userID, err := strconv.Atoi(userIDParam)
 		if err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid user ID format"})
+			return apierror.BadRequest("Invalid user ID format")
 		}
 
-		user, err := services.GetUserProfileByID(uint(userID))
+		user, err := users.GetProfile(viewerID, uint(userID))
 		if err != nil {
-			if fiberErr, ok := err.(*fiber.Error); ok {
-				return c.Status(fiberErr.Code).JSON(fiber.Map{"error": fiberErr.Message})
-			}
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Internal Server Error"})
+			return err
 		}
 
 		return c.Status(fiber.StatusOK).JSON(user)
@@ -70,7 +71,7 @@ It takes the user ID from the context, the updated data from the request body, a
 If the user is not found or any other error occurs, it returns an appropriate error message.
 The function returns a JSON response with the updated user profile.
 */
-func UpdateUserProfile() fiber.Handler {
+func UpdateUserProfile(users *services.UserService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		userID, ok := c.Locals("userID").(uint)
 		if !ok {
@@ -79,17 +80,12 @@ func UpdateUserProfile() fiber.Handler {
 
 		var updateData map[string]interface{}
 		if err := c.BodyParser(&updateData); err != nil {
-			return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-				"error": "Invalid request body",
-			})
+			return apierror.BadRequest("Invalid request body")
 		}
 
-		user, err := services.UpdateUserProfile(userID, updateData)
+		user, err := users.UpdateProfile(userID, updateData)
 		if err != nil {
-			if fiberErr, ok := err.(*fiber.Error); ok {
-				return c.Status(fiberErr.Code).JSON(fiber.Map{"error": fiberErr.Message})
-			}
-			return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Internal Server Error"})
+			return err
 		}
 
 		return c.Status(fiber.StatusOK).JSON(fiber.Map{
@@ -1,37 +1,53 @@
 package middleware
 
 import (
+	"errors"
 	"strings"
 
+	"cnep-backend/pkg/apierror"
 	"cnep-backend/pkg/utils"
+	"cnep-backend/source/services"
 	"github.com/gofiber/fiber/v2"
 )
 
-func AuthMiddleware() fiber.Handler {
+func AuthMiddleware(sessions *services.SessionService) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		// Get the Authorization header
 		authHeader := c.Get("Authorization")
 
 		// Check if the Authorization header is empty or doesn't start with "Bearer "
 		if authHeader == "" || !strings.HasPrefix(authHeader, "Bearer ") {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Unauthorized: Missing or invalid Authorization header",
-			})
+			return apierror.Unauthorized("Unauthorized: Missing or invalid Authorization header")
 		}
 
 		// Extract the token from the Authorization header
 		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 
 		// Validate the JWT token
-		userID, err := utils.ValidateJWT(tokenString)
+		claims, err := utils.ValidateJWT(tokenString)
 		if err != nil {
-			return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-				"error": "Unauthorized: Invalid token",
-			})
+			return apierror.Unauthorized("Unauthorized: Invalid token")
 		}
 
-		// Add the user ID to the context for use in subsequent handlers
-		c.Locals("userID", userID)
+		// Reject tokens of sessions that were logged out or revoked
+		active, err := sessions.Validate(claims.UserID, claims.SessionID)
+		if errors.Is(err, services.ErrAccountSuspended) {
+			return apierror.Forbidden("Forbidden: Account suspended").WithCode(apierror.CodeAccountSuspended)
+		}
+		if err != nil {
+			return apierror.Internal("Could not validate session")
+		}
+		if !active {
+			return apierror.Unauthorized("Unauthorized: Session expired or revoked")
+		}
+
+		// Add the user and session IDs and the role to the context for use in subsequent handlers
+		c.Locals("userID", claims.UserID)
+		c.Locals("sessionID", claims.SessionID)
+		c.Locals("role", claims.Role)
+		if claims.ImpersonatorID != 0 {
+			c.Locals("impersonatorID", claims.ImpersonatorID)
+		}
 		return c.Next()
 	}
 }
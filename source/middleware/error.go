@@ -0,0 +1,36 @@
+package middleware
+
+import (
+	"errors"
+	"log"
+
+	"cnep-backend/pkg/apierror"
+
+	"github.com/gofiber/fiber/v2"
+)
+
+/*
+The ErrorHandler function is the Fiber error handler of the app. It writes every error
+returned by a handler or middleware as an apierror JSON body with the request ID.
+
+Errors that are neither an apierror nor a Fiber error are unexpected, they are logged
+and answered with a generic internal error so no detail leaks to the client.
+*/
+func ErrorHandler(c *fiber.Ctx, err error) error {
+	var apiErr *apierror.Error
+	var fiberErr *fiber.Error
+
+	switch {
+	case errors.As(err, &apiErr):
+		copied := *apiErr
+		apiErr = &copied
+	case errors.As(err, &fiberErr):
+		apiErr = apierror.FromStatus(fiberErr.Code, fiberErr.Message)
+	default:
+		log.Printf("Unhandled error on %s %s: %v", c.Method(), c.Path(), err)
+		apiErr = apierror.Internal("Internal server error")
+	}
+
+	apiErr.RequestID = RequestID(c)
+	return c.Status(apiErr.Status).JSON(apiErr)
+}
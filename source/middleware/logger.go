@@ -11,8 +11,13 @@ func Logger() fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		start := time.Now()
 
-		// Process request
-		err := c.Next()
+		// Process request. Errors are written by the error handler right away,
+		// so the logged status is the one sent to the client
+		if err := c.Next(); err != nil {
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				return err
+			}
+		}
 
 		// Log after request is processed
 		log.Printf(
@@ -25,6 +30,6 @@ func Logger() fiber.Handler {
 			time.Since(start).Milliseconds(),
 		)
 
-		return err
+		return nil
 	}
 }
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/services"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/requestid"
+)
+
+// RequestContext gives every request an ID, taken from the X-Request-ID header when the client
+// sends one, and puts the client IP, user agent, device and locale in the user context,
+// where the services read them from.
+func RequestContext() fiber.Handler {
+	setID := requestid.New()
+
+	return func(c *fiber.Ctx) error {
+		device := c.Get("X-Device-Name")
+		if device == "" {
+			device = utils.DeviceFromUserAgent(c.Get(fiber.HeaderUserAgent))
+		}
+
+		c.SetUserContext(services.WithClient(c.UserContext(), services.Client{
+			IP:        c.IP(),
+			UserAgent: c.Get(fiber.HeaderUserAgent),
+			Device:    device,
+			Locale:    utils.LocaleFromHeader(c.Get(fiber.HeaderAcceptLanguage)),
+		}))
+
+		return setID(c)
+	}
+}
+
+// RequestID returns the ID of the request set by RequestContext.
+func RequestID(c *fiber.Ctx) string {
+	id, _ := c.Locals("requestid").(string)
+	return id
+}
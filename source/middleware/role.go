@@ -0,0 +1,33 @@
+package middleware
+
+import (
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/utils"
+	"github.com/gofiber/fiber/v2"
+)
+
+// RequireRole only lets users through whose role is the given role or ranked above it.
+// It must run after AuthMiddleware, which puts the role from the token in the context.
+func RequireRole(role string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		userRole, _ := c.Locals("role").(string)
+
+		if !utils.HasRole(userRole, role) {
+			return apierror.Forbidden("Forbidden: Insufficient permissions")
+		}
+
+		return c.Next()
+	}
+}
+
+// NoImpersonation rejects requests made with an impersonation token. It guards actions
+// only the account owner may take, such as changing the password or the email.
+func NoImpersonation() fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		if _, ok := c.Locals("impersonatorID").(uint); ok {
+			return apierror.Forbidden("Forbidden: Not allowed while impersonating")
+		}
+
+		return c.Next()
+	}
+}
@@ -0,0 +1,64 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+// AuditLog records an action taken by an admin or moderator.
+type AuditLog struct {
+	ID         uint         `gorm:"primaryKey" json:"id"`
+	ActorID    *uint        `json:"actor_id"`
+	Action     string       `gorm:"not null" json:"action"`
+	TargetType string       `gorm:"not null" json:"target_type"`
+	TargetID   uint         `json:"target_id"`
+	Details    AuditDetails `gorm:"type:jsonb" json:"details"`
+	IP         string       `json:"ip"`
+	CreatedAt  time.Time    `gorm:"default:current_timestamp" json:"created_at"`
+}
+
+// AuditDetails holds action specific data, such as the reason for a suspension.
+// It is stored as a jsonb column.
+type AuditDetails map[string]interface{}
+
+func (d AuditDetails) Value() (driver.Value, error) {
+	if d == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(d)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (d *AuditDetails) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case nil:
+		*d = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(data, d)
+	case string:
+		return json.Unmarshal([]byte(data), d)
+	default:
+		return errors.New("unsupported type for AuditDetails")
+	}
+}
+
+// AdminUserResponse is the full view of a user shown to admins and moderators.
+// Unlike UserResponse it includes the verification, lock and suspension state.
+type AdminUserResponse struct {
+	UserResponse
+	LockedUntil       *time.Time `json:"locked_until"`
+	OTPFailedAttempts int        `json:"otp_failed_attempts"`
+	OTPLockedUntil    *time.Time `json:"otp_locked_until"`
+	OTPCodeFailures   int        `json:"otp_code_failures"`
+	SuspendedAt       *time.Time `json:"suspended_at"`
+	SuspendedUntil    *time.Time `json:"suspended_until"`
+	SuspensionReason  string     `json:"suspension_reason"`
+	Suspended         bool       `json:"suspended"`
+	ActiveSessions    int64      `json:"active_sessions,omitempty"`
+}
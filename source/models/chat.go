@@ -18,6 +18,7 @@ type Message struct {
 	SenderID       uint         `gorm:"not null" json:"sender_id"`
 	ReceiverID     uint         `gorm:"not null" json:"receiver_id"`
 	Content        string       `gorm:"not null" json:"content"`
+	HiddenAt       *time.Time   `json:"-"`
 	CreatedAt      time.Time    `gorm:"default:current_timestamp" json:"created_at"`
 	Conversation   Conversation `gorm:"foreignKey:ConversationID" json:"conversation"`
 	Sender         User         `gorm:"foreignKey:SenderID" json:"sender"`
@@ -0,0 +1,26 @@
+package models
+
+import "time"
+
+// UserIdentity links a user to an account at an external login provider.
+// Subject is the provider's stable id for the account, the email is kept for reference only.
+type UserIdentity struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null" json:"user_id"`
+	Provider  string    `gorm:"not null" json:"provider"`
+	Subject   string    `gorm:"not null" json:"subject"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `gorm:"default:current_timestamp" json:"created_at"`
+}
+
+// OAuthState remembers a login started with an external provider until the client comes back with the code.
+// Only a hash of the state is stored; the PKCE verifier and nonce never leave the server.
+type OAuthState struct {
+	ID           uint      `gorm:"primaryKey" json:"id"`
+	StateHash    string    `gorm:"unique;not null" json:"-"`
+	Provider     string    `gorm:"not null" json:"provider"`
+	CodeVerifier string    `gorm:"not null" json:"-"`
+	Nonce        string    `gorm:"not null" json:"-"`
+	ExpiresAt    time.Time `gorm:"not null" json:"expires_at"`
+	CreatedAt    time.Time `gorm:"default:current_timestamp" json:"created_at"`
+}
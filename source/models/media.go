@@ -0,0 +1,98 @@
+package models
+
+import (
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+)
+
+// ImageVariants holds the URLs of the standard sizes generated for an uploaded image.
+// It is stored as a single jsonb column on the owning model.
+type ImageVariants struct {
+	Thumb  string `json:"thumb,omitempty"`
+	Medium string `json:"medium,omitempty"`
+	Full   string `json:"full,omitempty"`
+}
+
+func (v ImageVariants) Value() (driver.Value, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (v *ImageVariants) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case nil:
+		*v = ImageVariants{}
+		return nil
+	case []byte:
+		return json.Unmarshal(data, v)
+	case string:
+		return json.Unmarshal([]byte(data), v)
+	default:
+		return errors.New("unsupported type for ImageVariants")
+	}
+}
+
+// Set stores url under the variant name produced by utils.ProcessImage.
+func (v *ImageVariants) Set(variant, url string) {
+	switch variant {
+	case "thumb":
+		v.Thumb = url
+	case "medium":
+		v.Medium = url
+	case "full":
+		v.Full = url
+	}
+}
+
+// URLs returns every non-empty variant URL.
+func (v ImageVariants) URLs() []string {
+	var urls []string
+	for _, url := range []string{v.Thumb, v.Medium, v.Full} {
+		if url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
+}
+
+// ImageVariantsList holds the variants of several images, such as the images of a product.
+// It is stored as a single jsonb array.
+type ImageVariantsList []ImageVariants
+
+func (l ImageVariantsList) Value() (driver.Value, error) {
+	if l == nil {
+		l = ImageVariantsList{}
+	}
+	b, err := json.Marshal([]ImageVariants(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func (l *ImageVariantsList) Scan(value interface{}) error {
+	switch data := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(data, (*[]ImageVariants)(l))
+	case string:
+		return json.Unmarshal([]byte(data), (*[]ImageVariants)(l))
+	default:
+		return errors.New("unsupported type for ImageVariantsList")
+	}
+}
+
+// URLs returns every non-empty variant URL of every image.
+func (l ImageVariantsList) URLs() []string {
+	var urls []string
+	for _, variants := range l {
+		urls = append(urls, variants.URLs()...)
+	}
+	return urls
+}
@@ -0,0 +1,28 @@
+package models
+
+import "time"
+
+// OneTimeCode is an OTP sent to a user for a single purpose (signup, reset, email change).
+// Only an HMAC of the code is stored. Target holds purpose specific data, such as the new
+// address for an email change.
+type OneTimeCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null" json:"user_id"`
+	Purpose   string     `gorm:"not null;check:purpose IN ('signup', 'reset', 'email_change')" json:"purpose"`
+	CodeHash  string     `gorm:"not null" json:"-"`
+	Target    string     `json:"target"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	Attempts  int        `gorm:"default:0" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `gorm:"default:current_timestamp" json:"created_at"`
+}
+
+// RecoveryCode is a one-time code that stands in for a TOTP code when the user has lost
+// their authenticator. Like OTPs, only an HMAC of the code is stored.
+type RecoveryCode struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	UserID    uint       `gorm:"not null" json:"user_id"`
+	CodeHash  string     `gorm:"not null" json:"-"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `gorm:"default:current_timestamp" json:"created_at"`
+}
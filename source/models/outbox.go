@@ -0,0 +1,21 @@
+package models
+
+import (
+	"github.com/lib/pq"
+	"time"
+)
+
+// OutboxEmail is an email waiting to be delivered by the outbox worker.
+// Failed deliveries are retried with backoff until SentAt is set or the attempts run out.
+type OutboxEmail struct {
+	ID            uint           `gorm:"primaryKey" json:"id"`
+	Recipients    pq.StringArray `gorm:"type:text[];not null" json:"recipients"`
+	Subject       string         `gorm:"not null" json:"subject"`
+	Body          string         `gorm:"not null" json:"body"`
+	TextBody      string         `json:"text_body"`
+	Attempts      int            `gorm:"not null;default:0" json:"attempts"`
+	LastError     string         `json:"last_error"`
+	NextAttemptAt time.Time      `gorm:"default:current_timestamp" json:"next_attempt_at"`
+	SentAt        *time.Time     `json:"sent_at"`
+	CreatedAt     time.Time      `gorm:"default:current_timestamp" json:"created_at"`
+}
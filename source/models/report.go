@@ -0,0 +1,47 @@
+package models
+
+import "time"
+
+// ModerationCase groups the reports on one item. While a case is open every new report
+// on the item is added to it, so moderators review each item once.
+type ModerationCase struct {
+	ID          uint       `gorm:"primaryKey" json:"id"`
+	TargetType  string     `gorm:"not null;check:target_type IN ('post', 'comment', 'message', 'business', 'user')" json:"target_type"`
+	TargetID    uint       `gorm:"not null" json:"target_id"`
+	AuthorID    *uint      `json:"author_id"`
+	Status      string     `gorm:"not null;default:open;check:status IN ('open', 'resolved')" json:"status"`
+	Resolution  *string    `gorm:"check:resolution IN ('dismissed', 'hidden', 'suspended')" json:"resolution"`
+	ReportCount int        `gorm:"not null;default:0" json:"report_count"`
+	Note        string     `json:"note"`
+	ResolvedBy  *uint      `json:"resolved_by"`
+	ResolvedAt  *time.Time `json:"resolved_at"`
+	CreatedAt   time.Time  `gorm:"default:current_timestamp" json:"created_at"`
+	UpdatedAt   time.Time  `gorm:"default:current_timestamp" json:"updated_at"`
+}
+
+// Report is a single user's report on an item.
+type Report struct {
+	ID         uint      `gorm:"primaryKey" json:"id"`
+	CaseID     uint      `gorm:"not null" json:"case_id"`
+	ReporterID uint      `gorm:"not null" json:"reporter_id"`
+	Reason     string    `gorm:"not null" json:"reason"`
+	Details    string    `json:"details"`
+	CreatedAt  time.Time `gorm:"default:current_timestamp" json:"created_at"`
+}
+
+// ReportWithReporter is a report as shown to moderators.
+type ReportWithReporter struct {
+	ID           uint      `json:"id"`
+	Reason       string    `json:"reason"`
+	Details      string    `json:"details"`
+	ReporterID   uint      `json:"reporter_id"`
+	ReporterName string    `json:"reporter_name"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+// ModerationCaseDetail is a case with its reports and the reported item as it is now.
+type ModerationCaseDetail struct {
+	Case    ModerationCase       `json:"case"`
+	Reports []ReportWithReporter `json:"reports"`
+	Content interface{}          `json:"content"`
+}
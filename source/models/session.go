@@ -0,0 +1,40 @@
+package models
+
+import "time"
+
+// A Session is a single login of a user. Every refresh token issued for that login
+// belongs to the same session, so revoking the session revokes the whole token family.
+type Session struct {
+	ID             uint       `gorm:"primaryKey" json:"id"`
+	UserID         uint       `gorm:"not null" json:"user_id"`
+	Device         string     `json:"device"`
+	IP             string     `json:"ip"`
+	UserAgent      string     `json:"user_agent"`
+	LastSeenAt     time.Time  `gorm:"default:current_timestamp" json:"last_seen_at"`
+	RevokedAt      *time.Time `json:"revoked_at"`
+	ImpersonatorID *uint      `json:"impersonator_id"` // set on sessions an admin started as the user
+	CreatedAt      time.Time  `gorm:"default:current_timestamp" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"default:current_timestamp" json:"updated_at"`
+}
+
+// SessionResponse is what a user sees when listing their own sessions.
+type SessionResponse struct {
+	ID         uint      `json:"id"`
+	Device     string    `json:"device"`
+	IP         string    `json:"ip"`
+	UserAgent  string    `json:"user_agent"`
+	LastSeenAt time.Time `json:"last_seen_at"`
+	CreatedAt  time.Time `json:"created_at"`
+	Current    bool      `json:"current"`
+}
+
+// RefreshToken stores only the SHA-256 hash of the token handed to the client.
+// A token can be exchanged exactly once; UsedAt is set when it is rotated.
+type RefreshToken struct {
+	ID        uint       `gorm:"primaryKey" json:"id"`
+	SessionID uint       `gorm:"not null" json:"session_id"`
+	TokenHash string     `gorm:"unique;not null" json:"-"`
+	ExpiresAt time.Time  `gorm:"not null" json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `gorm:"default:current_timestamp" json:"created_at"`
+}
@@ -1,38 +1,88 @@
 package models
 
-import "time"
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
 
 // Original User struct
 type User struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Name        string    `gorm:"not null" json:"name"`
-	Username    string    `gorm:"not null" json:"username"`
-	Avatar      string    `json:"avatar"`
-	Email       string    `gorm:"unique;not null" json:"email"`
-	Password    string    `gorm:"not null" json:"password"`
-	Address     string    `json:"address"`
-	Designation string    `json:"designation"`
-	Phone       string    `json:"phone"`
-	OTP         string    `gorm:"size:6" json:"otp"`
-	OTPExpiry   time.Time `json:"otp_expiry"`
-	IsVerified  bool      `gorm:"default:false" json:"is_verified"`
-	CreatedAt   time.Time `gorm:"default:current_timestamp" json:"created_at"`
-	UpdatedAt   time.Time `gorm:"default:current_timestamp" json:"updated_at"`
+	ID             uint          `gorm:"primaryKey" json:"id"`
+	Name           string        `gorm:"not null" json:"name"`
+	Username       string        `gorm:"not null" json:"username"`
+	Avatar         string        `json:"avatar"`
+	AvatarVariants ImageVariants `gorm:"type:jsonb" json:"avatar_variants"`
+	Email          string        `gorm:"unique;not null" json:"email"`
+	Password       string        `gorm:"not null" json:"password"`
+	Address        string        `json:"address"`
+	Designation    string        `json:"designation"`
+	Phone          string        `json:"phone"`
+	Locale         string        `gorm:"default:en" json:"locale"`
+	Role           string        `gorm:"not null;default:user;check:role IN ('user', 'moderator', 'admin')" json:"role"`
+	Topics         pq.Int64Array `gorm:"type:integer[]" json:"topics"`
+	// Location of the user, only the user can see it
+	Latitude  *float64 `json:"latitude"`
+	Longitude *float64 `json:"longitude"`
+	// Two-factor brute-force protection and OTP resend throttling
+	OTPFailedAttempts int        `gorm:"default:0" json:"-"`
+	LockedUntil       *time.Time `json:"-"`
+	// Wrong emailed codes, counted across resends, and the lock they lead to
+	OTPCodeFailures    int        `gorm:"default:0" json:"-"`
+	OTPLockedUntil     *time.Time `json:"-"`
+	OTPLastSentAt      *time.Time `json:"-"`
+	OTPSendCount       int        `gorm:"default:0" json:"-"`
+	OTPSendWindowStart *time.Time `json:"-"`
+	// TOTP two-factor authentication, the secret is encrypted at rest
+	TwoFactorEnabled bool   `gorm:"default:false" json:"two_factor_enabled"`
+	TOTPSecret       string `json:"-"`
+	TOTPLastStep     int64  `gorm:"default:0" json:"-"`
+	// Suspension by an admin or moderator, a suspension without an end is a ban
+	SuspendedAt      *time.Time `json:"-"`
+	SuspendedUntil   *time.Time `json:"-"`
+	SuspensionReason string     `json:"-"`
+	// Who can see the email, phone and address of the user
+	Privacy    PrivacySettings `gorm:"embedded" json:"-"`
+	IsVerified bool            `gorm:"default:false" json:"is_verified"`
+	CreatedAt  time.Time       `gorm:"default:current_timestamp" json:"created_at"`
+	UpdatedAt  time.Time       `gorm:"default:current_timestamp" json:"updated_at"`
+}
+
+// PrivacySettings holds the visibility of the contact fields of a user profile.
+// Each field is public, visible to accepted partners only, or private.
+type PrivacySettings struct {
+	EmailVisibility   string `gorm:"not null;default:partners;check:email_visibility IN ('public', 'partners', 'private')" json:"email_visibility"`
+	PhoneVisibility   string `gorm:"not null;default:private;check:phone_visibility IN ('public', 'partners', 'private')" json:"phone_visibility"`
+	AddressVisibility string `gorm:"not null;default:partners;check:address_visibility IN ('public', 'partners', 'private')" json:"address_visibility"`
 }
 
 // Excluded sensitive fields from User
 type UserResponse struct {
-	ID          uint      `gorm:"primaryKey" json:"id"`
-	Name        string    `gorm:"not null" json:"name"`
-	Username    string    `gorm:"unique;not null" json:"username"`
-	Avatar      string    `json:"avatar"`
-	Email       string    `gorm:"unique;not null" json:"email"`
-	Address     string    `json:"address"`
-	Designation string    `json:"designation"`
-	Phone       string    `json:"phone"`
-	IsVerified  bool      `gorm:"default:false" json:"is_verified"`
-	CreatedAt   time.Time `gorm:"default:current_timestamp" json:"created_at"`
-	UpdatedAt   time.Time `gorm:"default:current_timestamp" json:"updated_at"`
+	ID               uint            `gorm:"primaryKey" json:"id"`
+	Name             string          `gorm:"not null" json:"name"`
+	Username         string          `gorm:"unique;not null" json:"username"`
+	Avatar           string          `json:"avatar"`
+	AvatarVariants   ImageVariants   `gorm:"type:jsonb" json:"avatar_variants"`
+	Email            string          `gorm:"unique;not null" json:"email"`
+	Address          string          `json:"address"`
+	Designation      string          `json:"designation"`
+	Phone            string          `json:"phone"`
+	Locale           string          `gorm:"default:en" json:"locale,omitempty"`
+	Role             string          `gorm:"default:user" json:"role,omitempty"`
+	Topics           pq.Int64Array   `gorm:"type:integer[]" json:"topics"`
+	Latitude         *float64        `json:"latitude,omitempty"`
+	Longitude        *float64        `json:"longitude,omitempty"`
+	TwoFactorEnabled bool            `gorm:"default:false" json:"two_factor_enabled"`
+	Privacy          PrivacySettings `gorm:"embedded" json:"-"`
+	IsVerified       bool            `gorm:"default:false" json:"is_verified"`
+	CreatedAt        time.Time       `gorm:"default:current_timestamp" json:"created_at"`
+	UpdatedAt        time.Time       `gorm:"default:current_timestamp" json:"updated_at"`
+}
+
+// PartnerUser is a user of a partner list, with the time the partnership or request started.
+type PartnerUser struct {
+	UserResponse
+	Since time.Time `json:"since"`
 }
 
 type Partner struct {
@@ -44,6 +94,31 @@ type Partner struct {
 	UpdatedAt  time.Time `gorm:"default:current_timestamp" json:"updated_at"`
 }
 
+// PartnerRelationship is the relationship between a user and another user, as seen by the user.
+type PartnerRelationship struct {
+	Relationship string `json:"relationship"`
+	CanRequest   bool   `json:"can_request"`
+	RetryAfter   int    `json:"retry_after,omitempty"` // seconds left of the cooldown after a decline
+}
+
+// UserBlock is a block of BlockedID by BlockerID. A block works in both directions:
+// neither user can interact with or see the content of the other.
+type UserBlock struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	BlockerID uint      `gorm:"not null" json:"blocker_id"`
+	BlockedID uint      `gorm:"not null" json:"blocked_id"`
+	CreatedAt time.Time `gorm:"default:current_timestamp" json:"created_at"`
+}
+
+// BlockedUser is an entry of the blocked users list.
+type BlockedUser struct {
+	ID        uint      `json:"id"`
+	Name      string    `json:"name"`
+	Username  string    `json:"username"`
+	Avatar    string    `json:"avatar"`
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
 type SenderInfo struct {
 	ID     uint    `json:"id"`
 	Name   string  `json:"name"`
@@ -51,3 +126,19 @@ type SenderInfo struct {
 	Rating float32 `json:"rating"`
 }
 
+// PartnerSuggestion is a user the viewer may know, with the reasons they were suggested.
+type PartnerSuggestion struct {
+	ID             uint          `json:"id"`
+	Name           string        `json:"name"`
+	Username       string        `json:"username"`
+	Avatar         string        `json:"avatar"`
+	AvatarVariants ImageVariants `gorm:"type:jsonb" json:"avatar_variants"`
+	Designation    string        `json:"designation"`
+	MutualPartners int           `json:"mutual_partners"`
+	SharedTopics   int           `json:"shared_topics"`
+	Helps          int           `json:"helps"`
+	Nearby         bool          `json:"nearby"`
+	Score          float64       `json:"score"`
+	DistanceKm     *float64      `json:"-"`
+	Total          int64         `json:"-"`
+}
@@ -26,6 +26,8 @@ type FeedbackSender struct {
 	SenderRating float32   `json:"sender_rating"`
 	CreatedAt    time.Time `json:"created_at"`
 	UpdatedAt    time.Time `json:"updated_at"`
+	// Privacy setting of the sender email, used to hide it from the viewer
+	SenderEmailVisibility string `json:"-"`
 }
 
 type FeedbackWithSender struct {
@@ -47,3 +49,12 @@ type Badge struct {
 	CreatedAt   time.Time `gorm:"default:current_timestamp" json:"created_at"`
 	UpdatedAt   time.Time `gorm:"default:current_timestamp" json:"updated_at"`
 }
+
+// ==== User Topics ====
+
+type Topic struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	Title     string    `gorm:"not null" json:"title"`
+	CreatedAt time.Time `gorm:"default:current_timestamp" json:"created_at"`
+	UpdatedAt time.Time `gorm:"default:current_timestamp" json:"updated_at"`
+}
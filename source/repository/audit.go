@@ -0,0 +1,59 @@
+package repository
+
+import (
+	"cnep-backend/pkg/consts"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/models"
+
+	"gorm.io/gorm"
+)
+
+// AuditRepository stores the audit log of admin and moderator actions.
+type AuditRepository interface {
+	Create(entry *models.AuditLog) error
+	// List returns a page of the entries matching the filter and the total count of matches.
+	List(filter AuditFilter, page utils.Page) ([]models.AuditLog, int64, error)
+}
+
+// AuditFilter narrows the audit log. Zero fields do not filter.
+type AuditFilter struct {
+	ActorID    uint
+	Action     string
+	TargetType string
+	TargetID   uint
+}
+
+type auditRepository struct {
+	db *gorm.DB
+}
+
+func (r *auditRepository) Create(entry *models.AuditLog) error {
+	return r.db.Table(consts.AUDIT_LOGS_TABLE).Create(entry).Error
+}
+
+func (r *auditRepository) List(filter AuditFilter, page utils.Page) ([]models.AuditLog, int64, error) {
+	var entries []models.AuditLog
+	var total int64
+
+	query := r.db.Table(consts.AUDIT_LOGS_TABLE)
+	if filter.ActorID != 0 {
+		query = query.Where("actor_id = ?", filter.ActorID)
+	}
+	if filter.TargetID != 0 {
+		query = query.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.Action != "" {
+		query = query.Where("action = ?", filter.Action)
+	}
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := findPage(query, page, &entries); err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
@@ -0,0 +1,73 @@
+package repository
+
+import (
+	"cnep-backend/pkg/consts"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// BlockRepository stores the users blocked by each user.
+type BlockRepository interface {
+	// Create blocks the user for the blocker. Blocking a user twice is not an error.
+	Create(blockerID, blockedID uint) error
+	// Delete removes the block. It returns ErrNotFound if the user was not blocked.
+	Delete(blockerID, blockedID uint) error
+	// Between reports whether either user has blocked the other.
+	Between(userID, otherID uint) (bool, error)
+	// List returns a page of the users blocked by the blocker.
+	List(blockerID uint, page utils.Page) ([]models.BlockedUser, error)
+}
+
+type blockRepository struct {
+	db *gorm.DB
+}
+
+func (r *blockRepository) Create(blockerID, blockedID uint) error {
+	block := models.UserBlock{BlockerID: blockerID, BlockedID: blockedID}
+	return r.db.Table(consts.USER_BLOCKS_TABLE).
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&block).Error
+}
+
+func (r *blockRepository) Delete(blockerID, blockedID uint) error {
+	return affected(r.db.Table(consts.USER_BLOCKS_TABLE).
+		Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).
+		Delete(&models.UserBlock{}))
+}
+
+func (r *blockRepository) Between(userID, otherID uint) (bool, error) {
+	var count int64
+	err := r.db.Table(consts.USER_BLOCKS_TABLE).
+		Where("(blocker_id = ? AND blocked_id = ?) OR (blocker_id = ? AND blocked_id = ?)", userID, otherID, otherID, userID).
+		Count(&count).Error
+	return count > 0, err
+}
+
+func (r *blockRepository) List(blockerID uint, page utils.Page) ([]models.BlockedUser, error) {
+	var users []models.BlockedUser
+
+	query := r.db.Table(consts.USER_BLOCKS_TABLE).
+		Select("users.id, users.name, users.username, users.avatar, user_blocks.created_at as blocked_at").
+		Joins("JOIN users ON users.id = user_blocks.blocked_id").
+		Where("user_blocks.blocker_id = ?", blockerID)
+
+	if err := scanPage(query, page, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// NotBlocked is a query scope that leaves out rows whose column refers to a user who blocked
+// the viewer or was blocked by them. Feedback lists apply it to the sender, partner lists and
+// their search to the listed user, and partner suggestions to the suggested user. Feeds apply it
+// to the author of posts and comments, e.g. db.Scopes(repository.Visible, repository.NotBlocked(viewerID, "posts.user_id")).
+// Messages between blocked users are dropped when they are sent, so message reads need no scope.
+func NotBlocked(viewerID uint, column string) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Where(column+" NOT IN (SELECT blocked_id FROM user_blocks WHERE blocker_id = ?)", viewerID).
+			Where(column+" NOT IN (SELECT blocker_id FROM user_blocks WHERE blocked_id = ?)", viewerID)
+	}
+}
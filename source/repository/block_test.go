@@ -0,0 +1,119 @@
+package repository
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"cnep-backend/pkg/utils"
+)
+
+func TestNotBlocked(t *testing.T) {
+	db, recorder := dryRun(t)
+
+	var ids []uint
+	db.Table("posts").Select("id").Scopes(NotBlocked(7, "posts.user_id")).Find(&ids)
+
+	sql := recorder.last(t)
+	for _, want := range []string{
+		"posts.user_id NOT IN (SELECT blocked_id FROM user_blocks WHERE blocker_id = 7)",
+		"posts.user_id NOT IN (SELECT blocker_id FROM user_blocks WHERE blocked_id = 7)",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Fatalf("expected %s in %s", want, sql)
+		}
+	}
+}
+
+func TestFeedbackListLeavesOutBlockedSenders(t *testing.T) {
+	tests := []struct {
+		name             string
+		viewerID, userID uint
+	}{
+		{"own feedback", 3, 3},
+		{"feedback of another user", 7, 3},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, recorder := dryRun(t)
+			repo := &feedbackRepository{db: db}
+
+			page := utils.Page{
+				Limit:    20,
+				Sort:     utils.SortField{Name: "created", Column: "feedbacks.created_at", Kind: utils.SortTime},
+				IDColumn: "feedbacks.id",
+			}
+			repo.ListReceived(tt.viewerID, tt.userID, 0, page)
+
+			// Blocks are checked against the viewer, not the receiver of the feedback
+			sql := recorder.last(t)
+			for _, want := range []string{
+				"feedbacks.sender_id NOT IN (SELECT blocked_id FROM user_blocks WHERE blocker_id = " + fmt.Sprint(tt.viewerID) + ")",
+				"feedbacks.sender_id NOT IN (SELECT blocker_id FROM user_blocks WHERE blocked_id = " + fmt.Sprint(tt.viewerID) + ")",
+				"feedbacks.receiver_id = " + fmt.Sprint(tt.userID),
+			} {
+				if !strings.Contains(sql, want) {
+					t.Fatalf("expected %s in %s", want, sql)
+				}
+			}
+		})
+	}
+}
+
+func TestPartnerListsLeaveOutBlockedUsers(t *testing.T) {
+	tests := []struct {
+		name string
+		list func(r *partnerRepository, page utils.Page)
+	}{
+		{"accepted", func(r *partnerRepository, page utils.Page) { r.ListAccepted(7, "", page) }},
+		{"incoming", func(r *partnerRepository, page utils.Page) { r.ListIncoming(7, "", page) }},
+		{"outgoing", func(r *partnerRepository, page utils.Page) { r.ListOutgoing(7, "", page) }},
+		{"search", func(r *partnerRepository, page utils.Page) { r.ListAccepted(7, "ann", page) }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, recorder := dryRun(t)
+
+			page := utils.Page{
+				Limit:    20,
+				Sort:     utils.SortField{Name: "since", Column: "since", Kind: utils.SortTime},
+				IDColumn: "users.id",
+			}
+			tt.list(&partnerRepository{db: db}, page)
+
+			sql := recorder.last(t)
+			for _, want := range []string{
+				"users.id NOT IN (SELECT blocked_id FROM user_blocks WHERE blocker_id = 7)",
+				"users.id NOT IN (SELECT blocker_id FROM user_blocks WHERE blocked_id = 7)",
+			} {
+				if !strings.Contains(sql, want) {
+					t.Fatalf("expected %s in %s", want, sql)
+				}
+			}
+		})
+	}
+}
+
+func TestSuggestionsLeaveOutBlockedUsers(t *testing.T) {
+	db, recorder := dryRun(t)
+	repo := &partnerRepository{db: db}
+
+	page := utils.Page{
+		Limit:    20,
+		Sort:     utils.SortField{Name: "score", Column: "score", Kind: utils.SortFloat, Desc: true},
+		IDColumn: "id",
+	}
+	repo.Suggestions(7, SuggestionScoring{RadiusKm: 25}, page)
+
+	// Blocked users are left out before the total is counted
+	sql := recorder.last(t)
+	blocked := strings.Index(sql, "ranked.id NOT IN (SELECT blocked_id FROM user_blocks WHERE blocker_id = 7)")
+	blocker := strings.Index(sql, "ranked.id NOT IN (SELECT blocker_id FROM user_blocks WHERE blocked_id = 7)")
+	counted := strings.Index(sql, "COUNT(*) OVER () AS total")
+	paged := strings.Index(sql, ") AS suggestions")
+	if counted < 0 || blocked < counted || blocker < counted || blocked > paged || blocker > paged {
+		t.Fatalf("expected blocks to be checked inside the counted subquery in %s", sql)
+	}
+}
@@ -0,0 +1,67 @@
+package repository
+
+import (
+	"cnep-backend/pkg/consts"
+	"cnep-backend/source/models"
+
+	"gorm.io/gorm"
+)
+
+// BusinessRepository stores business pages and their products.
+type BusinessRepository interface {
+	CreatePage(page *models.BusinessPage) error
+	// GetPage returns the page, or ErrNotFound when there is no such page or moderators hid it.
+	GetPage(id uint) (*models.BusinessPage, error)
+	// UpdatePage changes the given columns of the page. It returns ErrNotFound if there is no such page.
+	UpdatePage(id uint, fields map[string]interface{}) error
+
+	CreateProduct(product *models.Product) error
+	GetProduct(id uint) (*models.Product, error)
+	// AddProductImage appends an image and its variants to the product. It reports false, adding
+	// nothing, when the product already has maxImages images. Check and append are a single
+	// statement, so concurrent uploads cannot pass the limit or drop each other's image.
+	AddProductImage(id uint, url string, variants models.ImageVariants, maxImages int) (bool, error)
+}
+
+type businessRepository struct {
+	db *gorm.DB
+}
+
+func (r *businessRepository) CreatePage(page *models.BusinessPage) error {
+	return translate(r.db.Table(consts.BUSINESSES_TABLE).Omit("User", "Followers").Create(page).Error)
+}
+
+func (r *businessRepository) GetPage(id uint) (*models.BusinessPage, error) {
+	var page models.BusinessPage
+	if err := r.db.Table(consts.BUSINESSES_TABLE).Where("id = ?", id).Scopes(Visible).First(&page).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &page, nil
+}
+
+func (r *businessRepository) UpdatePage(id uint, fields map[string]interface{}) error {
+	// The model lets gorm keep updated_at current, the table name differs from the one gorm derives
+	return affected(r.db.Model(&models.BusinessPage{}).Table(consts.BUSINESSES_TABLE).Where("id = ?", id).Updates(fields))
+}
+
+func (r *businessRepository) CreateProduct(product *models.Product) error {
+	return translate(r.db.Table(consts.PRODUCTS_TABLE).Create(product).Error)
+}
+
+func (r *businessRepository) GetProduct(id uint) (*models.Product, error) {
+	var product models.Product
+	if err := r.db.Table(consts.PRODUCTS_TABLE).Where("id = ?", id).First(&product).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &product, nil
+}
+
+func (r *businessRepository) AddProductImage(id uint, url string, variants models.ImageVariants, maxImages int) (bool, error) {
+	result := r.db.Model(&models.Product{}).
+		Where("id = ? AND COALESCE(array_length(images, 1), 0) < ?", id, maxImages).
+		Updates(map[string]interface{}{
+			"images":         gorm.Expr("array_append(COALESCE(images, '{}'), ?)", url),
+			"image_variants": gorm.Expr("COALESCE(image_variants, '[]'::jsonb) || jsonb_build_array(?::jsonb)", variants),
+		})
+	return result.RowsAffected == 1, translate(result.Error)
+}
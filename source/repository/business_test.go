@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"cnep-backend/source/models"
+)
+
+func TestAddProductImageIsOneStatement(t *testing.T) {
+	db, recorder := dryRun(t)
+	repo := &businessRepository{db: db}
+
+	repo.AddProductImage(4, "/uploads/product/1/a_full.jpg", models.ImageVariants{Full: "/uploads/product/1/a_full.jpg"}, 10)
+
+	// The limit is checked by the update itself, and both columns are appended to in place
+	sql := recorder.last(t)
+	for _, want := range []string{
+		`UPDATE "products" SET`,
+		`"images"=array_append(COALESCE(images, '{}'), '/uploads/product/1/a_full.jpg')`,
+		`"image_variants"=COALESCE(image_variants, '[]'::jsonb) || jsonb_build_array('{"full":"/uploads/product/1/a_full.jpg"}'::jsonb)`,
+		"WHERE id = 4 AND COALESCE(array_length(images, 1), 0) < 10",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Fatalf("expected %s in %s", want, sql)
+		}
+	}
+}
+
+func TestGetPageLeavesOutHiddenPages(t *testing.T) {
+	db, recorder := dryRun(t)
+	repo := &businessRepository{db: db}
+
+	repo.GetPage(4)
+
+	if sql := recorder.last(t); !strings.Contains(sql, `"businesses"."hidden_at" IS NULL`) {
+		t.Fatalf("expected hidden pages to be left out, got %s", sql)
+	}
+}
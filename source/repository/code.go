@@ -0,0 +1,99 @@
+package repository
+
+import (
+	"time"
+
+	"cnep-backend/pkg/consts"
+	"cnep-backend/source/models"
+
+	"gorm.io/gorm"
+)
+
+// CodeRepository stores the one-time codes emailed to users.
+type CodeRepository interface {
+	Create(code *models.OneTimeCode) error
+	// DeleteUnused removes the unused codes of the user for the purpose.
+	DeleteUnused(userID uint, purpose string) error
+	// Active returns the newest unused code of the user for the purpose that has not expired.
+	Active(userID uint, purpose string) (*models.OneTimeCode, error)
+	// Attempt counts an attempt to redeem the code. It reports false, without counting, once
+	// maxAttempts were made, so a code can only be guessed a few times however many requests run at once.
+	Attempt(id uint, maxAttempts int) (bool, error)
+	// Use marks the code as used. It returns ErrNotFound if the code was already used,
+	// so a code can never be redeemed twice.
+	Use(id uint) error
+}
+
+// RecoveryCodeRepository stores the two-factor recovery codes of users.
+type RecoveryCodeRepository interface {
+	Create(codes []models.RecoveryCode) error
+	DeleteAll(userID uint) error
+	Unused(userID uint) ([]models.RecoveryCode, error)
+	// Use marks the code as used. It reports false when the code was already used.
+	Use(id uint) (bool, error)
+}
+
+type codeRepository struct {
+	db *gorm.DB
+}
+
+func (r *codeRepository) Create(code *models.OneTimeCode) error {
+	return r.db.Table(consts.ONE_TIME_CODES_TABLE).Create(code).Error
+}
+
+func (r *codeRepository) DeleteUnused(userID uint, purpose string) error {
+	return r.db.Table(consts.ONE_TIME_CODES_TABLE).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL", userID, purpose).
+		Delete(&models.OneTimeCode{}).Error
+}
+
+func (r *codeRepository) Active(userID uint, purpose string) (*models.OneTimeCode, error) {
+	var code models.OneTimeCode
+	if err := r.db.Table(consts.ONE_TIME_CODES_TABLE).
+		Where("user_id = ? AND purpose = ? AND used_at IS NULL AND expires_at > ?", userID, purpose, time.Now()).
+		Order("created_at DESC").
+		First(&code).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &code, nil
+}
+
+func (r *codeRepository) Attempt(id uint, maxAttempts int) (bool, error) {
+	result := r.db.Table(consts.ONE_TIME_CODES_TABLE).
+		Where("id = ? AND attempts < ?", id, maxAttempts).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *codeRepository) Use(id uint) error {
+	return affected(r.db.Table(consts.ONE_TIME_CODES_TABLE).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now()))
+}
+
+type recoveryCodeRepository struct {
+	db *gorm.DB
+}
+
+func (r *recoveryCodeRepository) Create(codes []models.RecoveryCode) error {
+	return r.db.Table(consts.RECOVERY_CODES_TABLE).Create(&codes).Error
+}
+
+func (r *recoveryCodeRepository) DeleteAll(userID uint) error {
+	return r.db.Table(consts.RECOVERY_CODES_TABLE).Where("user_id = ?", userID).Delete(&models.RecoveryCode{}).Error
+}
+
+func (r *recoveryCodeRepository) Unused(userID uint) ([]models.RecoveryCode, error) {
+	var codes []models.RecoveryCode
+	err := r.db.Table(consts.RECOVERY_CODES_TABLE).
+		Where("user_id = ? AND used_at IS NULL", userID).
+		Find(&codes).Error
+	return codes, err
+}
+
+func (r *recoveryCodeRepository) Use(id uint) (bool, error) {
+	result := r.db.Table(consts.RECOVERY_CODES_TABLE).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
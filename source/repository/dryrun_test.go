@@ -0,0 +1,53 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqlRecorder is a gorm logger keeping the statements a query builds.
+type sqlRecorder struct {
+	logger.Interface
+	statements []string
+}
+
+func (r *sqlRecorder) LogMode(logger.LogLevel) logger.Interface { return r }
+
+func (r *sqlRecorder) Trace(ctx context.Context, begin time.Time, fc func() (string, int64), err error) {
+	sql, _ := fc()
+	r.statements = append(r.statements, sql)
+}
+
+// last returns the last statement built, failing the test if there is none.
+func (r *sqlRecorder) last(t *testing.T) string {
+	t.Helper()
+
+	if len(r.statements) == 0 {
+		t.Fatal("no statement was built")
+	}
+	return r.statements[len(r.statements)-1]
+}
+
+// dryRun returns a Postgres connection that builds statements without running them,
+// so the SQL of a repository can be checked without a database.
+func dryRun(t *testing.T) (*gorm.DB, *sqlRecorder) {
+	t.Helper()
+
+	recorder := &sqlRecorder{Interface: logger.Discard}
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=localhost"}), &gorm.Config{
+		DryRun:               true,
+		DisableAutomaticPing: true,
+		// Writes would otherwise open a transaction, which needs a connection
+		SkipDefaultTransaction: true,
+		Logger:                 recorder,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, recorder
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"cnep-backend/pkg/consts"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/models"
+
+	"gorm.io/gorm"
+)
+
+// FeedbackRepository stores the feedback users give each other.
+type FeedbackRepository interface {
+	Create(feedback *models.Feedback) error
+	// ListReceived returns a page of the feedback received by the user with its senders, as seen by the viewer.
+	// Feedback from senders who blocked the viewer or were blocked by them is left out.
+	// A rating from 1 to 5 only returns feedback with that rating, 0 returns all feedback.
+	ListReceived(viewerID, userID uint, rating int, page utils.Page) ([]models.FeedbackSender, error)
+}
+
+type feedbackRepository struct {
+	db *gorm.DB
+}
+
+func (r *feedbackRepository) Create(feedback *models.Feedback) error {
+	return r.db.Table(consts.FEEDBACK_TABLE).Create(feedback).Error
+}
+
+func (r *feedbackRepository) ListReceived(viewerID, userID uint, rating int, page utils.Page) ([]models.FeedbackSender, error) {
+	var feedbacks []models.FeedbackSender
+
+	query := r.db.Table(consts.FEEDBACK_TABLE).
+		Select("feedbacks.id as feedback_id, feedbacks.content, feedbacks.rating, feedbacks.sender_id, users.name as sender_name, users.email as sender_email, users.email_visibility as sender_email_visibility, "+
+			"(SELECT COALESCE(AVG(received.rating), 0) FROM feedbacks received WHERE received.receiver_id = users.id) as sender_rating, feedbacks.created_at, feedbacks.updated_at").
+		Joins("JOIN users ON feedbacks.sender_id = users.id").
+		Where("feedbacks.receiver_id = ?", userID).
+		Scopes(NotBlocked(viewerID, "feedbacks.sender_id"))
+
+	if rating != 0 {
+		query = query.Where("feedbacks.rating = ?", rating)
+	}
+
+	if err := scanPage(query, page, &feedbacks); err != nil {
+		return nil, err
+	}
+	return feedbacks, nil
+}
@@ -0,0 +1,61 @@
+package repository
+
+import (
+	"time"
+
+	"cnep-backend/pkg/consts"
+	"cnep-backend/source/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// IdentityRepository stores the links between users and external login provider accounts,
+// and the state of logins in progress with a provider.
+type IdentityRepository interface {
+	Find(provider, subject string) (*models.UserIdentity, error)
+	Create(identity *models.UserIdentity) error
+
+	CreateState(state *models.OAuthState) error
+	// TakeState deletes and returns the unexpired state with the hash, so a state can only be used once.
+	TakeState(hash, provider string) (*models.OAuthState, error)
+	DeleteExpiredStates() error
+}
+
+type identityRepository struct {
+	db *gorm.DB
+}
+
+func (r *identityRepository) Find(provider, subject string) (*models.UserIdentity, error) {
+	var identity models.UserIdentity
+	if err := r.db.Table(consts.USER_IDENTITIES_TABLE).
+		Where("provider = ? AND subject = ?", provider, subject).
+		First(&identity).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &identity, nil
+}
+
+func (r *identityRepository) Create(identity *models.UserIdentity) error {
+	return translate(r.db.Table(consts.USER_IDENTITIES_TABLE).Create(identity).Error)
+}
+
+func (r *identityRepository) CreateState(state *models.OAuthState) error {
+	return r.db.Table(consts.OAUTH_STATES_TABLE).Create(state).Error
+}
+
+func (r *identityRepository) TakeState(hash, provider string) (*models.OAuthState, error) {
+	var state models.OAuthState
+	result := r.db.Table(consts.OAUTH_STATES_TABLE).
+		Clauses(clause.Returning{}).
+		Where("state_hash = ? AND provider = ? AND expires_at > ?", hash, provider, time.Now()).
+		Delete(&state)
+	if err := affected(result); err != nil {
+		return nil, err
+	}
+	return &state, nil
+}
+
+func (r *identityRepository) DeleteExpiredStates() error {
+	return r.db.Table(consts.OAUTH_STATES_TABLE).Where("expires_at < ?", time.Now()).Delete(&models.OAuthState{}).Error
+}
@@ -0,0 +1,21 @@
+package repository
+
+import (
+	"cnep-backend/pkg/consts"
+	"cnep-backend/source/models"
+
+	"gorm.io/gorm"
+)
+
+// MessageRepository stores chat messages.
+type MessageRepository interface {
+	Create(message *models.Message) error
+}
+
+type messageRepository struct {
+	db *gorm.DB
+}
+
+func (r *messageRepository) Create(message *models.Message) error {
+	return r.db.Table(consts.MESSAGES_TABLE).Create(message).Error
+}
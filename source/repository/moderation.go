@@ -0,0 +1,170 @@
+package repository
+
+import (
+	"time"
+
+	"cnep-backend/pkg/consts"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ReportTarget describes where a reportable item is stored and who wrote it.
+type ReportTarget struct {
+	Table        string
+	AuthorColumn string
+	// Hideable items can be hidden by moderators, users are suspended instead
+	Hideable bool
+}
+
+// ReportTargets are the items users can report, by target type.
+var ReportTargets = map[string]ReportTarget{
+	consts.REPORT_TARGET_POST:     {Table: consts.POSTS_TABLE, AuthorColumn: "user_id", Hideable: true},
+	consts.REPORT_TARGET_COMMENT:  {Table: consts.COMMENTS_TABLE, AuthorColumn: "user_id", Hideable: true},
+	consts.REPORT_TARGET_MESSAGE:  {Table: consts.MESSAGES_TABLE, AuthorColumn: "sender_id", Hideable: true},
+	consts.REPORT_TARGET_BUSINESS: {Table: consts.BUSINESSES_TABLE, AuthorColumn: "owner_id", Hideable: true},
+	consts.REPORT_TARGET_USER:     {Table: consts.USERS_TABLE, AuthorColumn: "id"},
+}
+
+// ModerationRepository stores reports and the moderation cases they are grouped in.
+type ModerationRepository interface {
+	// Author returns the author of a reportable item as seen by the reporter. Messages are only
+	// found for their participants and hidden items are not found. It returns ErrNotFound if there is no such item.
+	Author(targetType string, targetID, reporterID uint) (uint, error)
+	// OpenCase counts a report on the open case of the item, opening a case if there is none,
+	// and returns the ID of the case.
+	OpenCase(targetType string, targetID, authorID uint) (uint, error)
+	// CreateReport stores a report. It returns ErrDuplicate if the reporter already reported the case.
+	CreateReport(report *models.Report) error
+
+	// ListCases returns a page of the cases matching the filter and the total count of matches.
+	ListCases(filter CaseFilter, page utils.Page) ([]models.ModerationCase, int64, error)
+	GetCase(id uint) (*models.ModerationCase, error)
+	// LockCase is GetCase that also locks the case until the end of the transaction.
+	LockCase(id uint) (*models.ModerationCase, error)
+	UpdateCase(id uint, fields map[string]interface{}) error
+	// Reports returns the reports of the case with their reporters, oldest first.
+	Reports(caseID uint) ([]models.ReportWithReporter, error)
+
+	// Content returns the reported item as it is stored now.
+	Content(targetType string, targetID uint) (map[string]interface{}, error)
+	// Hide hides the reported item from feeds and listings.
+	Hide(targetType string, targetID uint) error
+}
+
+// CaseFilter narrows the moderation queue. An empty target type does not filter.
+type CaseFilter struct {
+	Status     string
+	TargetType string
+}
+
+type moderationRepository struct {
+	db *gorm.DB
+}
+
+func (r *moderationRepository) Author(targetType string, targetID, reporterID uint) (uint, error) {
+	target := ReportTargets[targetType]
+
+	query := r.db.Table(target.Table).Select(target.AuthorColumn).Where("id = ?", targetID)
+	if target.Hideable {
+		// Hidden items are gone for users, so they cannot be reported again
+		query = query.Scopes(Visible)
+	}
+	if targetType == consts.REPORT_TARGET_MESSAGE {
+		query = query.Where("(sender_id = ? OR receiver_id = ?)", reporterID, reporterID)
+	}
+
+	var authorID uint
+	if err := affected(query.Scan(&authorID)); err != nil {
+		return 0, err
+	}
+	return authorID, nil
+}
+
+func (r *moderationRepository) OpenCase(targetType string, targetID, authorID uint) (uint, error) {
+	var caseID uint
+	err := r.db.Raw(`
+		INSERT INTO moderation_cases (target_type, target_id, author_id, report_count)
+		VALUES (?, ?, ?, 1)
+		ON CONFLICT (target_type, target_id) WHERE status = 'open'
+		DO UPDATE SET report_count = moderation_cases.report_count + 1, updated_at = CURRENT_TIMESTAMP
+		RETURNING id`, targetType, targetID, authorID).Scan(&caseID).Error
+	return caseID, err
+}
+
+func (r *moderationRepository) CreateReport(report *models.Report) error {
+	return translate(r.db.Table(consts.REPORTS_TABLE).Create(report).Error)
+}
+
+func (r *moderationRepository) ListCases(filter CaseFilter, page utils.Page) ([]models.ModerationCase, int64, error) {
+	var cases []models.ModerationCase
+	var total int64
+
+	query := r.db.Table(consts.MODERATION_CASES_TABLE).Where("status = ?", filter.Status)
+	if filter.TargetType != "" {
+		query = query.Where("target_type = ?", filter.TargetType)
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := findPage(query, page, &cases); err != nil {
+		return nil, 0, err
+	}
+	return cases, total, nil
+}
+
+func (r *moderationRepository) GetCase(id uint) (*models.ModerationCase, error) {
+	return findCase(r.db, id)
+}
+
+func (r *moderationRepository) LockCase(id uint) (*models.ModerationCase, error) {
+	return findCase(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), id)
+}
+
+func findCase(db *gorm.DB, id uint) (*models.ModerationCase, error) {
+	var moderationCase models.ModerationCase
+	if err := db.Table(consts.MODERATION_CASES_TABLE).Where("id = ?", id).First(&moderationCase).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &moderationCase, nil
+}
+
+func (r *moderationRepository) UpdateCase(id uint, fields map[string]interface{}) error {
+	return affected(r.db.Table(consts.MODERATION_CASES_TABLE).Where("id = ?", id).Updates(fields))
+}
+
+func (r *moderationRepository) Reports(caseID uint) ([]models.ReportWithReporter, error) {
+	var reports []models.ReportWithReporter
+	err := r.db.Table(consts.REPORTS_TABLE).
+		Select("reports.id, reports.reason, reports.details, reports.reporter_id, users.name as reporter_name, reports.created_at").
+		Joins("JOIN users ON reports.reporter_id = users.id").
+		Where("reports.case_id = ?", caseID).
+		Order("reports.created_at ASC").
+		Scan(&reports).Error
+	return reports, err
+}
+
+func (r *moderationRepository) Content(targetType string, targetID uint) (map[string]interface{}, error) {
+	item := map[string]interface{}{}
+	if err := r.db.Table(ReportTargets[targetType].Table).Where("id = ?", targetID).Take(&item).Error; err != nil {
+		return nil, translate(err)
+	}
+	return item, nil
+}
+
+func (r *moderationRepository) Hide(targetType string, targetID uint) error {
+	return r.db.Table(ReportTargets[targetType].Table).
+		Where("id = ? AND hidden_at IS NULL", targetID).
+		Update("hidden_at", time.Now()).Error
+}
+
+// Visible is a query scope that leaves out content hidden by moderators. Every feed, listing and lookup
+// by ID of posts, comments, messages and business pages users read must apply it with db.Scopes(repository.Visible),
+// as BusinessRepository.GetPage does. Only the moderation queue reads hidden content.
+// The column is qualified with the table of the query, so the scope also works on joins.
+func Visible(db *gorm.DB) *gorm.DB {
+	return db.Where(clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: "hidden_at"}, Value: nil})
+}
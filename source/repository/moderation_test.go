@@ -0,0 +1,39 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+
+	"cnep-backend/pkg/consts"
+)
+
+func TestAuthorLeavesOutHiddenItems(t *testing.T) {
+	tests := []struct {
+		targetType string
+		hidden     string
+	}{
+		{consts.REPORT_TARGET_POST, `"posts"."hidden_at" IS NULL`},
+		{consts.REPORT_TARGET_COMMENT, `"comments"."hidden_at" IS NULL`},
+		{consts.REPORT_TARGET_MESSAGE, `"messages"."hidden_at" IS NULL`},
+		{consts.REPORT_TARGET_BUSINESS, `"businesses"."hidden_at" IS NULL`},
+		// Users cannot be hidden, they are suspended instead
+		{consts.REPORT_TARGET_USER, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.targetType, func(t *testing.T) {
+			db, recorder := dryRun(t)
+			repo := &moderationRepository{db: db}
+
+			repo.Author(tt.targetType, 1, 2)
+
+			sql := recorder.last(t)
+			if tt.hidden == "" && strings.Contains(sql, "hidden_at") {
+				t.Fatalf("expected no hidden filter, got %s", sql)
+			}
+			if tt.hidden != "" && !strings.Contains(sql, tt.hidden) {
+				t.Fatalf("expected %s in %s", tt.hidden, sql)
+			}
+		})
+	}
+}
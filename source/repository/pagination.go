@@ -0,0 +1,55 @@
+package repository
+
+import (
+	"cnep-backend/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+/*
+The paginate function is a query scope that returns the page of a list, using keyset pagination.
+It orders the list on the sort column and the ID column, starts after the cursor when there is one,
+and fetches one item more than the limit so the caller can tell whether there is a next page.
+It returns utils.ErrInvalidCursor when the cursor does not match the sort.
+*/
+func paginate(page utils.Page) (func(db *gorm.DB) *gorm.DB, error) {
+	var after interface{}
+	if page.Cursor != nil {
+		value, err := page.CursorValue()
+		if err != nil {
+			return nil, utils.ErrInvalidCursor
+		}
+		after = value
+	}
+
+	direction, compare := "ASC", ">"
+	if page.Desc {
+		direction, compare = "DESC", "<"
+	}
+
+	return func(db *gorm.DB) *gorm.DB {
+		if page.Cursor != nil {
+			db = db.Where("("+page.Sort.Column+", "+page.IDColumn+") "+compare+" (?, ?)", after, page.Cursor.ID)
+		}
+		return db.Order(page.Sort.Column + " " + direction + ", " + page.IDColumn + " " + direction).
+			Limit(page.Limit + 1)
+	}, nil
+}
+
+// findPage runs the query for the page into items with Find.
+func findPage(query *gorm.DB, page utils.Page, items interface{}) error {
+	scope, err := paginate(page)
+	if err != nil {
+		return err
+	}
+	return query.Scopes(scope).Find(items).Error
+}
+
+// scanPage runs the query for the page into items with Scan, for queries that select into a custom struct.
+func scanPage(query *gorm.DB, page utils.Page, items interface{}) error {
+	scope, err := paginate(page)
+	if err != nil {
+		return err
+	}
+	return query.Scopes(scope).Scan(items).Error
+}
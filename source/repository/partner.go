@@ -0,0 +1,154 @@
+package repository
+
+import (
+	"time"
+
+	"cnep-backend/pkg/consts"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/models"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PartnerRepository stores partner requests and partnerships. A pair of users has at most
+// one partner row, whichever of them sent the request.
+type PartnerRepository interface {
+	// FindPair returns the partner row of two users, whichever of them sent the request.
+	FindPair(a, b uint) (*models.Partner, error)
+	// LockPair is FindPair that also locks the row until the end of the transaction.
+	LockPair(a, b uint) (*models.Partner, error)
+	Create(partner *models.Partner) error
+	Update(id uint, fields map[string]interface{}) error
+	// Respond sets the status of the pending request the sender sent to the receiver.
+	// It returns ErrNotFound if there is no such request.
+	Respond(senderID, receiverID uint, status string) error
+	// DeletePending removes the pending request the sender sent to the receiver.
+	DeletePending(senderID, receiverID uint) error
+	// DeleteAccepted ends the partnership of two users, whichever of them sent the request.
+	DeleteAccepted(a, b uint) error
+	// Unlink removes any pending or accepted partner row of two users, in either direction.
+	Unlink(a, b uint) error
+	// Accepted returns the accepted partnerships between the viewer and the given users.
+	Accepted(viewerID uint, userIDs []uint) ([]models.Partner, error)
+
+	// ListAccepted, ListIncoming and ListOutgoing return a page of the partners of the user,
+	// the users who sent the user a pending request and the users the user sent one to.
+	// search matches the name or username when it is not empty. Users either way blocked are left out.
+	ListAccepted(userID uint, search string, page utils.Page) ([]models.PartnerUser, error)
+	ListIncoming(userID uint, search string, page utils.Page) ([]models.PartnerUser, error)
+	ListOutgoing(userID uint, search string, page utils.Page) ([]models.PartnerUser, error)
+
+	// Suggestions returns a page of the users scored as likely partners of the user.
+	Suggestions(userID uint, scoring SuggestionScoring, page utils.Page) ([]models.PartnerSuggestion, error)
+}
+
+type partnerRepository struct {
+	db *gorm.DB
+}
+
+func (r *partnerRepository) FindPair(a, b uint) (*models.Partner, error) {
+	return findPartnerPair(r.db, a, b)
+}
+
+func (r *partnerRepository) LockPair(a, b uint) (*models.Partner, error) {
+	return findPartnerPair(r.db.Clauses(clause.Locking{Strength: "UPDATE"}), a, b)
+}
+
+func findPartnerPair(db *gorm.DB, a, b uint) (*models.Partner, error) {
+	var partner models.Partner
+	if err := db.Table(consts.PARTNERS_TABLE).
+		Where("(sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)", a, b, b, a).
+		First(&partner).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &partner, nil
+}
+
+func (r *partnerRepository) Create(partner *models.Partner) error {
+	return translate(r.db.Table(consts.PARTNERS_TABLE).Create(partner).Error)
+}
+
+func (r *partnerRepository) Update(id uint, fields map[string]interface{}) error {
+	return affected(r.db.Table(consts.PARTNERS_TABLE).Where("id = ?", id).Updates(fields))
+}
+
+func (r *partnerRepository) Respond(senderID, receiverID uint, status string) error {
+	return affected(r.db.Table(consts.PARTNERS_TABLE).
+		Where("receiver_id = ? AND sender_id = ? AND status = ?", receiverID, senderID, consts.PARTNER_STATUS_PENDING).
+		Updates(map[string]interface{}{"status": status, "updated_at": time.Now()}))
+}
+
+func (r *partnerRepository) DeletePending(senderID, receiverID uint) error {
+	return affected(r.db.Table(consts.PARTNERS_TABLE).
+		Where("sender_id = ? AND receiver_id = ? AND status = ?", senderID, receiverID, consts.PARTNER_STATUS_PENDING).
+		Delete(&models.Partner{}))
+}
+
+func (r *partnerRepository) DeleteAccepted(a, b uint) error {
+	return affected(r.db.Table(consts.PARTNERS_TABLE).
+		Where("((sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)) AND status = ?",
+			a, b, b, a, consts.PARTNER_STATUS_ACCEPTED).
+		Delete(&models.Partner{}))
+}
+
+func (r *partnerRepository) Unlink(a, b uint) error {
+	return r.db.Table(consts.PARTNERS_TABLE).
+		Where("((sender_id = ? AND receiver_id = ?) OR (sender_id = ? AND receiver_id = ?)) AND status IN ?",
+			a, b, b, a, []string{consts.PARTNER_STATUS_PENDING, consts.PARTNER_STATUS_ACCEPTED}).
+		Delete(&models.Partner{}).Error
+}
+
+func (r *partnerRepository) Accepted(viewerID uint, userIDs []uint) ([]models.Partner, error) {
+	var partners []models.Partner
+	err := r.db.Table(consts.PARTNERS_TABLE).
+		Where("status = ? AND ((sender_id = ? AND receiver_id IN ?) OR (receiver_id = ? AND sender_id IN ?))",
+			consts.PARTNER_STATUS_ACCEPTED, viewerID, userIDs, viewerID, userIDs).
+		Find(&partners).Error
+	return partners, err
+}
+
+func (r *partnerRepository) ListAccepted(userID uint, search string, page utils.Page) ([]models.PartnerUser, error) {
+	query := r.db.Table(consts.USERS_TABLE).
+		Select("users.*, partners.updated_at AS since").
+		Joins("JOIN partners ON (partners.sender_id = ? AND partners.receiver_id = users.id) OR (partners.receiver_id = ? AND partners.sender_id = users.id)", userID, userID).
+		Where("partners.status = ?", consts.PARTNER_STATUS_ACCEPTED).
+		Scopes(NotBlocked(userID, "users.id"))
+
+	return partnerList(query, search, page)
+}
+
+func (r *partnerRepository) ListIncoming(userID uint, search string, page utils.Page) ([]models.PartnerUser, error) {
+	query := r.db.Table(consts.USERS_TABLE).
+		Select("users.*, partners.sent_at AS since").
+		Joins("JOIN partners ON partners.sender_id = users.id").
+		Where("partners.receiver_id = ? AND partners.status = ?", userID, consts.PARTNER_STATUS_PENDING).
+		Scopes(NotBlocked(userID, "users.id"))
+
+	return partnerList(query, search, page)
+}
+
+func (r *partnerRepository) ListOutgoing(userID uint, search string, page utils.Page) ([]models.PartnerUser, error) {
+	query := r.db.Table(consts.USERS_TABLE).
+		Select("users.*, partners.sent_at AS since").
+		Joins("JOIN partners ON partners.receiver_id = users.id").
+		Where("partners.sender_id = ? AND partners.status = ?", userID, consts.PARTNER_STATUS_PENDING).
+		Scopes(NotBlocked(userID, "users.id"))
+
+	return partnerList(query, search, page)
+}
+
+// partnerList returns a page of a partner list query, filtered on the name search.
+func partnerList(query *gorm.DB, search string, page utils.Page) ([]models.PartnerUser, error) {
+	var users []models.PartnerUser
+
+	if search != "" {
+		like := "%" + utils.EscapeLike(search) + "%"
+		query = query.Where("(users.name ILIKE ? OR users.username ILIKE ?)", like, like)
+	}
+
+	if err := scanPage(query, page, &users); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
@@ -0,0 +1,87 @@
+package repotest
+
+import (
+	"slices"
+	"time"
+
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
+)
+
+type businesses struct {
+	s *Store
+}
+
+func (r *businesses) CreatePage(page *models.BusinessPage) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	page.ID = r.s.nextID()
+	stamp(&page.CreatedAt)
+	stamp(&page.UpdatedAt)
+	r.s.data.pages = append(r.s.data.pages, *page)
+	return nil
+}
+
+func (r *businesses) GetPage(id uint) (*models.BusinessPage, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	i := slices.IndexFunc(r.s.data.pages, func(page models.BusinessPage) bool { return page.ID == id && page.HiddenAt == nil })
+	if i < 0 {
+		return nil, repository.ErrNotFound
+	}
+	found := r.s.data.pages[i]
+	return &found, nil
+}
+
+func (r *businesses) UpdatePage(id uint, fields map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	i := slices.IndexFunc(r.s.data.pages, func(page models.BusinessPage) bool { return page.ID == id })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	return update(&r.s.data.pages[i], fields)
+}
+
+func (r *businesses) CreateProduct(product *models.Product) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	product.ID = r.s.nextID()
+	stamp(&product.CreatedAt)
+	stamp(&product.UpdatedAt)
+	r.s.data.products = append(r.s.data.products, *product)
+	return nil
+}
+
+func (r *businesses) GetProduct(id uint) (*models.Product, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	i := slices.IndexFunc(r.s.data.products, func(product models.Product) bool { return product.ID == id })
+	if i < 0 {
+		return nil, repository.ErrNotFound
+	}
+	found := r.s.data.products[i]
+	return &found, nil
+}
+
+func (r *businesses) AddProductImage(id uint, url string, variants models.ImageVariants, maxImages int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	i := slices.IndexFunc(r.s.data.products, func(product models.Product) bool { return product.ID == id })
+	if i < 0 || len(r.s.data.products[i].Images) >= maxImages {
+		return false, nil
+	}
+
+	// Append to copies, so a snapshot taken by Transaction keeps its own slices
+	product := &r.s.data.products[i]
+	product.Images = append(slices.Clone(product.Images), url)
+	product.ImageVariants = append(slices.Clone(product.ImageVariants), variants)
+	product.UpdatedAt = time.Now()
+	return true, nil
+}
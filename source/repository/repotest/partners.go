@@ -0,0 +1,296 @@
+package repotest
+
+import (
+	"slices"
+	"time"
+
+	"cnep-backend/pkg/consts"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
+)
+
+type partners struct {
+	s *Store
+}
+
+// index returns the index of the partner row the match function picks, or -1. The caller holds the lock.
+func (r *partners) index(match func(partner models.Partner) bool) int {
+	return slices.IndexFunc(r.s.data.partners, match)
+}
+
+// isPair reports whether the row links a and b, whichever of them sent the request.
+func isPair(partner models.Partner, a, b uint) bool {
+	return (partner.SenderID == a && partner.ReceiverID == b) || (partner.SenderID == b && partner.ReceiverID == a)
+}
+
+func (r *partners) FindPair(a, b uint) (*models.Partner, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	i := r.index(func(partner models.Partner) bool { return isPair(partner, a, b) })
+	if i < 0 {
+		return nil, repository.ErrNotFound
+	}
+	found := r.s.data.partners[i]
+	return &found, nil
+}
+
+// LockPair is FindPair, the in-memory store does not isolate transactions.
+func (r *partners) LockPair(a, b uint) (*models.Partner, error) {
+	return r.FindPair(a, b)
+}
+
+func (r *partners) Create(partner *models.Partner) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if r.index(func(other models.Partner) bool { return isPair(other, partner.SenderID, partner.ReceiverID) }) >= 0 {
+		return repository.ErrDuplicate
+	}
+
+	partner.ID = r.s.nextID()
+	stamp(&partner.SentAt)
+	stamp(&partner.UpdatedAt)
+	r.s.data.partners = append(r.s.data.partners, *partner)
+	return nil
+}
+
+func (r *partners) Update(id uint, fields map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	i := r.index(func(partner models.Partner) bool { return partner.ID == id })
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	return update(&r.s.data.partners[i], fields)
+}
+
+func (r *partners) Respond(senderID, receiverID uint, status string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	i := r.index(func(partner models.Partner) bool {
+		return partner.SenderID == senderID && partner.ReceiverID == receiverID && partner.Status == consts.PARTNER_STATUS_PENDING
+	})
+	if i < 0 {
+		return repository.ErrNotFound
+	}
+	r.s.data.partners[i].Status = status
+	r.s.data.partners[i].UpdatedAt = time.Now()
+	return nil
+}
+
+// delete removes the partner rows the match function picks, returning ErrNotFound when there are none.
+func (r *partners) delete(match func(partner models.Partner) bool) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	before := len(r.s.data.partners)
+	r.s.data.partners = slices.DeleteFunc(r.s.data.partners, match)
+	if len(r.s.data.partners) == before {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *partners) DeletePending(senderID, receiverID uint) error {
+	return r.delete(func(partner models.Partner) bool {
+		return partner.SenderID == senderID && partner.ReceiverID == receiverID && partner.Status == consts.PARTNER_STATUS_PENDING
+	})
+}
+
+func (r *partners) DeleteAccepted(a, b uint) error {
+	return r.delete(func(partner models.Partner) bool {
+		return isPair(partner, a, b) && partner.Status == consts.PARTNER_STATUS_ACCEPTED
+	})
+}
+
+func (r *partners) Unlink(a, b uint) error {
+	// Unlinking users who are not linked is not an error
+	_ = r.delete(func(partner models.Partner) bool {
+		return isPair(partner, a, b) && partner.Status != consts.PARTNER_STATUS_DECLINED
+	})
+	return nil
+}
+
+func (r *partners) Accepted(viewerID uint, userIDs []uint) ([]models.Partner, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var accepted []models.Partner
+	for _, partner := range r.s.data.partners {
+		if partner.Status != consts.PARTNER_STATUS_ACCEPTED {
+			continue
+		}
+		if (partner.SenderID == viewerID && slices.Contains(userIDs, partner.ReceiverID)) ||
+			(partner.ReceiverID == viewerID && slices.Contains(userIDs, partner.SenderID)) {
+			accepted = append(accepted, partner)
+		}
+	}
+	return accepted, nil
+}
+
+func (r *partners) ListAccepted(userID uint, search string, page utils.Page) ([]models.PartnerUser, error) {
+	return nil, ErrUnsupported
+}
+
+func (r *partners) ListIncoming(userID uint, search string, page utils.Page) ([]models.PartnerUser, error) {
+	return nil, ErrUnsupported
+}
+
+func (r *partners) ListOutgoing(userID uint, search string, page utils.Page) ([]models.PartnerUser, error) {
+	return nil, ErrUnsupported
+}
+
+func (r *partners) Suggestions(userID uint, scoring repository.SuggestionScoring, page utils.Page) ([]models.PartnerSuggestion, error) {
+	return nil, ErrUnsupported
+}
+
+type blocks struct {
+	s *Store
+}
+
+func (r *blocks) Create(blockerID, blockedID uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if slices.ContainsFunc(r.s.data.blocks, func(block models.UserBlock) bool {
+		return block.BlockerID == blockerID && block.BlockedID == blockedID
+	}) {
+		return nil
+	}
+
+	r.s.data.blocks = append(r.s.data.blocks, models.UserBlock{
+		ID:        r.s.nextID(),
+		BlockerID: blockerID,
+		BlockedID: blockedID,
+		CreatedAt: time.Now(),
+	})
+	return nil
+}
+
+func (r *blocks) Delete(blockerID, blockedID uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	before := len(r.s.data.blocks)
+	r.s.data.blocks = slices.DeleteFunc(r.s.data.blocks, func(block models.UserBlock) bool {
+		return block.BlockerID == blockerID && block.BlockedID == blockedID
+	})
+	if len(r.s.data.blocks) == before {
+		return repository.ErrNotFound
+	}
+	return nil
+}
+
+func (r *blocks) Between(userID, otherID uint) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	return slices.ContainsFunc(r.s.data.blocks, func(block models.UserBlock) bool {
+		return (block.BlockerID == userID && block.BlockedID == otherID) || (block.BlockerID == otherID && block.BlockedID == userID)
+	}), nil
+}
+
+func (r *blocks) List(blockerID uint, page utils.Page) ([]models.BlockedUser, error) {
+	return nil, ErrUnsupported
+}
+
+type feedback struct {
+	s *Store
+}
+
+func (r *feedback) Create(feedback *models.Feedback) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	feedback.ID = r.s.nextID()
+	stamp(&feedback.CreatedAt)
+	stamp(&feedback.UpdatedAt)
+	r.s.data.feedback = append(r.s.data.feedback, *feedback)
+	return nil
+}
+
+func (r *feedback) ListReceived(viewerID, userID uint, rating int, page utils.Page) ([]models.FeedbackSender, error) {
+	return nil, ErrUnsupported
+}
+
+type audit struct {
+	s *Store
+}
+
+func (r *audit) Create(entry *models.AuditLog) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	entry.ID = r.s.nextID()
+	stamp(&entry.CreatedAt)
+	r.s.data.audit = append(r.s.data.audit, *entry)
+	return nil
+}
+
+func (r *audit) List(filter repository.AuditFilter, page utils.Page) ([]models.AuditLog, int64, error) {
+	return nil, 0, ErrUnsupported
+}
+
+type messages struct {
+	s *Store
+}
+
+func (r *messages) Create(message *models.Message) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	message.ID = r.s.nextID()
+	stamp(&message.CreatedAt)
+	r.s.data.messages = append(r.s.data.messages, *message)
+	return nil
+}
+
+// moderation stores nothing, the moderation queue works on posts, comments and business pages
+// the in-memory store does not have.
+type moderation struct {
+	s *Store
+}
+
+func (r *moderation) Author(targetType string, targetID, reporterID uint) (uint, error) {
+	return 0, ErrUnsupported
+}
+
+func (r *moderation) OpenCase(targetType string, targetID, authorID uint) (uint, error) {
+	return 0, ErrUnsupported
+}
+
+func (r *moderation) CreateReport(report *models.Report) error {
+	return ErrUnsupported
+}
+
+func (r *moderation) ListCases(filter repository.CaseFilter, page utils.Page) ([]models.ModerationCase, int64, error) {
+	return nil, 0, ErrUnsupported
+}
+
+func (r *moderation) GetCase(id uint) (*models.ModerationCase, error) {
+	return nil, ErrUnsupported
+}
+
+func (r *moderation) LockCase(id uint) (*models.ModerationCase, error) {
+	return nil, ErrUnsupported
+}
+
+func (r *moderation) UpdateCase(id uint, fields map[string]interface{}) error {
+	return ErrUnsupported
+}
+
+func (r *moderation) Reports(caseID uint) ([]models.ReportWithReporter, error) {
+	return nil, ErrUnsupported
+}
+
+func (r *moderation) Content(targetType string, targetID uint) (map[string]interface{}, error) {
+	return nil, ErrUnsupported
+}
+
+func (r *moderation) Hide(targetType string, targetID uint) error {
+	return ErrUnsupported
+}
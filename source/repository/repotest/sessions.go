@@ -0,0 +1,376 @@
+package repotest
+
+import (
+	"slices"
+	"time"
+
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
+)
+
+type sessions struct {
+	s *Store
+}
+
+// get returns the stored session the match function picks, or ErrNotFound. The caller holds the lock.
+func (r *sessions) get(match func(session *models.Session) bool) (*models.Session, error) {
+	for i := range r.s.data.sessions {
+		if match(&r.s.data.sessions[i]) {
+			return &r.s.data.sessions[i], nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *sessions) Create(session *models.Session) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	session.ID = r.s.nextID()
+	stamp(&session.LastSeenAt)
+	stamp(&session.CreatedAt)
+	stamp(&session.UpdatedAt)
+	r.s.data.sessions = append(r.s.data.sessions, *session)
+	return nil
+}
+
+func (r *sessions) Get(id uint) (*models.Session, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	session, err := r.get(func(session *models.Session) bool { return session.ID == id })
+	if err != nil {
+		return nil, err
+	}
+	found := *session
+	return &found, nil
+}
+
+func (r *sessions) GetActive(userID, id uint) (*models.Session, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	session, err := r.get(func(session *models.Session) bool {
+		return session.ID == id && session.UserID == userID && session.RevokedAt == nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	found := *session
+	return &found, nil
+}
+
+func (r *sessions) GetWithUser(userID, id uint) (*repository.SessionWithUser, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	session, err := r.get(func(session *models.Session) bool { return session.ID == id && session.UserID == userID })
+	if err != nil {
+		return nil, err
+	}
+	user, err := (&users{r.s}).byID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &repository.SessionWithUser{
+		Session:        *session,
+		SuspendedAt:    user.SuspendedAt,
+		SuspendedUntil: user.SuspendedUntil,
+	}, nil
+}
+
+func (r *sessions) ListActive(userID uint) ([]models.Session, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var active []models.Session
+	for _, session := range r.s.data.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			active = append(active, session)
+		}
+	}
+	slices.SortStableFunc(active, func(a, b models.Session) int { return b.LastSeenAt.Compare(a.LastSeenAt) })
+	return active, nil
+}
+
+func (r *sessions) CountActive(userID uint) (int64, error) {
+	active, err := r.ListActive(userID)
+	return int64(len(active)), err
+}
+
+func (r *sessions) Update(id uint, fields map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	session, err := r.get(func(session *models.Session) bool { return session.ID == id })
+	if err != nil {
+		return err
+	}
+	return update(session, fields)
+}
+
+func (r *sessions) Revoke(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if session, err := r.get(func(session *models.Session) bool { return session.ID == id }); err == nil && session.RevokedAt == nil {
+		now := time.Now()
+		session.RevokedAt = &now
+	}
+	return nil
+}
+
+func (r *sessions) RevokeOthers(userID, exceptID uint) (int64, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var revoked int64
+	now := time.Now()
+	for i := range r.s.data.sessions {
+		session := &r.s.data.sessions[i]
+		if session.UserID == userID && session.ID != exceptID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func (r *sessions) CreateRefreshToken(token *models.RefreshToken) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if slices.ContainsFunc(r.s.data.refreshTokens, func(other models.RefreshToken) bool { return other.TokenHash == token.TokenHash }) {
+		return repository.ErrDuplicate
+	}
+
+	token.ID = r.s.nextID()
+	stamp(&token.CreatedAt)
+	r.s.data.refreshTokens = append(r.s.data.refreshTokens, *token)
+	return nil
+}
+
+func (r *sessions) GetRefreshToken(hash string) (*models.RefreshToken, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, token := range r.s.data.refreshTokens {
+		if token.TokenHash == hash {
+			return &token, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *sessions) UseRefreshToken(id uint) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for i := range r.s.data.refreshTokens {
+		token := &r.s.data.refreshTokens[i]
+		if token.ID == id && token.UsedAt == nil {
+			now := time.Now()
+			token.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type codes struct {
+	s *Store
+}
+
+func (r *codes) Create(code *models.OneTimeCode) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	code.ID = r.s.nextID()
+	stamp(&code.CreatedAt)
+	r.s.data.codes = append(r.s.data.codes, *code)
+	return nil
+}
+
+func (r *codes) DeleteUnused(userID uint, purpose string) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.data.codes = slices.DeleteFunc(r.s.data.codes, func(code models.OneTimeCode) bool {
+		return code.UserID == userID && code.Purpose == purpose && code.UsedAt == nil
+	})
+	return nil
+}
+
+func (r *codes) Active(userID uint, purpose string) (*models.OneTimeCode, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var active *models.OneTimeCode
+	now := time.Now()
+	for _, code := range r.s.data.codes {
+		if code.UserID == userID && code.Purpose == purpose && code.UsedAt == nil && code.ExpiresAt.After(now) &&
+			(active == nil || code.CreatedAt.After(active.CreatedAt)) {
+			found := code
+			active = &found
+		}
+	}
+	if active == nil {
+		return nil, repository.ErrNotFound
+	}
+	return active, nil
+}
+
+func (r *codes) Attempt(id uint, maxAttempts int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for i := range r.s.data.codes {
+		code := &r.s.data.codes[i]
+		if code.ID == id && code.Attempts < maxAttempts {
+			code.Attempts++
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *codes) Use(id uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for i := range r.s.data.codes {
+		code := &r.s.data.codes[i]
+		if code.ID == id && code.UsedAt == nil {
+			now := time.Now()
+			code.UsedAt = &now
+			return nil
+		}
+	}
+	return repository.ErrNotFound
+}
+
+type recoveryCodes struct {
+	s *Store
+}
+
+func (r *recoveryCodes) Create(codes []models.RecoveryCode) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for i := range codes {
+		codes[i].ID = r.s.nextID()
+		stamp(&codes[i].CreatedAt)
+		r.s.data.recoveryCodes = append(r.s.data.recoveryCodes, codes[i])
+	}
+	return nil
+}
+
+func (r *recoveryCodes) DeleteAll(userID uint) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	r.s.data.recoveryCodes = slices.DeleteFunc(r.s.data.recoveryCodes, func(code models.RecoveryCode) bool {
+		return code.UserID == userID
+	})
+	return nil
+}
+
+func (r *recoveryCodes) Unused(userID uint) ([]models.RecoveryCode, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	var unused []models.RecoveryCode
+	for _, code := range r.s.data.recoveryCodes {
+		if code.UserID == userID && code.UsedAt == nil {
+			unused = append(unused, code)
+		}
+	}
+	return unused, nil
+}
+
+func (r *recoveryCodes) Use(id uint) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for i := range r.s.data.recoveryCodes {
+		code := &r.s.data.recoveryCodes[i]
+		if code.ID == id && code.UsedAt == nil {
+			now := time.Now()
+			code.UsedAt = &now
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type identities struct {
+	s *Store
+}
+
+func (r *identities) Find(provider, subject string) (*models.UserIdentity, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, identity := range r.s.data.identities {
+		if identity.Provider == provider && identity.Subject == subject {
+			return &identity, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *identities) Create(identity *models.UserIdentity) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if slices.ContainsFunc(r.s.data.identities, func(other models.UserIdentity) bool {
+		return other.Provider == identity.Provider && other.Subject == identity.Subject
+	}) {
+		return repository.ErrDuplicate
+	}
+
+	identity.ID = r.s.nextID()
+	stamp(&identity.CreatedAt)
+	r.s.data.identities = append(r.s.data.identities, *identity)
+	return nil
+}
+
+func (r *identities) CreateState(state *models.OAuthState) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	if slices.ContainsFunc(r.s.data.states, func(other models.OAuthState) bool { return other.StateHash == state.StateHash }) {
+		return repository.ErrDuplicate
+	}
+
+	state.ID = r.s.nextID()
+	stamp(&state.CreatedAt)
+	r.s.data.states = append(r.s.data.states, *state)
+	return nil
+}
+
+func (r *identities) TakeState(hash, provider string) (*models.OAuthState, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := time.Now()
+	for i, state := range r.s.data.states {
+		if state.StateHash == hash && state.Provider == provider && state.ExpiresAt.After(now) {
+			r.s.data.states = slices.Delete(r.s.data.states, i, i+1)
+			return &state, nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *identities) DeleteExpiredStates() error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	now := time.Now()
+	r.s.data.states = slices.DeleteFunc(r.s.data.states, func(state models.OAuthState) bool {
+		return state.ExpiresAt.Before(now)
+	})
+	return nil
+}
@@ -0,0 +1,169 @@
+// Package repotest provides an in-memory repository.Store, so services can be tested without a database.
+package repotest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
+
+	"gorm.io/gorm/schema"
+)
+
+// ErrUnsupported is returned by the queries the fake does not implement, such as the paged
+// listings and the moderation queue. Test those against the database with the apitest harness.
+var ErrUnsupported = errors.New("not supported by the in-memory store")
+
+// Store is an in-memory repository.Store. It keeps the unique constraints services rely on
+// and treats a transaction as all or nothing, but it does not isolate concurrent transactions.
+type Store struct {
+	mu   sync.Mutex
+	data data
+}
+
+// data holds every table of the store. Rows are kept by value, so a copy of the slices is a snapshot.
+type data struct {
+	lastID        uint
+	users         []models.User
+	sessions      []models.Session
+	refreshTokens []models.RefreshToken
+	codes         []models.OneTimeCode
+	recoveryCodes []models.RecoveryCode
+	identities    []models.UserIdentity
+	states        []models.OAuthState
+	partners      []models.Partner
+	blocks        []models.UserBlock
+	feedback      []models.Feedback
+	audit         []models.AuditLog
+	messages      []models.Message
+	pages         []models.BusinessPage
+	products      []models.Product
+	topics        []models.Topic
+}
+
+func (d data) clone() data {
+	d.users = slices.Clone(d.users)
+	d.sessions = slices.Clone(d.sessions)
+	d.refreshTokens = slices.Clone(d.refreshTokens)
+	d.codes = slices.Clone(d.codes)
+	d.recoveryCodes = slices.Clone(d.recoveryCodes)
+	d.identities = slices.Clone(d.identities)
+	d.states = slices.Clone(d.states)
+	d.partners = slices.Clone(d.partners)
+	d.blocks = slices.Clone(d.blocks)
+	d.feedback = slices.Clone(d.feedback)
+	d.audit = slices.Clone(d.audit)
+	d.messages = slices.Clone(d.messages)
+	d.pages = slices.Clone(d.pages)
+	d.products = slices.Clone(d.products)
+	d.topics = slices.Clone(d.topics)
+	return d
+}
+
+// New returns an empty store.
+func New() *Store {
+	return &Store{}
+}
+
+func (s *Store) Users() repository.UserRepository                 { return &users{s} }
+func (s *Store) Sessions() repository.SessionRepository           { return &sessions{s} }
+func (s *Store) Codes() repository.CodeRepository                 { return &codes{s} }
+func (s *Store) RecoveryCodes() repository.RecoveryCodeRepository { return &recoveryCodes{s} }
+func (s *Store) Identities() repository.IdentityRepository        { return &identities{s} }
+func (s *Store) Partners() repository.PartnerRepository           { return &partners{s} }
+func (s *Store) Blocks() repository.BlockRepository               { return &blocks{s} }
+func (s *Store) Feedback() repository.FeedbackRepository          { return &feedback{s} }
+func (s *Store) Moderation() repository.ModerationRepository      { return &moderation{s} }
+func (s *Store) Audit() repository.AuditRepository                { return &audit{s} }
+func (s *Store) Messages() repository.MessageRepository           { return &messages{s} }
+func (s *Store) Businesses() repository.BusinessRepository        { return &businesses{s} }
+func (s *Store) Topics() repository.TopicRepository               { return &topics{s} }
+
+// Transaction runs fn on the store itself and restores the data as it was before when fn fails.
+func (s *Store) Transaction(fn func(tx repository.Store) error) error {
+	s.mu.Lock()
+	snapshot := s.data.clone()
+	s.mu.Unlock()
+
+	if err := fn(s); err != nil {
+		s.mu.Lock()
+		s.data = snapshot
+		s.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// User returns a copy of the stored user, so tests can check what a service wrote.
+func (s *Store) User(id uint) (models.User, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, user := range s.data.users {
+		if user.ID == id {
+			return user, true
+		}
+	}
+	return models.User{}, false
+}
+
+// nextID returns a new primary key. The keys are unique across tables, which no service depends on.
+func (s *Store) nextID() uint {
+	s.data.lastID++
+	return s.data.lastID
+}
+
+// stamp fills in the defaults the database would set on insert.
+func stamp(created *time.Time) {
+	if created.IsZero() {
+		*created = time.Now()
+	}
+}
+
+var schemas sync.Map
+
+// update sets the columns of row, a pointer to a model, the way gorm maps column names to fields.
+// Expressions such as gorm.Expr are not supported.
+func update(row interface{}, fields map[string]interface{}) error {
+	sch, err := schema.Parse(row, &schemas, schema.NamingStrategy{})
+	if err != nil {
+		return err
+	}
+
+	value := reflect.ValueOf(row).Elem()
+	for column, v := range fields {
+		field := sch.LookUpField(column)
+		if field == nil {
+			return fmt.Errorf("unknown column %s of %s", column, sch.Table)
+		}
+		if err := field.Set(context.Background(), value, v); err != nil {
+			return fmt.Errorf("column %s: %w", column, err)
+		}
+	}
+
+	if field := sch.LookUpField("updated_at"); field != nil {
+		if _, ok := fields["updated_at"]; !ok {
+			return field.Set(context.Background(), value, time.Now())
+		}
+	}
+	return nil
+}
+
+// copyFields copies the fields of src to the fields of dst with the same name and type,
+// the way a query selects a model table into a narrower struct.
+func copyFields(dst, src interface{}) {
+	to := reflect.ValueOf(dst).Elem()
+	from := reflect.ValueOf(src).Elem()
+	for i := 0; i < to.NumField(); i++ {
+		field := from.FieldByName(to.Type().Field(i).Name)
+		if field.IsValid() && field.Type() == to.Field(i).Type() {
+			to.Field(i).Set(field)
+		}
+	}
+}
@@ -0,0 +1,34 @@
+package repotest
+
+import (
+	"slices"
+
+	"cnep-backend/source/models"
+)
+
+type topics struct {
+	s *Store
+}
+
+func (r *topics) Create(topic *models.Topic) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	topic.ID = r.s.nextID()
+	stamp(&topic.CreatedAt)
+	stamp(&topic.UpdatedAt)
+	r.s.data.topics = append(r.s.data.topics, *topic)
+	return nil
+}
+
+func (r *topics) Exist(ids []int64) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	for _, id := range ids {
+		if !slices.ContainsFunc(r.s.data.topics, func(topic models.Topic) bool { return int64(topic.ID) == id }) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
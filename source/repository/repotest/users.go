@@ -0,0 +1,247 @@
+package repotest
+
+import (
+	"strings"
+	"time"
+
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
+)
+
+type users struct {
+	s *Store
+}
+
+// get returns the stored user the match function picks, or ErrNotFound. The caller holds the lock.
+func (r *users) get(match func(user *models.User) bool) (*models.User, error) {
+	for i := range r.s.data.users {
+		if match(&r.s.data.users[i]) {
+			return &r.s.data.users[i], nil
+		}
+	}
+	return nil, repository.ErrNotFound
+}
+
+func (r *users) byID(id uint) (*models.User, error) {
+	return r.get(func(user *models.User) bool { return user.ID == id })
+}
+
+func (r *users) Get(id uint) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, err := r.byID(id)
+	if err != nil {
+		return nil, err
+	}
+	found := *user
+	return &found, nil
+}
+
+func (r *users) GetByEmail(email string) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, err := r.get(func(user *models.User) bool { return user.Email == email })
+	if err != nil {
+		return nil, err
+	}
+	found := *user
+	return &found, nil
+}
+
+func (r *users) GetByEmailFold(email string) (*models.User, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, err := r.get(func(user *models.User) bool { return strings.EqualFold(user.Email, email) })
+	if err != nil {
+		return nil, err
+	}
+	found := *user
+	return &found, nil
+}
+
+func (r *users) Exists(id uint) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	_, err := r.byID(id)
+	return err == nil, nil
+}
+
+func (r *users) EmailExists(email string) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	_, err := r.get(func(user *models.User) bool { return user.Email == email })
+	return err == nil, nil
+}
+
+func (r *users) Create(user *models.User) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	// The email is unique regardless of case, and so is the username
+	if _, err := r.get(func(other *models.User) bool {
+		return strings.EqualFold(other.Email, user.Email) || other.Username == user.Username
+	}); err == nil {
+		return repository.ErrDuplicate
+	}
+
+	if user.Role == "" {
+		user.Role = "user"
+	}
+	if user.Locale == "" {
+		user.Locale = "en"
+	}
+	if user.Privacy == (models.PrivacySettings{}) {
+		user.Privacy = models.PrivacySettings{EmailVisibility: "partners", PhoneVisibility: "private", AddressVisibility: "partners"}
+	}
+	user.ID = r.s.nextID()
+	stamp(&user.CreatedAt)
+	stamp(&user.UpdatedAt)
+
+	r.s.data.users = append(r.s.data.users, *user)
+	return nil
+}
+
+func (r *users) Update(id uint, fields map[string]interface{}) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, err := r.byID(id)
+	if err != nil {
+		return err
+	}
+
+	updated := *user
+	if err := update(&updated, fields); err != nil {
+		return err
+	}
+	if _, err := r.get(func(other *models.User) bool {
+		return other.ID != id && (strings.EqualFold(other.Email, updated.Email) || other.Username == updated.Username)
+	}); err == nil {
+		return repository.ErrDuplicate
+	}
+
+	*user = updated
+	return nil
+}
+
+func (r *users) Role(id uint) (string, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, err := r.byID(id)
+	if err != nil {
+		return "", err
+	}
+	return user.Role, nil
+}
+
+func (r *users) Profile(id uint) (*models.UserResponse, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, err := r.byID(id)
+	if err != nil {
+		return nil, err
+	}
+
+	var profile models.UserResponse
+	copyFields(&profile, user)
+	return &profile, nil
+}
+
+func (r *users) Privacy(id uint) (*models.PrivacySettings, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, err := r.byID(id)
+	if err != nil {
+		return nil, err
+	}
+	privacy := user.Privacy
+	return &privacy, nil
+}
+
+func (r *users) ClaimOTPSend(id uint, at time.Time, cooldown time.Duration, dailyLimit int) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, err := r.byID(id)
+	if err != nil {
+		return false, nil
+	}
+
+	windowOver := user.OTPSendWindowStart == nil || !user.OTPSendWindowStart.After(at.Add(-24*time.Hour))
+	if user.OTPLastSentAt != nil && user.OTPLastSentAt.After(at.Add(-cooldown)) {
+		return false, nil
+	}
+	if !windowOver && user.OTPSendCount >= dailyLimit {
+		return false, nil
+	}
+
+	user.OTPLastSentAt = &at
+	if windowOver {
+		user.OTPSendWindowStart = &at
+		user.OTPSendCount = 1
+	} else {
+		user.OTPSendCount++
+	}
+	return true, nil
+}
+
+func (r *users) RecordTwoFactorFailure(id uint, maxAttempts int, lockUntil time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, err := r.byID(id)
+	if err != nil {
+		return nil
+	}
+
+	if user.OTPFailedAttempts+1 >= maxAttempts {
+		user.LockedUntil = &lockUntil
+		user.OTPFailedAttempts = 0
+	} else {
+		user.OTPFailedAttempts++
+	}
+	return nil
+}
+
+func (r *users) RecordOTPFailure(id uint, maxFailures int, lockUntil time.Time) error {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, err := r.byID(id)
+	if err != nil {
+		return nil
+	}
+
+	if user.OTPCodeFailures+1 >= maxFailures {
+		user.OTPLockedUntil = &lockUntil
+		user.OTPCodeFailures = 0
+	} else {
+		user.OTPCodeFailures++
+	}
+	return nil
+}
+
+func (r *users) AdvanceTOTPStep(id uint, step int64) (bool, error) {
+	r.s.mu.Lock()
+	defer r.s.mu.Unlock()
+
+	user, err := r.byID(id)
+	if err != nil || user.TOTPLastStep >= step {
+		return false, nil
+	}
+	user.TOTPLastStep = step
+	return true, nil
+}
+
+func (r *users) Search(filter repository.UserFilter, page utils.Page) ([]models.User, int64, error) {
+	return nil, 0, ErrUnsupported
+}
@@ -0,0 +1,133 @@
+package repository
+
+import (
+	"time"
+
+	"cnep-backend/pkg/consts"
+	"cnep-backend/source/models"
+
+	"gorm.io/gorm"
+)
+
+// SessionRepository stores sessions and the refresh tokens issued for them.
+type SessionRepository interface {
+	Create(session *models.Session) error
+	Get(id uint) (*models.Session, error)
+	// GetActive returns the session if it belongs to the user and is not revoked.
+	GetActive(userID, id uint) (*models.Session, error)
+	// GetWithUser returns the session of the user together with the suspension state of the user.
+	GetWithUser(userID, id uint) (*SessionWithUser, error)
+	// ListActive returns the sessions of the user that are not revoked, most recently seen first.
+	ListActive(userID uint) ([]models.Session, error)
+	CountActive(userID uint) (int64, error)
+	Update(id uint, fields map[string]interface{}) error
+	// Revoke marks the session as revoked, which invalidates every refresh token in its family.
+	Revoke(id uint) error
+	// RevokeOthers revokes every active session of the user except exceptID and returns how many were revoked.
+	// Passing 0 revokes all sessions.
+	RevokeOthers(userID, exceptID uint) (int64, error)
+
+	CreateRefreshToken(token *models.RefreshToken) error
+	GetRefreshToken(hash string) (*models.RefreshToken, error)
+	// UseRefreshToken marks the token as used. It reports false when the token was already used,
+	// so only one request can ever exchange a token.
+	UseRefreshToken(id uint) (bool, error)
+}
+
+// SessionWithUser is a session with the suspension state of its user.
+type SessionWithUser struct {
+	models.Session
+	SuspendedAt    *time.Time
+	SuspendedUntil *time.Time
+}
+
+type sessionRepository struct {
+	db *gorm.DB
+}
+
+func (r *sessionRepository) Create(session *models.Session) error {
+	return r.db.Table(consts.SESSIONS_TABLE).Create(session).Error
+}
+
+func (r *sessionRepository) Get(id uint) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.Table(consts.SESSIONS_TABLE).First(&session, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) GetActive(userID, id uint) (*models.Session, error) {
+	var session models.Session
+	if err := r.db.Table(consts.SESSIONS_TABLE).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		First(&session).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) GetWithUser(userID, id uint) (*SessionWithUser, error) {
+	var session SessionWithUser
+	if err := r.db.Table(consts.SESSIONS_TABLE).
+		Select("sessions.*, users.suspended_at, users.suspended_until").
+		Joins("JOIN users ON users.id = sessions.user_id").
+		Where("sessions.id = ? AND sessions.user_id = ?", id, userID).
+		Take(&session).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &session, nil
+}
+
+func (r *sessionRepository) ListActive(userID uint) ([]models.Session, error) {
+	var sessions []models.Session
+	err := r.db.Table(consts.SESSIONS_TABLE).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Order("last_seen_at DESC").
+		Find(&sessions).Error
+	return sessions, err
+}
+
+func (r *sessionRepository) CountActive(userID uint) (int64, error) {
+	var count int64
+	err := r.db.Table(consts.SESSIONS_TABLE).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Count(&count).Error
+	return count, err
+}
+
+func (r *sessionRepository) Update(id uint, fields map[string]interface{}) error {
+	return affected(r.db.Table(consts.SESSIONS_TABLE).Where("id = ?", id).Updates(fields))
+}
+
+func (r *sessionRepository) Revoke(id uint) error {
+	return r.db.Table(consts.SESSIONS_TABLE).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now()).Error
+}
+
+func (r *sessionRepository) RevokeOthers(userID, exceptID uint) (int64, error) {
+	result := r.db.Table(consts.SESSIONS_TABLE).
+		Where("user_id = ? AND id <> ? AND revoked_at IS NULL", userID, exceptID).
+		Update("revoked_at", time.Now())
+	return result.RowsAffected, result.Error
+}
+
+func (r *sessionRepository) CreateRefreshToken(token *models.RefreshToken) error {
+	return translate(r.db.Table(consts.REFRESH_TOKENS_TABLE).Create(token).Error)
+}
+
+func (r *sessionRepository) GetRefreshToken(hash string) (*models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := r.db.Table(consts.REFRESH_TOKENS_TABLE).Where("token_hash = ?", hash).First(&token).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &token, nil
+}
+
+func (r *sessionRepository) UseRefreshToken(id uint) (bool, error) {
+	result := r.db.Table(consts.REFRESH_TOKENS_TABLE).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", time.Now())
+	return result.RowsAffected == 1, result.Error
+}
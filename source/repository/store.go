@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"errors"
+
+	"cnep-backend/pkg/utils"
+
+	"gorm.io/gorm"
+)
+
+var (
+	// ErrNotFound is returned when the record looked up or changed does not exist.
+	ErrNotFound = errors.New("record not found")
+	// ErrDuplicate is returned when a write violates a unique constraint.
+	ErrDuplicate = errors.New("duplicate record")
+)
+
+// Store gives access to every repository. Services depend on this interface only,
+// so they can be built on the database or on fakes.
+type Store interface {
+	Users() UserRepository
+	Sessions() SessionRepository
+	Codes() CodeRepository
+	RecoveryCodes() RecoveryCodeRepository
+	Identities() IdentityRepository
+	Partners() PartnerRepository
+	Blocks() BlockRepository
+	Feedback() FeedbackRepository
+	Moderation() ModerationRepository
+	Audit() AuditRepository
+	Messages() MessageRepository
+	Businesses() BusinessRepository
+	Topics() TopicRepository
+
+	// Transaction runs fn with a store whose repositories all use the same transaction.
+	// The transaction is committed when fn returns nil and rolled back otherwise.
+	Transaction(fn func(tx Store) error) error
+}
+
+type store struct {
+	db *gorm.DB
+}
+
+// NewStore returns a Store backed by the given database connection.
+func NewStore(db *gorm.DB) Store {
+	return &store{db: db}
+}
+
+func (s *store) Users() UserRepository                 { return &userRepository{db: s.db} }
+func (s *store) Sessions() SessionRepository           { return &sessionRepository{db: s.db} }
+func (s *store) Codes() CodeRepository                 { return &codeRepository{db: s.db} }
+func (s *store) RecoveryCodes() RecoveryCodeRepository { return &recoveryCodeRepository{db: s.db} }
+func (s *store) Identities() IdentityRepository        { return &identityRepository{db: s.db} }
+func (s *store) Partners() PartnerRepository           { return &partnerRepository{db: s.db} }
+func (s *store) Blocks() BlockRepository               { return &blockRepository{db: s.db} }
+func (s *store) Feedback() FeedbackRepository          { return &feedbackRepository{db: s.db} }
+func (s *store) Moderation() ModerationRepository      { return &moderationRepository{db: s.db} }
+func (s *store) Audit() AuditRepository                { return &auditRepository{db: s.db} }
+func (s *store) Messages() MessageRepository           { return &messageRepository{db: s.db} }
+func (s *store) Businesses() BusinessRepository        { return &businessRepository{db: s.db} }
+func (s *store) Topics() TopicRepository               { return &topicRepository{db: s.db} }
+
+func (s *store) Transaction(fn func(tx Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&store{db: tx})
+	})
+}
+
+// translate turns the gorm and driver errors services care about into ErrNotFound and ErrDuplicate.
+// Other errors are returned unchanged.
+func translate(err error) error {
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return ErrNotFound
+	case utils.IsDuplicateEntryError(err):
+		return ErrDuplicate
+	}
+	return err
+}
+
+// affected returns the error of a write, or ErrNotFound when it changed no rows.
+func affected(result *gorm.DB) error {
+	if result.Error != nil {
+		return translate(result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
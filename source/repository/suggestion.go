@@ -0,0 +1,92 @@
+package repository
+
+import (
+	"time"
+
+	"cnep-backend/pkg/consts"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/models"
+)
+
+// SuggestionScoring holds the weights of the signals a partner suggestion is scored on.
+type SuggestionScoring struct {
+	MutualWeight float64
+	TopicWeight  float64
+	HelpWeight   float64
+	NearbyWeight float64
+	// Users further away than this are not scored for being nearby
+	RadiusKm float64
+}
+
+const partnerSuggestionsQuery = `
+WITH me AS (
+	SELECT topics, latitude, longitude FROM users WHERE id = @user
+), my_partners AS (
+	SELECT CASE WHEN sender_id = @user THEN receiver_id ELSE sender_id END AS id
+	FROM partners
+	WHERE status = @accepted AND (sender_id = @user OR receiver_id = @user)
+), mutual AS (
+	SELECT CASE WHEN p.sender_id = mp.id THEN p.receiver_id ELSE p.sender_id END AS id, COUNT(*) AS n
+	FROM partners p
+	JOIN my_partners mp ON mp.id IN (p.sender_id, p.receiver_id)
+	WHERE p.status = @accepted
+	GROUP BY 1
+), helped AS (
+	SELECT CASE WHEN sender_id = @user THEN receiver_id ELSE sender_id END AS id, COUNT(*) AS n
+	FROM helps
+	WHERE sender_id = @user OR receiver_id = @user
+	GROUP BY 1
+), candidates AS (
+	SELECT u.id, u.name, u.username, u.avatar, u.avatar_variants, u.designation,
+		COALESCE(m.n, 0) AS mutual_partners,
+		COALESCE(h.n, 0) AS helps,
+		CARDINALITY(ARRAY(SELECT UNNEST(u.topics) INTERSECT SELECT UNNEST(me.topics))) AS shared_topics,
+		CASE WHEN u.latitude IS NOT NULL AND u.longitude IS NOT NULL AND me.latitude IS NOT NULL AND me.longitude IS NOT NULL
+			-- Rounding can take the root just past 1 for antipodal users, out of the domain of ASIN
+			THEN 6371 * 2 * ASIN(LEAST(1, SQRT(
+				POWER(SIN(RADIANS(u.latitude - me.latitude) / 2), 2) +
+				COS(RADIANS(me.latitude)) * COS(RADIANS(u.latitude)) * POWER(SIN(RADIANS(u.longitude - me.longitude) / 2), 2))))
+		END AS distance_km
+	FROM users u
+	CROSS JOIN me
+	LEFT JOIN mutual m ON m.id = u.id
+	LEFT JOIN helped h ON h.id = u.id
+	WHERE u.id <> @user
+		AND u.is_verified
+		AND (u.suspended_at IS NULL OR u.suspended_until <= @now)
+		AND NOT EXISTS (
+			SELECT 1 FROM partners p
+			WHERE (p.sender_id = @user AND p.receiver_id = u.id) OR (p.sender_id = u.id AND p.receiver_id = @user))
+), scored AS (
+	SELECT *,
+		mutual_partners * @mutual_weight + shared_topics * @topic_weight + helps * @help_weight +
+		CASE WHEN distance_km < @radius THEN @nearby_weight * (1 - distance_km / @radius) ELSE 0 END AS score
+	FROM candidates
+)
+SELECT *
+FROM scored
+WHERE score > 0`
+
+func (r *partnerRepository) Suggestions(userID uint, scoring SuggestionScoring, page utils.Page) ([]models.PartnerSuggestion, error) {
+	var suggestions []models.PartnerSuggestion
+
+	scored := r.db.Raw(partnerSuggestionsQuery, map[string]interface{}{
+		"user":          userID,
+		"accepted":      consts.PARTNER_STATUS_ACCEPTED,
+		"now":           time.Now(),
+		"mutual_weight": scoring.MutualWeight,
+		"topic_weight":  scoring.TopicWeight,
+		"help_weight":   scoring.HelpWeight,
+		"nearby_weight": scoring.NearbyWeight,
+		"radius":        scoring.RadiusKm,
+	})
+
+	// The total is counted in the subquery, after blocked users but before the cursor leaves out rows
+	counted := r.db.Table("(?) AS ranked", scored).
+		Select("*, COUNT(*) OVER () AS total").
+		Scopes(NotBlocked(userID, "ranked.id"))
+	if err := scanPage(r.db.Table("(?) AS suggestions", counted), page, &suggestions); err != nil {
+		return nil, err
+	}
+	return suggestions, nil
+}
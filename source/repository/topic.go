@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"slices"
+
+	"cnep-backend/pkg/consts"
+	"cnep-backend/source/models"
+
+	"gorm.io/gorm"
+)
+
+// TopicRepository stores the topics users and business pages pick their interests from.
+type TopicRepository interface {
+	Create(topic *models.Topic) error
+	// Exist reports whether every given topic exists. Repeated ids are counted once.
+	Exist(ids []int64) (bool, error)
+}
+
+type topicRepository struct {
+	db *gorm.DB
+}
+
+func (r *topicRepository) Create(topic *models.Topic) error {
+	return translate(r.db.Table(consts.TOPICS_TABLE).Create(topic).Error)
+}
+
+func (r *topicRepository) Exist(ids []int64) (bool, error) {
+	ids = slices.Compact(slices.Sorted(slices.Values(ids)))
+	if len(ids) == 0 {
+		return true, nil
+	}
+
+	var count int64
+	err := r.db.Table(consts.TOPICS_TABLE).Where("id IN ?", ids).Count(&count).Error
+	return count == int64(len(ids)), err
+}
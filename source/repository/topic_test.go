@@ -0,0 +1,25 @@
+package repository
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTopicsExistCountsRepeatedIDsOnce(t *testing.T) {
+	db, recorder := dryRun(t)
+	repo := &topicRepository{db: db}
+
+	repo.Exist([]int64{3, 1, 3, 2, 1})
+	if sql := recorder.last(t); !strings.Contains(sql, "id IN (1,2,3)") {
+		t.Fatalf("expected the distinct ids to be counted in %s", sql)
+	}
+
+	// No topics need no query
+	before := len(recorder.statements)
+	if exist, err := repo.Exist(nil); !exist || err != nil {
+		t.Fatalf("expected no topics to exist, got %v, %v", exist, err)
+	}
+	if len(recorder.statements) != before {
+		t.Fatal("expected no statement for an empty list")
+	}
+}
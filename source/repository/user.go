@@ -0,0 +1,202 @@
+package repository
+
+import (
+	"time"
+
+	"cnep-backend/pkg/consts"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/models"
+
+	"gorm.io/gorm"
+)
+
+// UserRepository stores users, their profile and their sign-in state.
+type UserRepository interface {
+	Get(id uint) (*models.User, error)
+	GetByEmail(email string) (*models.User, error)
+	// GetByEmailFold matches the email case-insensitively.
+	GetByEmailFold(email string) (*models.User, error)
+	Exists(id uint) (bool, error)
+	EmailExists(email string) (bool, error)
+	Create(user *models.User) error
+	// Update changes the given columns of the user. It returns ErrNotFound if there is no such user.
+	Update(id uint, fields map[string]interface{}) error
+	Role(id uint) (string, error)
+	Profile(id uint) (*models.UserResponse, error)
+	Privacy(id uint) (*models.PrivacySettings, error)
+
+	// ClaimOTPSend counts an OTP email sent at the given time, starting a new daily window when the
+	// last one is over. It reports false, counting nothing, when the last email was sent less than
+	// cooldown ago or dailyLimit emails were already sent in the window. Check and count are a single
+	// statement, so concurrent requests cannot all pass the check.
+	ClaimOTPSend(id uint, at time.Time, cooldown time.Duration, dailyLimit int) (bool, error)
+	// RecordTwoFactorFailure counts a wrong two-factor code and locks the user until lockUntil once maxAttempts is reached.
+	// The counter is updated in a single statement so concurrent guesses cannot slip past the limit.
+	RecordTwoFactorFailure(id uint, maxAttempts int, lockUntil time.Time) error
+	// RecordOTPFailure counts a wrong emailed code and locks the codes of the user until lockUntil
+	// once maxFailures is reached. The counter is kept on the user, so a resent code does not reset it.
+	RecordOTPFailure(id uint, maxFailures int, lockUntil time.Time) error
+	// AdvanceTOTPStep records step as the last TOTP time step used. It reports false
+	// when the step is not later than the last one, so a TOTP code cannot be replayed.
+	AdvanceTOTPStep(id uint, step int64) (bool, error)
+
+	// Search returns a page of the users matching the filter and the total count of matches.
+	Search(filter UserFilter, page utils.Page) ([]models.User, int64, error)
+}
+
+// UserFilter narrows the admin user search. Empty fields do not filter.
+type UserFilter struct {
+	// Query matches the name, username or email.
+	Query string
+	Role  string
+	// Status is one of verified, unverified, locked, suspended or banned.
+	Status string
+}
+
+type userRepository struct {
+	db *gorm.DB
+}
+
+func (r *userRepository) Get(id uint) (*models.User, error) {
+	var user models.User
+	if err := r.db.Table(consts.USERS_TABLE).Where("id = ?", id).First(&user).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) GetByEmail(email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Table(consts.USERS_TABLE).Where("email = ?", email).First(&user).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) GetByEmailFold(email string) (*models.User, error) {
+	var user models.User
+	if err := r.db.Table(consts.USERS_TABLE).Where("LOWER(email) = LOWER(?)", email).First(&user).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) Exists(id uint) (bool, error) {
+	var count int64
+	err := r.db.Table(consts.USERS_TABLE).Where("id = ?", id).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *userRepository) EmailExists(email string) (bool, error) {
+	var count int64
+	err := r.db.Table(consts.USERS_TABLE).Where("email = ?", email).Count(&count).Error
+	return count > 0, err
+}
+
+func (r *userRepository) Create(user *models.User) error {
+	return translate(r.db.Table(consts.USERS_TABLE).Create(user).Error)
+}
+
+func (r *userRepository) Update(id uint, fields map[string]interface{}) error {
+	// The model lets gorm keep updated_at current
+	return affected(r.db.Model(&models.User{}).Where("id = ?", id).Updates(fields))
+}
+
+func (r *userRepository) Role(id uint) (string, error) {
+	var user models.User
+	if err := r.db.Table(consts.USERS_TABLE).Select("role").Where("id = ?", id).Take(&user).Error; err != nil {
+		return "", translate(err)
+	}
+	return user.Role, nil
+}
+
+func (r *userRepository) Profile(id uint) (*models.UserResponse, error) {
+	var user models.UserResponse
+	if err := r.db.Table(consts.USERS_TABLE).First(&user, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user, nil
+}
+
+func (r *userRepository) Privacy(id uint) (*models.PrivacySettings, error) {
+	var user models.User
+	if err := r.db.Table(consts.USERS_TABLE).
+		Select("id", "email_visibility", "phone_visibility", "address_visibility").
+		First(&user, id).Error; err != nil {
+		return nil, translate(err)
+	}
+	return &user.Privacy, nil
+}
+
+func (r *userRepository) ClaimOTPSend(id uint, at time.Time, cooldown time.Duration, dailyLimit int) (bool, error) {
+	windowOver := "(otp_send_window_start IS NULL OR otp_send_window_start <= ?)"
+	windowStart := at.Add(-24 * time.Hour)
+
+	result := r.db.Table(consts.USERS_TABLE).
+		Where("id = ?", id).
+		Where("(otp_last_sent_at IS NULL OR otp_last_sent_at <= ?)", at.Add(-cooldown)).
+		Where("("+windowOver+" OR otp_send_count < ?)", windowStart, dailyLimit).
+		Updates(map[string]interface{}{
+			"otp_last_sent_at":      at,
+			"otp_send_window_start": gorm.Expr("CASE WHEN "+windowOver+" THEN ? ELSE otp_send_window_start END", windowStart, at),
+			"otp_send_count":        gorm.Expr("CASE WHEN "+windowOver+" THEN 1 ELSE otp_send_count + 1 END", windowStart),
+		})
+	return result.RowsAffected == 1, translate(result.Error)
+}
+
+func (r *userRepository) RecordTwoFactorFailure(id uint, maxAttempts int, lockUntil time.Time) error {
+	return translate(r.db.Table(consts.USERS_TABLE).Where("id = ?", id).Updates(map[string]interface{}{
+		"locked_until":        gorm.Expr("CASE WHEN otp_failed_attempts + 1 >= ? THEN ? ELSE locked_until END", maxAttempts, lockUntil),
+		"otp_failed_attempts": gorm.Expr("CASE WHEN otp_failed_attempts + 1 >= ? THEN 0 ELSE otp_failed_attempts + 1 END", maxAttempts),
+	}).Error)
+}
+
+func (r *userRepository) RecordOTPFailure(id uint, maxFailures int, lockUntil time.Time) error {
+	return translate(r.db.Table(consts.USERS_TABLE).Where("id = ?", id).Updates(map[string]interface{}{
+		"otp_locked_until":  gorm.Expr("CASE WHEN otp_code_failures + 1 >= ? THEN ? ELSE otp_locked_until END", maxFailures, lockUntil),
+		"otp_code_failures": gorm.Expr("CASE WHEN otp_code_failures + 1 >= ? THEN 0 ELSE otp_code_failures + 1 END", maxFailures),
+	}).Error)
+}
+
+func (r *userRepository) AdvanceTOTPStep(id uint, step int64) (bool, error) {
+	result := r.db.Table(consts.USERS_TABLE).
+		Where("id = ? AND totp_last_step < ?", id, step).
+		Update("totp_last_step", step)
+	return result.RowsAffected == 1, result.Error
+}
+
+func (r *userRepository) Search(filter UserFilter, page utils.Page) ([]models.User, int64, error) {
+	var users []models.User
+	var total int64
+
+	query := r.db.Table(consts.USERS_TABLE)
+	if filter.Query != "" {
+		like := "%" + utils.EscapeLike(filter.Query) + "%"
+		query = query.Where("(name ILIKE ? OR username ILIKE ? OR email ILIKE ?)", like, like, like)
+	}
+	if filter.Role != "" {
+		query = query.Where("role = ?", filter.Role)
+	}
+
+	now := time.Now()
+	switch filter.Status {
+	case "verified":
+		query = query.Where("is_verified = ?", true)
+	case "unverified":
+		query = query.Where("is_verified = ?", false)
+	case "locked":
+		query = query.Where("locked_until > ?", now)
+	case "suspended":
+		query = query.Where("suspended_at IS NOT NULL AND suspended_until > ?", now)
+	case "banned":
+		query = query.Where("suspended_at IS NOT NULL AND suspended_until IS NULL")
+	}
+
+	if err := query.Count(&total).Error; err != nil {
+		return nil, 0, err
+	}
+	if err := findPage(query, page, &users); err != nil {
+		return nil, 0, err
+	}
+	return users, total, nil
+}
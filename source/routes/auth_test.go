@@ -0,0 +1,145 @@
+package routes_test
+
+import (
+	"net/http"
+	"testing"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/source/apitest"
+	"cnep-backend/source/services"
+)
+
+func TestEmailExistence(t *testing.T) {
+	s := apitest.New(t)
+
+	var result struct {
+		Exists bool `json:"exists"`
+	}
+	s.Get("/api/auth/users?email=user1@example.com", "").Expect(http.StatusOK).Decode(&result)
+	if result.Exists {
+		t.Fatal("expected an unknown email not to exist")
+	}
+
+	s.CreateUser()
+	s.Get("/api/auth/users?email=user1@example.com", "").Expect(http.StatusOK).Decode(&result)
+	if !result.Exists {
+		t.Fatal("expected the email of the signed up user to exist")
+	}
+
+	s.Get("/api/auth/users?email=not-an-email", "").Expect(http.StatusBadRequest)
+}
+
+func TestSignUpAndLogin(t *testing.T) {
+	s := apitest.New(t)
+	signup := map[string]interface{}{"email": "new@example.com", "password": apitest.Password, "is_new": true}
+	login := map[string]interface{}{"email": "new@example.com", "password": apitest.Password}
+
+	s.Post("/api/auth/continue", "", signup).Expect(http.StatusCreated)
+	s.Post("/api/auth/continue", "", signup).Expect(http.StatusConflict)
+
+	// The account cannot be used before the email is verified
+	if code := s.Post("/api/auth/continue", "", login).Expect(http.StatusUnauthorized).ErrorCode(); code != apierror.CodeEmailNotVerified {
+		t.Fatalf("expected code %s, got %s", apierror.CodeEmailNotVerified, code)
+	}
+
+	var verified services.LoginResult
+	s.Post("/api/otp/verify", "", map[string]interface{}{
+		"email": "new@example.com",
+		"otp":   s.Mail.OTP(t, "new@example.com"),
+	}).Expect(http.StatusOK).Decode(&verified)
+	if verified.Token == "" || verified.RefreshToken == "" {
+		t.Fatalf("expected the verification to start a session, got %+v", verified)
+	}
+
+	var result services.LoginResult
+	s.Post("/api/auth/continue", "", login).Expect(http.StatusOK).Decode(&result)
+	if result.Token == "" || result.User == nil || result.User.Email != "new@example.com" {
+		t.Fatalf("expected a session for new@example.com, got %+v", result)
+	}
+
+	s.Post("/api/auth/continue", "", map[string]interface{}{
+		"email":    "new@example.com",
+		"password": "Wrong@1234",
+	}).Expect(http.StatusUnauthorized)
+}
+
+func TestSignUpGivesUniqueUsernames(t *testing.T) {
+	s := apitest.New(t)
+
+	usernames := make(map[string]bool)
+	for i := 0; i < 3; i++ {
+		user := s.CreateUser()
+
+		var profile struct {
+			Username string `json:"username"`
+		}
+		s.Get("/api/users/profile", user.Token).Expect(http.StatusOK).Decode(&profile)
+		if profile.Username == "" || usernames[profile.Username] {
+			t.Fatalf("expected a new unique username, got %q", profile.Username)
+		}
+		usernames[profile.Username] = true
+	}
+}
+
+func TestProtectedRoutesNeedToken(t *testing.T) {
+	s := apitest.New(t)
+
+	s.Get("/api/users/profile", "").Expect(http.StatusUnauthorized)
+	s.Get("/api/users/profile", "not-a-token").Expect(http.StatusUnauthorized)
+}
+
+func TestRefreshAndLogout(t *testing.T) {
+	s := apitest.New(t)
+	user := s.CreateUser()
+
+	var pair services.TokenPair
+	s.Post("/api/auth/refresh", "", map[string]interface{}{"refresh_token": user.RefreshToken}).
+		Expect(http.StatusOK).Decode(&pair)
+	if pair.AccessToken == "" || pair.RefreshToken == "" || pair.RefreshToken == user.RefreshToken {
+		t.Fatalf("expected a new token pair, got %+v", pair)
+	}
+	s.Get("/api/users/profile", pair.AccessToken).Expect(http.StatusOK)
+
+	// Using a refresh token twice revokes the whole session
+	s.Post("/api/auth/refresh", "", map[string]interface{}{"refresh_token": user.RefreshToken}).
+		Expect(http.StatusUnauthorized)
+	s.Post("/api/auth/refresh", "", map[string]interface{}{"refresh_token": pair.RefreshToken}).
+		Expect(http.StatusUnauthorized)
+	s.Get("/api/users/profile", pair.AccessToken).Expect(http.StatusUnauthorized)
+
+	session := s.Login(user)
+	s.Post("/api/auth/logout", "", map[string]interface{}{"refresh_token": session.RefreshToken}).
+		Expect(http.StatusOK)
+	s.Get("/api/users/profile", session.Token).Expect(http.StatusUnauthorized)
+	s.Post("/api/auth/refresh", "", map[string]interface{}{"refresh_token": session.RefreshToken}).
+		Expect(http.StatusUnauthorized)
+}
+
+func TestChangePassword(t *testing.T) {
+	s := apitest.New(t)
+	user := s.CreateUser()
+	other := s.Login(user)
+
+	s.Post("/api/users/password/change", user.Token, map[string]interface{}{
+		"old_password": "Wrong@1234",
+		"new_password": "Changed@123",
+	}).Expect(http.StatusUnauthorized)
+
+	s.Post("/api/users/password/change", user.Token, map[string]interface{}{
+		"old_password":          apitest.Password,
+		"new_password":          "Changed@123",
+		"logout_other_sessions": true,
+	}).Expect(http.StatusOK)
+
+	// The session changing the password stays, the others are signed out
+	s.Get("/api/users/profile", user.Token).Expect(http.StatusOK)
+	s.Get("/api/users/profile", other.Token).Expect(http.StatusUnauthorized)
+
+	s.Post("/api/auth/continue", "", map[string]interface{}{
+		"email":    user.Email,
+		"password": apitest.Password,
+	}).Expect(http.StatusUnauthorized)
+
+	user.Password = "Changed@123"
+	s.Login(user)
+}
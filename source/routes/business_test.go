@@ -0,0 +1,30 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"cnep-backend/pkg/consts"
+	"cnep-backend/source/apitest"
+	"cnep-backend/source/models"
+)
+
+func TestHiddenBusinessPageIsGone(t *testing.T) {
+	s := apitest.New(t)
+	owner := s.CreateUser()
+
+	page := &models.BusinessPage{OwnerID: owner.ID, Name: "Bakery"}
+	if err := s.Store.Businesses().CreatePage(page); err != nil {
+		t.Fatal(err)
+	}
+	logo := fmt.Sprintf("/api/businesses/%d/logo", page.ID)
+
+	// The page is found, the file is refused for not being an image
+	s.Send(uploadRequest(t, logo, owner.Token, "", 10)).Expect(http.StatusUnsupportedMediaType)
+
+	if err := s.Store.Moderation().Hide(consts.REPORT_TARGET_BUSINESS, page.ID); err != nil {
+		t.Fatal(err)
+	}
+	s.Send(uploadRequest(t, logo, owner.Token, "", 10)).Expect(http.StatusNotFound)
+}
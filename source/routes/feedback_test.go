@@ -0,0 +1,115 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"cnep-backend/source/apitest"
+	"cnep-backend/source/models"
+)
+
+// feedbackPage returns the feedback of a feedback list endpoint.
+func feedbackPage(t *testing.T, s *apitest.Server, path string, user *apitest.User) []models.FeedbackWithSender {
+	t.Helper()
+
+	var page struct {
+		Feedbacks []models.FeedbackWithSender `json:"feedbacks"`
+	}
+	s.Get(path, user.Token).Expect(http.StatusOK).Decode(&page)
+	return page.Feedbacks
+}
+
+func TestFeedback(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser()
+	bob := s.CreateUser()
+
+	var created struct {
+		Feedback models.Feedback `json:"feedback"`
+	}
+	s.Post("/api/users/feedback", alice.Token, map[string]interface{}{
+		"user_id": bob.ID,
+		"content": "Helped me move house",
+		"rating":  4,
+	}).Expect(http.StatusCreated).Decode(&created)
+	if created.Feedback.SenderID != alice.ID || created.Feedback.ReceiverID != bob.ID || created.Feedback.Rating != 4 {
+		t.Fatalf("unexpected feedback %+v", created.Feedback)
+	}
+
+	// The receiver lists their own feedback, anyone else lists it by user ID
+	for _, feedbacks := range [][]models.FeedbackWithSender{
+		feedbackPage(t, s, "/api/users/feedback", bob),
+		feedbackPage(t, s, fmt.Sprintf("/api/users/feedback/%d", bob.ID), alice),
+	} {
+		if len(feedbacks) != 1 {
+			t.Fatalf("expected one feedback, got %+v", feedbacks)
+		}
+		if feedbacks[0].Content != "Helped me move house" || feedbacks[0].Sender.ID != alice.ID {
+			t.Fatalf("unexpected feedback %+v", feedbacks[0])
+		}
+	}
+
+	// The sender is not a partner, so their email stays hidden from the receiver
+	if email := feedbackPage(t, s, "/api/users/feedback", bob)[0].Sender.Email; email != "" {
+		t.Fatalf("expected the sender email to be hidden, got %q", email)
+	}
+
+	if feedbacks := feedbackPage(t, s, "/api/users/feedback", alice); len(feedbacks) != 0 {
+		t.Fatalf("expected the sender to have received no feedback, got %+v", feedbacks)
+	}
+	if feedbacks := feedbackPage(t, s, "/api/users/feedback?rating=5", bob); len(feedbacks) != 0 {
+		t.Fatalf("expected no feedback rated 5, got %+v", feedbacks)
+	}
+	if feedbacks := feedbackPage(t, s, "/api/users/feedback?rating=4", bob); len(feedbacks) != 1 {
+		t.Fatalf("expected one feedback rated 4, got %+v", feedbacks)
+	}
+	s.Get("/api/users/feedback?rating=9", bob.Token).Expect(http.StatusBadRequest)
+}
+
+func TestFeedbackValidation(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser()
+	bob := s.CreateUser()
+
+	for _, body := range []map[string]interface{}{
+		{"user_id": bob.ID, "content": "", "rating": 4},
+		{"user_id": bob.ID, "content": "Great", "rating": 0},
+		{"user_id": bob.ID, "content": "Great", "rating": 6},
+		{"user_id": alice.ID, "content": "Great", "rating": 5},
+	} {
+		s.Post("/api/users/feedback", alice.Token, body).Expect(http.StatusBadRequest)
+	}
+
+	s.Get("/api/users/feedback/abc", alice.Token).Expect(http.StatusBadRequest)
+	if feedbacks := feedbackPage(t, s, "/api/users/feedback", bob); len(feedbacks) != 0 {
+		t.Fatalf("expected no feedback to be stored, got %+v", feedbacks)
+	}
+}
+
+func TestFeedbackFromBlockedUsers(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser()
+	bob := s.CreateUser()
+	carol := s.CreateUser()
+
+	s.Post("/api/users/feedback", alice.Token, map[string]interface{}{
+		"user_id": bob.ID,
+		"content": "Helped me move house",
+		"rating":  4,
+	}).Expect(http.StatusCreated)
+
+	// Bob blocks alice: her feedback leaves his list, but other users still see it
+	s.Post("/api/users/blocks", bob.Token, map[string]interface{}{"user_id": alice.ID}).Expect(http.StatusOK)
+	if feedbacks := feedbackPage(t, s, "/api/users/feedback", bob); len(feedbacks) != 0 {
+		t.Fatalf("expected the feedback of a blocked user to be left out, got %+v", feedbacks)
+	}
+	if feedbacks := feedbackPage(t, s, fmt.Sprintf("/api/users/feedback/%d", bob.ID), carol); len(feedbacks) != 1 {
+		t.Fatalf("expected other users to see the feedback, got %+v", feedbacks)
+	}
+
+	s.Delete(fmt.Sprintf("/api/users/blocks/%d", alice.ID), bob.Token).Expect(http.StatusOK)
+	if feedbacks := feedbackPage(t, s, "/api/users/feedback", bob); len(feedbacks) != 1 {
+		t.Fatalf("expected the feedback back after the unblock, got %+v", feedbacks)
+	}
+}
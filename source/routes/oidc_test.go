@@ -0,0 +1,92 @@
+package routes_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"cnep-backend/pkg/lib"
+	"cnep-backend/source/apitest"
+	"cnep-backend/source/services"
+)
+
+// fakeProvider is a login provider vouching for the same verified identity on every exchange.
+type fakeProvider struct{}
+
+func (fakeProvider) Name() string {
+	return "fake"
+}
+
+func (fakeProvider) AuthCodeURL(state, nonce, codeChallenge string) (string, error) {
+	return "https://idp.example.com/authorize?" + url.Values{"state": {state}}.Encode(), nil
+}
+
+func (fakeProvider) Exchange(ctx context.Context, code, codeVerifier, nonce string) (*lib.ExternalIdentity, error) {
+	return &lib.ExternalIdentity{Provider: "fake", Subject: "subject-1", Email: "oidc@example.com", EmailVerified: true}, nil
+}
+
+// startOIDCLogin starts a login with the fake provider and returns its state and state cookie.
+func startOIDCLogin(t *testing.T, s *apitest.Server) (string, *http.Cookie) {
+	t.Helper()
+
+	var login services.OIDCLogin
+	res := s.Get("/api/auth/oidc/fake", "").Expect(http.StatusOK).Decode(&login)
+	for _, cookie := range res.Cookies {
+		if cookie.Name == "oidc_state" {
+			if cookie.Value != login.State || !cookie.HttpOnly || cookie.SameSite != http.SameSiteLaxMode {
+				t.Fatalf("expected an HttpOnly SameSite Lax cookie with the state, got %+v", cookie)
+			}
+			return login.State, cookie
+		}
+	}
+	t.Fatalf("expected a state cookie, got %+v", res.Cookies)
+	return "", nil
+}
+
+// oidcCallback completes a login, as a redirect from the provider or as a JSON body forwarded by a frontend.
+func oidcCallback(s *apitest.Server, method, state string, cookie *http.Cookie) *apitest.Response {
+	path := "/api/auth/oidc/fake/callback"
+	var req *http.Request
+	if method == http.MethodGet {
+		req = httptest.NewRequest(method, path+"?"+url.Values{"code": {"code"}, "state": {state}}.Encode(), nil)
+	} else {
+		body, _ := json.Marshal(map[string]string{"code": "code", "state": state})
+		req = httptest.NewRequest(method, path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if cookie != nil {
+		req.AddCookie(&http.Cookie{Name: cookie.Name, Value: cookie.Value})
+	}
+	return s.Send(req)
+}
+
+func TestOIDCStateIsBoundToTheBrowser(t *testing.T) {
+	s := apitest.New(t)
+	lib.RegisterProvider(fakeProvider{})
+
+	state, cookie := startOIDCLogin(t, s)
+	otherState, otherCookie := startOIDCLogin(t, s)
+
+	// A state without its cookie, or with the cookie of another login, is refused
+	oidcCallback(s, http.MethodGet, state, nil).Expect(http.StatusBadRequest)
+	oidcCallback(s, http.MethodGet, state, otherCookie).Expect(http.StatusBadRequest)
+
+	var result services.LoginResult
+	res := oidcCallback(s, http.MethodGet, state, cookie).Expect(http.StatusOK).Decode(&result)
+	if result.Token == "" || result.User == nil || result.User.Email != "oidc@example.com" {
+		t.Fatalf("expected a session for the provider account, got %+v", result)
+	}
+	if len(res.Cookies) != 1 || res.Cookies[0].Name != "oidc_state" || res.Cookies[0].Expires.After(time.Now()) {
+		t.Fatalf("expected the state cookie to be cleared, got %+v", res.Cookies)
+	}
+
+	// The state is used up, even with its cookie
+	oidcCallback(s, http.MethodGet, state, cookie).Expect(http.StatusBadRequest)
+
+	oidcCallback(s, http.MethodPost, otherState, otherCookie).Expect(http.StatusOK)
+}
@@ -0,0 +1,115 @@
+package routes_test
+
+import (
+	"net/http"
+	"testing"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/source/apitest"
+)
+
+// signUp registers the email without verifying it and returns the code of the signup email.
+func signUp(t *testing.T, s *apitest.Server, email string) string {
+	t.Helper()
+
+	s.Post("/api/auth/continue", "", map[string]interface{}{
+		"email":    email,
+		"password": apitest.Password,
+		"is_new":   true,
+	}).Expect(http.StatusCreated)
+
+	return s.Mail.OTP(t, email)
+}
+
+func TestVerifyOTP(t *testing.T) {
+	s := apitest.New(t)
+	otp := signUp(t, s, "new@example.com")
+
+	s.Post("/api/otp/verify", "", map[string]interface{}{"email": "new@example.com", "otp": "short"}).
+		Expect(http.StatusBadRequest)
+	s.Post("/api/otp/verify", "", map[string]interface{}{"email": "new@example.com", "otp": "AAAA0000"}).
+		Expect(http.StatusBadRequest)
+	s.Post("/api/otp/verify", "", map[string]interface{}{"email": "other@example.com", "otp": otp}).
+		Expect(http.StatusBadRequest)
+
+	s.Post("/api/otp/verify", "", map[string]interface{}{"email": "new@example.com", "otp": otp}).
+		Expect(http.StatusOK)
+
+	// A code can only be used once
+	s.Post("/api/otp/verify", "", map[string]interface{}{"email": "new@example.com", "otp": otp}).
+		Expect(http.StatusBadRequest)
+}
+
+func TestVerifyOTPLimitsGuessesPerCode(t *testing.T) {
+	t.Setenv("OTP_MAX_ATTEMPTS", "2")
+
+	s := apitest.New(t)
+	otp := signUp(t, s, "new@example.com")
+
+	for i := 0; i < 2; i++ {
+		s.Post("/api/otp/verify", "", map[string]interface{}{"email": "new@example.com", "otp": "AAAA0000"}).
+			Expect(http.StatusBadRequest)
+	}
+
+	// The code took its guesses, even the right one is refused now
+	s.Post("/api/otp/verify", "", map[string]interface{}{"email": "new@example.com", "otp": otp}).
+		Expect(http.StatusBadRequest)
+
+	// A new code starts with its own guesses
+	t.Setenv("OTP_RESEND_COOLDOWN", "0")
+	s.Post("/api/otp/generate", "", map[string]interface{}{"email": "new@example.com"}).Expect(http.StatusOK)
+	s.Post("/api/otp/verify", "", map[string]interface{}{"email": "new@example.com", "otp": s.Mail.OTP(t, "new@example.com")}).
+		Expect(http.StatusOK)
+}
+
+func TestVerifyOTPCannotLockOthersOut(t *testing.T) {
+	t.Setenv("OTP_MAX_ATTEMPTS", "2")
+
+	s := apitest.New(t)
+	user := s.CreateUser()
+
+	// Guesses for verified and unknown emails get the same answer and are not counted anywhere
+	for _, email := range []string{user.Email, "unknown@example.com"} {
+		for i := 0; i < 5; i++ {
+			res := s.Post("/api/otp/verify", "", map[string]interface{}{"email": email, "otp": "AAAA0000"}).
+				Expect(http.StatusBadRequest)
+			if code := res.ErrorCode(); code != apierror.CodeBadRequest {
+				t.Fatalf("expected code %s, got %s", apierror.CodeBadRequest, code)
+			}
+		}
+	}
+
+	s.Login(user)
+}
+
+func TestRegenerateOTP(t *testing.T) {
+	s := apitest.New(t)
+	first := signUp(t, s, "new@example.com")
+
+	// The signup email was just sent, so the cooldown holds the new one back
+	s.Post("/api/otp/generate", "", map[string]interface{}{"email": "new@example.com"}).Expect(http.StatusOK)
+	if sent := len(s.Mail.To("new@example.com")); sent != 1 {
+		t.Fatalf("expected no email during the cooldown, got %d emails", sent)
+	}
+
+	t.Setenv("OTP_RESEND_COOLDOWN", "0")
+	s.Post("/api/otp/generate", "", map[string]interface{}{"email": "new@example.com"}).Expect(http.StatusOK)
+	if sent := len(s.Mail.To("new@example.com")); sent != 2 {
+		t.Fatalf("expected a new email, got %d emails", sent)
+	}
+	second := s.Mail.OTP(t, "new@example.com")
+
+	// The new code replaces the previous one
+	s.Post("/api/otp/verify", "", map[string]interface{}{"email": "new@example.com", "otp": first}).
+		Expect(http.StatusBadRequest)
+	s.Post("/api/otp/verify", "", map[string]interface{}{"email": "new@example.com", "otp": second}).
+		Expect(http.StatusOK)
+
+	// Unknown and verified emails get the same answer, without an email
+	s.Post("/api/otp/generate", "", map[string]interface{}{"email": "unknown@example.com"}).Expect(http.StatusOK)
+	s.Post("/api/otp/generate", "", map[string]interface{}{"email": "new@example.com"}).Expect(http.StatusOK)
+	if sent := len(s.Mail.To("unknown@example.com")) + len(s.Mail.To("new@example.com")); sent != 2 {
+		t.Fatalf("expected no more emails, got %d emails", sent)
+	}
+	s.Post("/api/otp/generate", "", map[string]interface{}{"email": "not-an-email"}).Expect(http.StatusBadRequest)
+}
@@ -0,0 +1,150 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"cnep-backend/pkg/consts"
+	"cnep-backend/source/apitest"
+	"cnep-backend/source/models"
+)
+
+type partnerStatus struct {
+	Relationship string `json:"relationship"`
+	CanRequest   bool   `json:"can_request"`
+	RetryAfter   int    `json:"retry_after"`
+}
+
+// expectRelationship fails the test unless the user sees the given relationship with the other user.
+func expectRelationship(t *testing.T, s *apitest.Server, user, other *apitest.User, relationship string) partnerStatus {
+	t.Helper()
+
+	var status partnerStatus
+	s.Get(fmt.Sprintf("/api/users/partner/status/%d", other.ID), user.Token).Expect(http.StatusOK).Decode(&status)
+	if status.Relationship != relationship {
+		t.Fatalf("expected user %d to see %s with user %d, got %s", user.ID, relationship, other.ID, status.Relationship)
+	}
+	return status
+}
+
+// partnerIDs returns the IDs of the users of a partner list endpoint.
+func partnerIDs(t *testing.T, s *apitest.Server, path string, user *apitest.User) []uint {
+	t.Helper()
+
+	var list struct {
+		Partners []models.PartnerUser `json:"partners"`
+	}
+	s.Get(path, user.Token).Expect(http.StatusOK).Decode(&list)
+
+	ids := make([]uint, 0, len(list.Partners))
+	for _, partner := range list.Partners {
+		ids = append(ids, partner.ID)
+	}
+	return ids
+}
+
+func expectIDs(t *testing.T, what string, got []uint, want ...uint) {
+	t.Helper()
+
+	if fmt.Sprint(got) != fmt.Sprint(want) {
+		t.Fatalf("expected %s %v, got %v", what, want, got)
+	}
+}
+
+func TestPartnerRequestFlow(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser()
+	bob := s.CreateUser()
+
+	expectRelationship(t, s, alice, bob, consts.PARTNER_RELATION_NONE)
+
+	s.Post("/api/users/partner", alice.Token, map[string]interface{}{"user_id": bob.ID}).Expect(http.StatusCreated)
+	s.Post("/api/users/partner", alice.Token, map[string]interface{}{"user_id": bob.ID}).Expect(http.StatusConflict)
+
+	expectRelationship(t, s, alice, bob, consts.PARTNER_RELATION_REQUEST_SENT)
+	expectRelationship(t, s, bob, alice, consts.PARTNER_RELATION_REQUEST_RECEIVED)
+	expectIDs(t, "outgoing requests", partnerIDs(t, s, "/api/users/partner/outgoing", alice), bob.ID)
+	expectIDs(t, "pending requests", partnerIDs(t, s, "/api/users/partner/pending", bob), alice.ID)
+
+	// Only the receiver can answer the request
+	s.Put(fmt.Sprintf("/api/users/partner/%d", bob.ID), alice.Token, map[string]interface{}{"accept": true}).
+		Expect(http.StatusNotFound)
+	s.Put(fmt.Sprintf("/api/users/partner/%d", alice.ID), bob.Token, map[string]interface{}{"accept": true}).
+		Expect(http.StatusOK)
+
+	expectRelationship(t, s, alice, bob, consts.PARTNER_RELATION_PARTNERS)
+	expectIDs(t, "partners", partnerIDs(t, s, "/api/users/partner", alice), bob.ID)
+	expectIDs(t, "partners", partnerIDs(t, s, "/api/users/partner", bob), alice.ID)
+	expectIDs(t, "pending requests", partnerIDs(t, s, "/api/users/partner/pending", bob))
+
+	// Partners see each other's email
+	var profile models.UserResponse
+	s.Get(fmt.Sprintf("/api/users/profile/%d", bob.ID), alice.Token).Expect(http.StatusOK).Decode(&profile)
+	if profile.Email != bob.Email {
+		t.Fatalf("expected a partner to see the email %q, got %q", bob.Email, profile.Email)
+	}
+
+	// Either partner can end the partnership
+	s.Delete(fmt.Sprintf("/api/users/partner/remove/%d", alice.ID), bob.Token).Expect(http.StatusOK)
+	s.Delete(fmt.Sprintf("/api/users/partner/remove/%d", alice.ID), bob.Token).Expect(http.StatusNotFound)
+	expectRelationship(t, s, alice, bob, consts.PARTNER_RELATION_NONE)
+	expectIDs(t, "partners", partnerIDs(t, s, "/api/users/partner", alice))
+}
+
+func TestPartnerReverseRequestAccepts(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser()
+	bob := s.CreateUser()
+
+	s.Post("/api/users/partner", alice.Token, map[string]interface{}{"user_id": bob.ID}).Expect(http.StatusCreated)
+	s.Post("/api/users/partner", bob.Token, map[string]interface{}{"user_id": alice.ID}).Expect(http.StatusOK)
+
+	expectRelationship(t, s, alice, bob, consts.PARTNER_RELATION_PARTNERS)
+	s.Post("/api/users/partner", alice.Token, map[string]interface{}{"user_id": bob.ID}).Expect(http.StatusConflict)
+}
+
+func TestPartnerDeclineCooldown(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser()
+	bob := s.CreateUser()
+
+	s.Post("/api/users/partner", alice.Token, map[string]interface{}{"user_id": bob.ID}).Expect(http.StatusCreated)
+	s.Put(fmt.Sprintf("/api/users/partner/%d", alice.ID), bob.Token, map[string]interface{}{"accept": false}).
+		Expect(http.StatusOK)
+
+	status := expectRelationship(t, s, alice, bob, consts.PARTNER_RELATION_DECLINED)
+	if status.CanRequest || status.RetryAfter <= 0 {
+		t.Fatalf("expected a cooldown after the decline, got %+v", status)
+	}
+	s.Post("/api/users/partner", alice.Token, map[string]interface{}{"user_id": bob.ID}).
+		Expect(http.StatusTooManyRequests)
+
+	// The user who declined can still ask
+	expectRelationship(t, s, bob, alice, consts.PARTNER_RELATION_NONE)
+	s.Post("/api/users/partner", bob.Token, map[string]interface{}{"user_id": alice.ID}).Expect(http.StatusCreated)
+	expectRelationship(t, s, alice, bob, consts.PARTNER_RELATION_REQUEST_RECEIVED)
+}
+
+func TestPartnerCancelRequest(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser()
+	bob := s.CreateUser()
+
+	s.Post("/api/users/partner", alice.Token, map[string]interface{}{"user_id": bob.ID}).Expect(http.StatusCreated)
+	s.Delete(fmt.Sprintf("/api/users/partner/%d", bob.ID), alice.Token).Expect(http.StatusOK)
+	s.Delete(fmt.Sprintf("/api/users/partner/%d", bob.ID), alice.Token).Expect(http.StatusNotFound)
+
+	expectRelationship(t, s, bob, alice, consts.PARTNER_RELATION_NONE)
+	expectIDs(t, "pending requests", partnerIDs(t, s, "/api/users/partner/pending", bob))
+}
+
+func TestPartnerInvalidRequests(t *testing.T) {
+	s := apitest.New(t)
+	alice := s.CreateUser()
+
+	s.Post("/api/users/partner", alice.Token, map[string]interface{}{"user_id": alice.ID}).Expect(http.StatusBadRequest)
+	s.Post("/api/users/partner", alice.Token, map[string]interface{}{"user_id": 999999}).Expect(http.StatusNotFound)
+	s.Get("/api/users/partner/status/999999", alice.Token).Expect(http.StatusNotFound)
+	s.Put("/api/users/partner/abc", alice.Token, map[string]interface{}{"accept": true}).Expect(http.StatusBadRequest)
+}
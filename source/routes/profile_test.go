@@ -0,0 +1,76 @@
+package routes_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"cnep-backend/source/apitest"
+	"cnep-backend/source/models"
+)
+
+func TestProfile(t *testing.T) {
+	s := apitest.New(t)
+	user := s.CreateUser()
+
+	var profile models.UserResponse
+	s.Get("/api/users/profile", user.Token).Expect(http.StatusOK).Decode(&profile)
+	if profile.ID != user.ID || profile.Email != user.Email || !profile.IsVerified || profile.Role != "user" {
+		t.Fatalf("unexpected profile %+v", profile)
+	}
+
+	var updated struct {
+		User models.UserResponse `json:"user"`
+	}
+	s.Put("/api/users/profile", user.Token, map[string]interface{}{
+		"name":        "Jane Doe",
+		"designation": "Volunteer",
+		"latitude":    52.37,
+		"longitude":   4.89,
+		// Fields a user cannot change are ignored
+		"role":  "admin",
+		"email": "changed@example.com",
+	}).Expect(http.StatusOK).Decode(&updated)
+	if updated.User.Name != "Jane Doe" || updated.User.Designation != "Volunteer" {
+		t.Fatalf("expected the profile to be updated, got %+v", updated.User)
+	}
+	if updated.User.Latitude == nil || *updated.User.Latitude != 52.37 {
+		t.Fatalf("expected the location to be updated, got %+v", updated.User)
+	}
+	if updated.User.Role != "user" || updated.User.Email != user.Email {
+		t.Fatalf("expected the role and email to be unchanged, got %+v", updated.User)
+	}
+
+	s.Get("/api/users/profile", user.Token).Expect(http.StatusOK).Decode(&profile)
+	if profile.Name != "Jane Doe" {
+		t.Fatalf("expected the update to be stored, got %+v", profile)
+	}
+
+	s.Put("/api/users/profile", user.Token, map[string]interface{}{"locale": "xx"}).Expect(http.StatusBadRequest)
+	s.Put("/api/users/profile", user.Token, map[string]interface{}{"latitude": 91}).Expect(http.StatusBadRequest)
+	s.Put("/api/users/profile", user.Token, map[string]interface{}{"topics": []int{999999}}).Expect(http.StatusBadRequest)
+}
+
+func TestProfileOfOtherUser(t *testing.T) {
+	s := apitest.New(t)
+	viewer := s.CreateUser()
+	user := s.CreateUser()
+
+	var profile models.UserResponse
+	s.Get(fmt.Sprintf("/api/users/profile/%d", user.ID), viewer.Token).Expect(http.StatusOK).Decode(&profile)
+	if profile.ID != user.ID {
+		t.Fatalf("expected the profile of user %d, got %d", user.ID, profile.ID)
+	}
+
+	// Emails are only shown to partners by default
+	if profile.Email != "" {
+		t.Fatalf("expected the email to be hidden from a stranger, got %q", profile.Email)
+	}
+	// The role and locale are only shown to the user
+	if profile.Role != "" || profile.Locale != "" {
+		t.Fatalf("expected the role and locale to be hidden from others, got %+v", profile)
+	}
+
+	s.Get("/api/users/profile/999999", viewer.Token).Expect(http.StatusNotFound)
+	s.Get("/api/users/profile/abc", viewer.Token).Expect(http.StatusBadRequest)
+}
@@ -1,51 +1,124 @@
 package routes
 
 import (
+	"cnep-backend/pkg/consts"
+	"cnep-backend/source/config"
 	"cnep-backend/source/handlers"
 	"cnep-backend/source/middleware"
+	"cnep-backend/source/services"
 
 	"github.com/gofiber/fiber/v2"
 )
 
-func SetupRoutes(app *fiber.App) {
+func SetupRoutes(app *fiber.App, cfg *config.Config, svc *services.Services) {
 
-	// use logger middleware
+	// give every request an ID and its client details, then log it
+	app.Use(middleware.RequestContext())
 	app.Use(middleware.Logger())
 
 	app.Get("/", handlers.Status())
-	
+
+	// Serve uploaded files when they are kept on the local filesystem
+	if cfg.StorageDriver == "local" {
+		app.Static(cfg.StorageBaseURL, cfg.StoragePath)
+	}
+
 	// Public routes
-	app.Get("/api/auth/users", handlers.CheckEmailExistence())
-	app.Post("/api/auth/continue", handlers.Authentication())
-	app.Post("/api/otp/generate", handlers.RegenerateOTP())
-	app.Post("/api/otp/verify", handlers.VerifyOTP())
+	app.Get("/api/auth/users", handlers.CheckEmailExistence(svc.Auth))
+	app.Post("/api/auth/continue", handlers.Authentication(svc.Auth))
+	app.Post("/api/auth/refresh", handlers.RefreshToken(svc.Sessions))
+	app.Post("/api/auth/logout", handlers.Logout(svc.Sessions))
+	app.Post("/api/auth/2fa/verify", handlers.VerifyTwoFactor(svc.TwoFactor))
+	app.Post("/api/auth/password/forgot", handlers.ForgotPassword(svc.Auth))
+	app.Post("/api/auth/password/reset", handlers.ResetPassword(svc.Auth))
+	app.Get("/api/auth/oidc/:provider", handlers.StartOIDCLogin(svc.OIDC))
+	app.Get("/api/auth/oidc/:provider/callback", handlers.OIDCCallback(svc.OIDC))
+	app.Post("/api/auth/oidc/:provider/callback", handlers.OIDCCallback(svc.OIDC))
+	app.Post("/api/otp/generate", handlers.RegenerateOTP(svc.Auth))
+	app.Post("/api/otp/verify", handlers.VerifyOTP(svc.Auth))
 
 	// Protected routes
-	api := app.Group("/api", middleware.AuthMiddleware())
+	api := app.Group("/api", middleware.AuthMiddleware(svc.Sessions))
 
 	// ===================================================================
 
 	usersApi := api.Group("/users")
 
 	// User routes
-	usersApi.Get("/profile", handlers.GetUserProfile())
-	usersApi.Get("/profile/:id", handlers.GetUserProfileByID())
-	usersApi.Put("/profile", handlers.UpdateUserProfile())
-	
-	// Sensitive routes
-	usersApi.Post("/password/change", handlers.ChangePassword())
+	usersApi.Get("/profile", handlers.GetUserProfile(svc.Users))
+	usersApi.Get("/profile/:id", handlers.GetUserProfileByID(svc.Users))
+	usersApi.Put("/profile", handlers.UpdateUserProfile(svc.Users))
+	usersApi.Post("/avatar", handlers.UploadAvatar(svc.Uploads))
+	usersApi.Get("/privacy", handlers.GetPrivacySettings(svc.Users))
+	usersApi.Put("/privacy", handlers.UpdatePrivacySettings(svc.Users))
+
+	// Sensitive routes, only the account owner may use them
+	usersApi.Post("/password/change", middleware.NoImpersonation(), handlers.ChangePassword(svc.Account))
+	usersApi.Post("/email/change", middleware.NoImpersonation(), handlers.ChangeEmail(svc.Account))
+	usersApi.Post("/email/verify", middleware.NoImpersonation(), handlers.VerifyEmailChange(svc.Account))
+
+	// Two-factor authentication routes
+	usersApi.Post("/2fa/setup", middleware.NoImpersonation(), handlers.SetupTwoFactor(svc.TwoFactor))
+	usersApi.Post("/2fa/confirm", middleware.NoImpersonation(), handlers.ConfirmTwoFactor(svc.TwoFactor))
+	usersApi.Post("/2fa/disable", middleware.NoImpersonation(), handlers.DisableTwoFactor(svc.TwoFactor))
+	usersApi.Post("/2fa/recovery-codes", middleware.NoImpersonation(), handlers.RegenerateRecoveryCodes(svc.TwoFactor))
+
+	// Session routes
+	usersApi.Get("/sessions", handlers.GetSessions(svc.Sessions))
+	usersApi.Delete("/sessions", middleware.NoImpersonation(), handlers.RevokeOtherSessions(svc.Sessions))
+	usersApi.Delete("/sessions/:id", middleware.NoImpersonation(), handlers.RevokeSession(svc.Sessions))
 
 	// Feedback routes
-	usersApi.Post("/feedback", handlers.AddFeedback())
-	usersApi.Get("/feedback", handlers.GetFeedback())
-	usersApi.Get("/feedback/:id", handlers.GetFeedbackByID())
+	usersApi.Post("/feedback", handlers.AddFeedback(svc.Feedback))
+	usersApi.Get("/feedback", handlers.GetFeedback(svc.Feedback))
+	usersApi.Get("/feedback/:id", handlers.GetFeedbackByID(svc.Feedback))
 
 	// Partner routes
-	usersApi.Get("/partner", handlers.GetPartners())
-	usersApi.Post("/partner", handlers.AddPartner())
-	usersApi.Put("/partner/:id", handlers.UpdatePartnerStatus())
-	usersApi.Get("/partner/pending", handlers.GetPendingPartners())
-	usersApi.Delete("/partner/:id", handlers.CancelPartnerRequest())
+	usersApi.Get("/partner", handlers.GetPartners(svc.Partners))
+	usersApi.Post("/partner", handlers.AddPartner(svc.Partners))
+	usersApi.Put("/partner/:id", handlers.UpdatePartnerStatus(svc.Partners))
+	usersApi.Get("/partner/pending", handlers.GetPendingPartners(svc.Partners))
+	usersApi.Get("/partner/outgoing", handlers.GetOutgoingPartnerRequests(svc.Partners))
+	usersApi.Get("/partner/suggestions", handlers.GetPartnerSuggestions(svc.Partners))
+	usersApi.Get("/partner/status/:id", handlers.GetPartnerStatus(svc.Partners))
+	usersApi.Delete("/partner/remove/:id", handlers.RemovePartner(svc.Partners))
+	usersApi.Delete("/partner/:id", handlers.CancelPartnerRequest(svc.Partners))
+
+	// Block routes
+	usersApi.Get("/blocks", handlers.GetBlockedUsers(svc.Blocks))
+	usersApi.Post("/blocks", handlers.BlockUser(svc.Blocks))
+	usersApi.Delete("/blocks/:id", handlers.UnblockUser(svc.Blocks))
+
+	// Media routes
+	api.Post("/media", handlers.UploadMedia(svc.Uploads))
+
+	// Business page routes
+	api.Post("/businesses/:id/logo", handlers.UploadBusinessImage(svc.Uploads, consts.UPLOAD_PURPOSE_LOGO))
+	api.Post("/businesses/:id/cover", handlers.UploadBusinessImage(svc.Uploads, consts.UPLOAD_PURPOSE_COVER))
+	api.Post("/businesses/products/:id/images", handlers.AddProductImage(svc.Uploads))
+
+	// Report routes
+	api.Post("/reports", handlers.ReportContent(svc.Moderation))
+
+	// Admin routes, open to moderators and admins. Admin only routes add their own RequireRole
+	adminApi := api.Group("/admin", middleware.RequireRole(consts.ROLE_MODERATOR))
+	adminApi.Get("/users", handlers.SearchUsers(svc.Admin))
+	adminApi.Get("/users/:id", handlers.GetUserForAdmin(svc.Admin))
+	adminApi.Post("/users/:id/suspend", handlers.SuspendUser(svc.Admin))
+	adminApi.Post("/users/:id/ban", middleware.RequireRole(consts.ROLE_ADMIN), handlers.BanUser(svc.Admin))
+	adminApi.Delete("/users/:id/suspension", middleware.RequireRole(consts.ROLE_ADMIN), handlers.ReinstateUser(svc.Admin))
+	adminApi.Post("/users/:id/verify", middleware.RequireRole(consts.ROLE_ADMIN), handlers.VerifyUserEmail(svc.Admin))
+	adminApi.Post("/users/:id/otp", middleware.RequireRole(consts.ROLE_ADMIN), handlers.ResendUserOTP(svc.Admin))
+	adminApi.Post("/users/:id/impersonate", middleware.RequireRole(consts.ROLE_ADMIN), handlers.ImpersonateUser(svc.Admin))
+	adminApi.Put("/users/:id/role", middleware.RequireRole(consts.ROLE_ADMIN), handlers.SetUserRole(svc.Admin))
+	adminApi.Get("/audit-logs", middleware.RequireRole(consts.ROLE_ADMIN), handlers.GetAuditLogs(svc.Admin))
+
+	// Moderation queue
+	adminApi.Get("/reports", handlers.GetModerationQueue(svc.Moderation))
+	adminApi.Get("/reports/:id", handlers.GetModerationCase(svc.Moderation))
+	adminApi.Post("/reports/:id/dismiss", handlers.DismissCase(svc.Moderation))
+	adminApi.Post("/reports/:id/hide", handlers.HideCaseContent(svc.Moderation))
+	adminApi.Post("/reports/:id/suspend", handlers.SuspendCaseAuthor(svc.Moderation))
 
 	// // Post routes
 	// api.Get("/posts", handlers.GetPosts(db))
@@ -0,0 +1,55 @@
+package routes_test
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"cnep-backend/source/apitest"
+	"cnep-backend/source/config"
+)
+
+// uploadRequest returns a multipart POST to path of a file of the given size,
+// with the purpose field unless it is empty.
+func uploadRequest(t *testing.T, path, token, purpose string, size int) *http.Request {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	if purpose != "" {
+		if err := writer.WriteField("purpose", purpose); err != nil {
+			t.Fatal(err)
+		}
+	}
+	part, err := writer.CreateFormFile("file", "upload.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(bytes.Repeat([]byte{'x'}, size))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return req
+}
+
+func TestUploadSizeIsCheckedByTheService(t *testing.T) {
+	s := apitest.New(t)
+	user := s.CreateUser()
+	limit := config.New().UploadMaxSize
+
+	// A file of the configured size fits in the body limit, so the service inspects it
+	s.Send(uploadRequest(t, "/api/media", user.Token, "post", limit)).Expect(http.StatusUnsupportedMediaType)
+
+	// A larger one is refused by the service, with its own error
+	var body struct {
+		Error string `json:"error"`
+	}
+	s.Send(uploadRequest(t, "/api/media", user.Token, "post", limit+1)).Expect(http.StatusRequestEntityTooLarge).Decode(&body)
+	if body.Error != "File too large" {
+		t.Fatalf("expected the error of the upload service, got %q", body.Error)
+	}
+}
@@ -0,0 +1,604 @@
+// Package seed fills a local database with generated data for development and demos.
+package seed
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"cnep-backend/pkg/consts"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/models"
+
+	"github.com/lib/pq"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Password is the password of every seeded user.
+const Password = "Password@123"
+
+// Options controls what is seeded. The same seed and user count always generate the same data,
+// only the timestamps move with the day the data is seeded.
+type Options struct {
+	Seed  int64
+	Users int
+	// Reset empties every table before seeding. Without it seeding refuses to run on a database with users.
+	Reset bool
+}
+
+// Summary counts the seeded rows by table.
+type Summary map[string]int
+
+// The center of the seeded user locations, users are placed within about 30 km of it
+const (
+	centerLatitude  = 22.5726
+	centerLongitude = 88.3639
+)
+
+var (
+	firstNames = []string{"Aarav", "Ananya", "Arjun", "Diya", "Ishaan", "Kavya", "Rohan", "Saanvi", "Vivaan", "Meera",
+		"Kabir", "Anika", "Aditya", "Riya", "Dev", "Priya", "Nikhil", "Tara", "Sameer", "Leela"}
+	lastNames = []string{"Sharma", "Banerjee", "Das", "Iyer", "Khan", "Mukherjee", "Patel", "Roy", "Sen", "Gupta",
+		"Chatterjee", "Nair", "Bose", "Rao", "Ghosh"}
+	designations = []string{"Student", "Teacher", "Software Engineer", "Nurse", "Shop Owner", "Designer", "Retired",
+		"Electrician", "Accountant", "Volunteer"}
+	neighbourhoods = []string{"Salt Lake", "Park Street", "Ballygunge", "Howrah", "Dum Dum", "Garia", "New Town",
+		"Behala", "Tollygunge", "Shyambazar"}
+	topicTitles = []string{"Education", "Health", "Food", "Environment", "Technology", "Elderly Care", "Animal Welfare",
+		"Transport", "Housing", "Arts"}
+	requestCaptions = []string{
+		"Looking for someone to help my grandmother with groceries this week",
+		"Need a tutor for class 10 maths, two evenings a week",
+		"Can anyone lend a ladder for the weekend?",
+		"Need help moving a few boxes to the new flat on Sunday",
+		"Looking for blood donors, O negative, at the city hospital",
+		"Could someone help set up a laptop for an online class?",
+		"Need a ride to the clinic on Thursday morning",
+		"Looking for volunteers to clean up the park by the lake",
+	}
+	postCaptions = []string{
+		"Thank you all for the help with the community kitchen last week!",
+		"The library corner in our building is open, bring a book and take a book",
+		"Planted twenty saplings along the main road today",
+		"Free health camp at the community hall this Saturday",
+		"Our street dog shelter found homes for three puppies",
+		"Sharing notes from the first aid workshop",
+	}
+	comments = []string{"I can help with this", "Count me in!", "Shared with my neighbours", "Thank you for organising",
+		"Sent you a message", "Great work", "Is this still needed?"}
+	feedbackContents = []string{"Very helpful and on time", "Went out of their way to help", "Friendly and patient",
+		"Would ask for help again", "Helped, but arrived late", "Great communication throughout"}
+	messageContents = []string{"Hi! Are you free this weekend?", "Thanks again for yesterday", "Sure, what time works for you?",
+		"I'll bring the tools", "See you at the park", "Can you share the address?", "Running ten minutes late"}
+	businessNames = []string{"Green Leaf Grocers", "Sen's Bakery", "Fix-It Electronics", "Paper Boat Books", "Chai Corner",
+		"Handloom House", "Bright Smiles Dental", "Cycle Doctor"}
+	businessCategories = []string{"Groceries", "Food", "Repairs", "Books", "Cafe", "Clothing", "Health", "Transport"}
+	productNames       = []string{"Gift Box", "Weekly Pack", "Service Visit", "Starter Kit", "Combo Meal", "Membership",
+		"Repair Voucher", "Seasonal Special"}
+)
+
+type topic struct {
+	ID        uint
+	Title     string
+	CreatedAt time.Time
+}
+
+type post struct {
+	ID         uint
+	UserID     uint
+	IsRequest  bool
+	IsUrgent   bool
+	AssignedTo *uint
+	Caption    string
+	Status     string
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type business struct {
+	ID          uint
+	OwnerID     uint
+	Name        string
+	Description string
+	Category    string
+	Contact     string
+	Location    string
+	Badges      pq.Int64Array `gorm:"type:integer[]"`
+	Topics      pq.Int64Array `gorm:"type:integer[]"`
+	Rating      float64
+	CreatedAt   time.Time
+}
+
+type pageFollower struct {
+	BusinessPageID uint
+	UserID         uint
+}
+
+// generator generates the rows of one seed run.
+type generator struct {
+	tx      *gorm.DB
+	rand    *rand.Rand
+	now     time.Time
+	summary Summary
+}
+
+/*
+The Run function seeds the database.
+
+Steps:
+ 1. Empties every table except schema_migrations when Reset is set, otherwise checks there are no users yet.
+ 2. Creates topics, badges and users, with an admin and a moderator among them.
+ 3. Links users as partners, with accepted, pending and declined requests.
+ 4. Creates posts, including help requests in every status, with helps, comments, reactions and feedback.
+ 5. Creates business pages with products and followers, and conversations between partners.
+
+Everything runs in one transaction, so a failed run leaves the database as it was.
+
+Returns:
+
+	The number of rows seeded by table.
+*/
+func Run(db *gorm.DB, opts Options) (Summary, error) {
+	if opts.Users < 3 {
+		return nil, fmt.Errorf("at least 3 users are needed, got %d", opts.Users)
+	}
+
+	g := &generator{
+		rand:    rand.New(rand.NewSource(opts.Seed)),
+		now:     time.Now().UTC().Truncate(time.Hour),
+		summary: Summary{},
+	}
+
+	// Hash once, bcrypt is slow on purpose
+	hash, err := utils.HashPassword(Password)
+	if err != nil {
+		return nil, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		g.tx = tx
+
+		if opts.Reset {
+			if err := truncateAll(tx); err != nil {
+				return err
+			}
+		} else {
+			var count int64
+			if err := tx.Table(consts.USERS_TABLE).Count(&count).Error; err != nil {
+				return err
+			}
+			if count > 0 {
+				return fmt.Errorf("the database already has users, seed with reset to replace them")
+			}
+		}
+
+		topics, err := g.topics()
+		if err != nil {
+			return err
+		}
+		badges, err := g.badges()
+		if err != nil {
+			return err
+		}
+		users, err := g.users(opts.Users, hash, topics)
+		if err != nil {
+			return err
+		}
+		partners, err := g.partners(users)
+		if err != nil {
+			return err
+		}
+		if err := g.posts(users, partners); err != nil {
+			return err
+		}
+		if err := g.businesses(users, topics, badges); err != nil {
+			return err
+		}
+		return g.conversations(partners)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return g.summary, nil
+}
+
+// truncateAll empties every table of the schema except schema_migrations and restarts the IDs.
+func truncateAll(tx *gorm.DB) error {
+	var tables []string
+	if err := tx.Raw(`SELECT tablename FROM pg_tables
+		WHERE schemaname = current_schema() AND tablename <> 'schema_migrations'`).Scan(&tables).Error; err != nil {
+		return err
+	}
+	if len(tables) == 0 {
+		return nil
+	}
+
+	quoted := make([]string, len(tables))
+	for i, table := range tables {
+		quoted[i] = pq.QuoteIdentifier(table)
+	}
+	return tx.Exec("TRUNCATE TABLE " + strings.Join(quoted, ", ") + " RESTART IDENTITY CASCADE").Error
+}
+
+// create inserts the rows into the table and counts them in the summary.
+func (g *generator) create(table string, rows interface{}, count int) error {
+	if count == 0 {
+		return nil
+	}
+	if err := g.tx.Table(table).Omit(clause.Associations).CreateInBatches(rows, 200).Error; err != nil {
+		return fmt.Errorf("seeding %s: %w", table, err)
+	}
+	g.summary[table] += count
+	return nil
+}
+
+// ago returns a time up to the given number of days before now.
+func (g *generator) ago(days int) time.Time {
+	return g.now.Add(-time.Duration(g.rand.Int63n(int64(days) * int64(24*time.Hour))))
+}
+
+func (g *generator) pick(values []string) string {
+	return values[g.rand.Intn(len(values))]
+}
+
+func (g *generator) topics() ([]uint, error) {
+	rows := make([]topic, len(topicTitles))
+	for i, title := range topicTitles {
+		rows[i] = topic{Title: title, CreatedAt: g.ago(365)}
+	}
+	if err := g.create(consts.TOPICS_TABLE, &rows, len(rows)); err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(rows))
+	for i := range rows {
+		ids[i] = rows[i].ID
+	}
+	return ids, nil
+}
+
+func (g *generator) badges() ([]uint, error) {
+	rows := []models.Badge{
+		{Name: "Verified Business", Description: "The business was verified by the community team", Image: "/static/badges/verified.png"},
+		{Name: "Local Favourite", Description: "Highly rated by people nearby", Image: "/static/badges/favourite.png"},
+		{Name: "Eco Friendly", Description: "Uses sustainable packaging", Image: "/static/badges/eco.png"},
+	}
+	for i := range rows {
+		rows[i].CreatedAt = g.ago(365)
+		rows[i].UpdatedAt = rows[i].CreatedAt
+	}
+	if err := g.create(consts.BADGES_TABLE, &rows, len(rows)); err != nil {
+		return nil, err
+	}
+
+	ids := make([]uint, len(rows))
+	for i := range rows {
+		ids[i] = rows[i].ID
+	}
+	return ids, nil
+}
+
+// users creates the users. The first is an admin and the second a moderator, the rest are users.
+// Emails are admin@example.com, moderator@example.com and user<n>@example.com.
+func (g *generator) users(count int, hash string, topics []uint) ([]models.User, error) {
+	visibilities := []string{consts.VISIBILITY_PUBLIC, consts.VISIBILITY_PARTNERS, consts.VISIBILITY_PRIVATE}
+
+	users := make([]models.User, count)
+	for i := range users {
+		first, last := g.pick(firstNames), g.pick(lastNames)
+		latitude := centerLatitude + (g.rand.Float64()-0.5)*0.5
+		longitude := centerLongitude + (g.rand.Float64()-0.5)*0.5
+
+		var userTopics pq.Int64Array
+		for _, id := range topics {
+			if g.rand.Intn(4) == 0 {
+				userTopics = append(userTopics, int64(id))
+			}
+		}
+
+		user := models.User{
+			Name:        first + " " + last,
+			Username:    fmt.Sprintf("%s.%s%d", strings.ToLower(first), strings.ToLower(last), i+1),
+			Email:       fmt.Sprintf("user%d@example.com", i+1),
+			Password:    hash,
+			Address:     fmt.Sprintf("%d %s Road, %s", g.rand.Intn(200)+1, g.pick(lastNames), g.pick(neighbourhoods)),
+			Designation: g.pick(designations),
+			Phone:       fmt.Sprintf("+9198%08d", g.rand.Intn(100000000)),
+			Locale:      "en",
+			Role:        consts.ROLE_USER,
+			Topics:      userTopics,
+			Latitude:    &latitude,
+			Longitude:   &longitude,
+			Privacy: models.PrivacySettings{
+				EmailVisibility:   visibilities[g.rand.Intn(len(visibilities))],
+				PhoneVisibility:   visibilities[g.rand.Intn(len(visibilities))],
+				AddressVisibility: visibilities[g.rand.Intn(len(visibilities))],
+			},
+			IsVerified: true,
+			CreatedAt:  g.ago(365),
+		}
+		user.UpdatedAt = user.CreatedAt
+		users[i] = user
+	}
+
+	users[0].Email, users[0].Role = "admin@example.com", consts.ROLE_ADMIN
+	users[1].Email, users[1].Role = "moderator@example.com", consts.ROLE_MODERATOR
+	// One unverified user to try the signup OTP flow with
+	users[count-1].IsVerified = false
+
+	if err := g.create(consts.USERS_TABLE, &users, len(users)); err != nil {
+		return nil, err
+	}
+	return users, nil
+}
+
+// partners links pairs of users. Each user gets a few accepted partners, and some pairs
+// have a pending or declined request. It returns the accepted pairs.
+func (g *generator) partners(users []models.User) ([][2]uint, error) {
+	var rows []models.Partner
+	var accepted [][2]uint
+	seen := make(map[[2]uint]bool)
+
+	for i := range users {
+		for n := 0; n < 4; n++ {
+			j := g.rand.Intn(len(users))
+			a, b := users[i].ID, users[j].ID
+			pair := [2]uint{min(a, b), max(a, b)}
+			if a == b || seen[pair] {
+				continue
+			}
+			seen[pair] = true
+
+			status := consts.PARTNER_STATUS_ACCEPTED
+			switch roll := g.rand.Intn(10); {
+			case roll < 2:
+				status = consts.PARTNER_STATUS_PENDING
+			case roll < 3:
+				status = consts.PARTNER_STATUS_DECLINED
+			}
+			if status == consts.PARTNER_STATUS_ACCEPTED {
+				accepted = append(accepted, pair)
+			}
+
+			sentAt := g.ago(180)
+			rows = append(rows, models.Partner{
+				SenderID:   a,
+				ReceiverID: b,
+				Status:     status,
+				SentAt:     sentAt,
+				UpdatedAt:  sentAt.Add(time.Duration(g.rand.Intn(72)) * time.Hour),
+			})
+		}
+	}
+
+	if err := g.create(consts.PARTNERS_TABLE, &rows, len(rows)); err != nil {
+		return nil, err
+	}
+	return accepted, nil
+}
+
+// posts creates regular posts and help requests. Requests cycle through every status: pending requests
+// are open, accepted ones are assigned to a partner, and completed ones also have a help and feedback.
+func (g *generator) posts(users []models.User, partners [][2]uint) error {
+	statuses := []string{consts.POST_STATUS_PENDING, consts.POST_STATUS_ACCEPTED, consts.POST_STATUS_COMPLETED}
+
+	var posts []post
+	for i, user := range users {
+		count := 1 + g.rand.Intn(3)
+		for n := 0; n < count; n++ {
+			createdAt := g.ago(90)
+			posts = append(posts, post{
+				UserID:    user.ID,
+				Caption:   g.pick(postCaptions),
+				Status:    consts.POST_STATUS_COMPLETED,
+				CreatedAt: createdAt,
+				UpdatedAt: createdAt,
+			})
+		}
+
+		// Every user with a partner asks for help, so requests in all statuses exist
+		helper := partnerOf(partners, user.ID, g.rand)
+		if helper == 0 {
+			continue
+		}
+		status := statuses[i%len(statuses)]
+		request := post{
+			UserID:    user.ID,
+			IsRequest: true,
+			IsUrgent:  g.rand.Intn(4) == 0,
+			Caption:   g.pick(requestCaptions),
+			Status:    status,
+			CreatedAt: g.ago(60),
+		}
+		request.UpdatedAt = request.CreatedAt
+		if status != consts.POST_STATUS_PENDING {
+			request.AssignedTo = &helper
+			request.UpdatedAt = request.CreatedAt.Add(time.Duration(1+g.rand.Intn(48)) * time.Hour)
+		}
+		posts = append(posts, request)
+	}
+
+	if err := g.create(consts.POSTS_TABLE, &posts, len(posts)); err != nil {
+		return err
+	}
+
+	var helps []models.Help
+	var feedbacks []models.Feedback
+	var postComments []models.Comment
+	var reactions []models.Reaction
+	reactionKinds := []string{"like", "wow", "love", "angry", "sad"}
+
+	for _, p := range posts {
+		if p.IsRequest && p.Status == consts.POST_STATUS_COMPLETED {
+			helps = append(helps, models.Help{SenderID: *p.AssignedTo, ReceiverID: p.UserID, PostID: p.ID, CreatedAt: p.UpdatedAt})
+			feedbacks = append(feedbacks, models.Feedback{
+				SenderID:   p.UserID,
+				ReceiverID: *p.AssignedTo,
+				Content:    g.pick(feedbackContents),
+				Rating:     uint8(3 + g.rand.Intn(3)),
+				CreatedAt:  p.UpdatedAt,
+				UpdatedAt:  p.UpdatedAt,
+			})
+		}
+
+		count := g.rand.Intn(3)
+		for n := 0; n < count; n++ {
+			author := users[g.rand.Intn(len(users))]
+			createdAt := p.CreatedAt.Add(time.Duration(1+g.rand.Intn(24)) * time.Hour)
+			postComments = append(postComments, models.Comment{
+				PostID:    p.ID,
+				UserID:    author.ID,
+				Content:   g.pick(comments),
+				CreatedAt: createdAt,
+				UpdatedAt: createdAt,
+			})
+		}
+
+		reacted := make(map[uint]bool)
+		count = g.rand.Intn(5)
+		for n := 0; n < count; n++ {
+			user := users[g.rand.Intn(len(users))]
+			if reacted[user.ID] {
+				continue
+			}
+			reacted[user.ID] = true
+			reactions = append(reactions, models.Reaction{
+				PostID:    p.ID,
+				UserID:    user.ID,
+				Reaction:  reactionKinds[g.rand.Intn(len(reactionKinds))],
+				CreatedAt: p.CreatedAt.Add(time.Duration(1+g.rand.Intn(24)) * time.Hour),
+			})
+		}
+	}
+
+	if err := g.create(consts.HELPS_TABLE, &helps, len(helps)); err != nil {
+		return err
+	}
+	if err := g.create(consts.FEEDBACK_TABLE, &feedbacks, len(feedbacks)); err != nil {
+		return err
+	}
+	if err := g.create(consts.COMMENTS_TABLE, &postComments, len(postComments)); err != nil {
+		return err
+	}
+	return g.create(consts.REACTIONS_TABLE, &reactions, len(reactions))
+}
+
+// businesses gives about one in five users a business page with products and followers.
+func (g *generator) businesses(users []models.User, topics, badges []uint) error {
+	var pages []business
+	for i := 2; i < len(users); i += 5 {
+		n := len(pages)
+		pages = append(pages, business{
+			OwnerID:     users[i].ID,
+			Name:        businessNames[n%len(businessNames)],
+			Description: "A neighbourhood business run by " + users[i].Name,
+			Category:    businessCategories[n%len(businessCategories)],
+			Contact:     users[i].Phone,
+			Location:    g.pick(neighbourhoods),
+			Badges:      pq.Int64Array{int64(badges[g.rand.Intn(len(badges))])},
+			Topics:      pq.Int64Array{int64(topics[g.rand.Intn(len(topics))])},
+			Rating:      float64(30+g.rand.Intn(21)) / 10,
+			CreatedAt:   g.ago(300),
+		})
+	}
+
+	if err := g.create(consts.BUSINESSES_TABLE, &pages, len(pages)); err != nil {
+		return err
+	}
+
+	var products []models.Product
+	var followers []pageFollower
+	for _, page := range pages {
+		count := 2 + g.rand.Intn(4)
+		for n := 0; n < count; n++ {
+			createdAt := page.CreatedAt.Add(time.Duration(g.rand.Intn(30*24)) * time.Hour)
+			products = append(products, models.Product{
+				BusinessPageID: page.ID,
+				Name:           page.Category + " " + g.pick(productNames),
+				Description:    "Available at our " + page.Location + " store",
+				Price:          float64(50+g.rand.Intn(1950)) + 0.99,
+				Category:       page.Category,
+				CreatedAt:      createdAt,
+				UpdatedAt:      createdAt,
+			})
+		}
+
+		following := make(map[uint]bool)
+		count = g.rand.Intn(10)
+		for n := 0; n < count; n++ {
+			user := users[g.rand.Intn(len(users))]
+			if user.ID == page.OwnerID || following[user.ID] {
+				continue
+			}
+			following[user.ID] = true
+			followers = append(followers, pageFollower{BusinessPageID: page.ID, UserID: user.ID})
+		}
+	}
+
+	if err := g.create(consts.PRODUCTS_TABLE, &products, len(products)); err != nil {
+		return err
+	}
+	return g.create(consts.PAGE_FOLLOWERS_TABLE, &followers, len(followers))
+}
+
+// conversations starts a conversation between about half of the partners, with a few messages each.
+func (g *generator) conversations(partners [][2]uint) error {
+	var conversations []models.Conversation
+	for _, pair := range partners {
+		if g.rand.Intn(2) == 0 {
+			continue
+		}
+		createdAt := g.ago(60)
+		conversations = append(conversations, models.Conversation{
+			User1ID:   pair[0],
+			User2ID:   pair[1],
+			CreatedAt: createdAt,
+			UpdatedAt: createdAt,
+		})
+	}
+
+	if err := g.create(consts.CONVERSATIONS_TABLE, &conversations, len(conversations)); err != nil {
+		return err
+	}
+
+	var messages []models.Message
+	for _, conversation := range conversations {
+		sentAt := conversation.CreatedAt
+		count := 2 + g.rand.Intn(6)
+		for n := 0; n < count; n++ {
+			sender, receiver := conversation.User1ID, conversation.User2ID
+			if g.rand.Intn(2) == 0 {
+				sender, receiver = receiver, sender
+			}
+			sentAt = sentAt.Add(time.Duration(1+g.rand.Intn(180)) * time.Minute)
+			messages = append(messages, models.Message{
+				ConversationID: conversation.ID,
+				SenderID:       sender,
+				ReceiverID:     receiver,
+				Content:        g.pick(messageContents),
+				CreatedAt:      sentAt,
+			})
+		}
+	}
+
+	return g.create(consts.MESSAGES_TABLE, &messages, len(messages))
+}
+
+// partnerOf returns a random accepted partner of the user, or 0 if the user has none.
+func partnerOf(partners [][2]uint, userID uint, r *rand.Rand) uint {
+	var ids []uint
+	for _, pair := range partners {
+		if pair[0] == userID {
+			ids = append(ids, pair[1])
+		} else if pair[1] == userID {
+			ids = append(ids, pair[0])
+		}
+	}
+	if len(ids) == 0 {
+		return 0
+	}
+	return ids[r.Intn(len(ids))]
+}
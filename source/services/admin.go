@@ -0,0 +1,455 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/consts"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
+)
+
+// AdminService holds the user management actions of admins and moderators.
+type AdminService struct {
+	store repository.Store
+}
+
+func NewAdminService(store repository.Store) *AdminService {
+	return &AdminService{store: store}
+}
+
+/*
+The SetRole method changes the role of a user.
+
+Steps:
+ 1. Checks that the role is one of user, moderator or admin.
+ 2. Refuses to change the role of the acting admin, so the last admin cannot lock themselves out.
+ 3. Updates the role and signs the user out everywhere, so a removed role stops working
+    right away instead of when the current access tokens expire.
+
+Returns:
+
+	The updated user, or an error if the user does not exist.
+*/
+func (s *AdminService) SetRole(ctx context.Context, adminID, userID uint, role string) (*models.UserResponse, error) {
+	if !utils.IsValidRole(role) {
+		return nil, apierror.BadRequest("Invalid role")
+	}
+
+	if adminID == userID {
+		return nil, apierror.BadRequest("You cannot change your own role")
+	}
+
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Role != role {
+		err := s.store.Transaction(func(tx repository.Store) error {
+			if err := tx.Users().Update(user.ID, map[string]interface{}{"role": role}); err != nil {
+				return err
+			}
+
+			if _, err := tx.Sessions().RevokeOthers(user.ID, 0); err != nil {
+				return err
+			}
+
+			return recordAudit(tx, ctx, adminID, consts.AUDIT_USER_ROLE_CHANGE, consts.AUDIT_TARGET_USER, user.ID, models.AuditDetails{
+				"from": user.Role,
+				"to":   role,
+			})
+		})
+		if err != nil {
+			return nil, apierror.Internal("Could not update role")
+		}
+		user.Role = role
+	}
+
+	response := utils.ConvertToUserResponse(user)
+	return &response, nil
+}
+
+// UsersList is the list spec of the admin user search, by default the newest users first.
+var UsersList = utils.ListSpec{
+	Sorts: []utils.SortField{
+		{Name: "created", Column: "created_at", Kind: utils.SortTime, Desc: true},
+		{Name: "name", Column: "name", Kind: utils.SortString},
+	},
+	Filters:  []string{"q", "role", "status"},
+	IDColumn: "id",
+}
+
+/*
+The SearchUsers method lists users for admins and moderators.
+
+Filters:
+  - q matches the name, username or email.
+  - role limits the result to one role.
+  - status is one of verified, unverified, locked, suspended or banned.
+
+Returns:
+
+	The matching page of users, including their verification and suspension state, the total count
+	and the cursor of the next page.
+*/
+func (s *AdminService) SearchUsers(page utils.Page) ([]models.AdminUserResponse, int64, *string, error) {
+	filter := repository.UserFilter{
+		Query:  page.Filters["q"],
+		Role:   page.Filters["role"],
+		Status: page.Filters["status"],
+	}
+
+	if filter.Role != "" && !utils.IsValidRole(filter.Role) {
+		return nil, 0, nil, apierror.BadRequest("Invalid role")
+	}
+
+	switch filter.Status {
+	case "", "verified", "unverified", "locked", "suspended", "banned":
+	default:
+		return nil, 0, nil, apierror.BadRequest("Invalid status")
+	}
+
+	users, total, err := s.store.Users().Search(filter, page)
+	if err != nil {
+		return nil, 0, nil, listError(err, "Failed to search users")
+	}
+
+	users, next := pageItems(page, users, func(user *models.User) (interface{}, uint) {
+		if page.Sort.Name == "name" {
+			return user.Name, user.ID
+		}
+		return user.CreatedAt, user.ID
+	})
+
+	results := make([]models.AdminUserResponse, len(users))
+	for i := range users {
+		results[i] = adminUserResponse(&users[i])
+	}
+
+	return results, total, next, nil
+}
+
+/*
+The GetUser method returns the full profile of a user, including the verification,
+lock and suspension state and the number of active sessions. The view is written to the audit log.
+*/
+func (s *AdminService) GetUser(ctx context.Context, actorID, userID uint) (*models.AdminUserResponse, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	response := adminUserResponse(user)
+	if response.ActiveSessions, err = s.store.Sessions().CountActive(user.ID); err != nil {
+		return nil, apierror.Internal("Database error")
+	}
+
+	if err := recordAudit(s.store, ctx, actorID, consts.AUDIT_USER_VIEW, consts.AUDIT_TARGET_USER, user.ID, nil); err != nil {
+		return nil, apierror.Internal("Could not write audit log")
+	}
+
+	return &response, nil
+}
+
+/*
+The Suspend method suspends a user until the given time, or bans the user when until is nil.
+
+Steps:
+ 1. Requires a reason, and an end in the future for suspensions.
+ 2. Checks the acting user outranks the user, so moderators cannot suspend admins or each other.
+ 3. Stores the suspension and revokes every session of the user, signing them out everywhere.
+ 4. Writes the action to the audit log.
+*/
+func (s *AdminService) Suspend(ctx context.Context, actorID uint, actorRole string, userID uint, reason string, until *time.Time) (*models.AdminUserResponse, error) {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return nil, apierror.BadRequest("A reason is required")
+	}
+
+	if until != nil && !until.After(time.Now()) {
+		return nil, apierror.BadRequest("Suspension end must be in the future")
+	}
+
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !outranks(actorRole, user.Role) {
+		return nil, apierror.Forbidden("You cannot suspend this user")
+	}
+
+	err = s.store.Transaction(func(tx repository.Store) error {
+		return suspendUser(tx, ctx, actorID, user, reason, until)
+	})
+	if err != nil {
+		return nil, apierror.Internal("Could not suspend user")
+	}
+
+	response := adminUserResponse(user)
+	return &response, nil
+}
+
+// suspendUser stores the suspension, revokes every session of the user and writes the audit entry.
+// A nil until bans the user. The user is updated in place.
+func suspendUser(tx repository.Store, ctx context.Context, actorID uint, user *models.User, reason string, until *time.Time) error {
+	action := consts.AUDIT_USER_SUSPEND
+	details := models.AuditDetails{"reason": reason}
+	if until == nil {
+		action = consts.AUDIT_USER_BAN
+	} else {
+		details["until"] = until
+	}
+
+	now := time.Now()
+	if err := tx.Users().Update(user.ID, map[string]interface{}{
+		"suspended_at":      now,
+		"suspended_until":   until,
+		"suspension_reason": reason,
+	}); err != nil {
+		return err
+	}
+
+	if _, err := tx.Sessions().RevokeOthers(user.ID, 0); err != nil {
+		return err
+	}
+
+	if err := recordAudit(tx, ctx, actorID, action, consts.AUDIT_TARGET_USER, user.ID, details); err != nil {
+		return err
+	}
+
+	user.SuspendedAt = &now
+	user.SuspendedUntil = until
+	user.SuspensionReason = reason
+	return nil
+}
+
+/*
+The Reinstate method lifts the suspension or ban of a user and writes the action to the audit log.
+*/
+func (s *AdminService) Reinstate(ctx context.Context, actorID, userID uint) (*models.AdminUserResponse, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.SuspendedAt == nil {
+		return nil, apierror.BadRequest("User is not suspended")
+	}
+
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Users().Update(user.ID, map[string]interface{}{
+			"suspended_at":      nil,
+			"suspended_until":   nil,
+			"suspension_reason": "",
+		}); err != nil {
+			return err
+		}
+
+		return recordAudit(tx, ctx, actorID, consts.AUDIT_USER_REINSTATE, consts.AUDIT_TARGET_USER, user.ID, models.AuditDetails{
+			"previous_reason": user.SuspensionReason,
+		})
+	})
+	if err != nil {
+		return nil, apierror.Internal("Could not reinstate user")
+	}
+
+	user.SuspendedAt = nil
+	user.SuspendedUntil = nil
+	user.SuspensionReason = ""
+	response := adminUserResponse(user)
+	return &response, nil
+}
+
+/*
+The VerifyEmail method marks the email of a user as verified without an OTP,
+for users who cannot receive the email. Pending signup codes are removed.
+*/
+func (s *AdminService) VerifyEmail(ctx context.Context, actorID, userID uint) (*models.AdminUserResponse, error) {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.IsVerified {
+		return nil, apierror.BadRequest("User is already verified")
+	}
+
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Users().Update(user.ID, map[string]interface{}{"is_verified": true}); err != nil {
+			return err
+		}
+
+		if err := tx.Codes().DeleteUnused(user.ID, consts.OTP_PURPOSE_SIGNUP); err != nil {
+			return err
+		}
+
+		return recordAudit(tx, ctx, actorID, consts.AUDIT_USER_VERIFY, consts.AUDIT_TARGET_USER, user.ID, models.AuditDetails{
+			"email": user.Email,
+		})
+	})
+	if err != nil {
+		return nil, apierror.Internal("Could not verify user")
+	}
+
+	user.IsVerified = true
+	response := adminUserResponse(user)
+	return &response, nil
+}
+
+/*
+The ResendOTP method sends a new signup OTP to an unverified user on their behalf.
+Unlike the public endpoint it ignores the resend cooldown, but it still counts towards and stops at the daily limit.
+*/
+func (s *AdminService) ResendOTP(ctx context.Context, actorID, userID uint) error {
+	user, err := s.findUser(userID)
+	if err != nil {
+		return err
+	}
+
+	if user.IsVerified {
+		return apierror.BadRequest("User is already verified")
+	}
+
+	var otp string
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := claimOTPSend(tx, user.ID, 0); err != nil {
+			return err
+		}
+
+		var err error
+		if otp, err = issueCode(tx, user.ID, consts.OTP_PURPOSE_SIGNUP, ""); err != nil {
+			return err
+		}
+
+		return recordAudit(tx, ctx, actorID, consts.AUDIT_USER_RESEND_OTP, consts.AUDIT_TARGET_USER, user.ID, nil)
+	})
+	if err == errOTPThrottled {
+		return apierror.TooManyRequests("The user reached the daily limit of OTP emails")
+	}
+	if err != nil {
+		return apierror.Internal("Could not create OTP")
+	}
+
+	if err := utils.SendOTPEmail(user.Email, otp, consts.OTP_PURPOSE_SIGNUP, user.Locale); err != nil {
+		log.Printf("Error sending OTP email to user %d: %v", user.ID, err)
+		return apierror.Internal("Could not send OTP email")
+	}
+
+	return nil
+}
+
+/*
+The Impersonate method starts a session as another user, so admins can see what the user sees.
+
+Steps:
+ 1. Refuses to impersonate admins or the acting admin.
+ 2. Starts a session for the user that records the admin as impersonator.
+ 3. Issues only an access token. The session cannot be refreshed and ends when the token expires.
+ 4. Writes the action to the audit log.
+
+Actions only the account owner may take, like changing the password, are refused for impersonation tokens.
+*/
+func (s *AdminService) Impersonate(ctx context.Context, adminID, userID uint) (*LoginResult, error) {
+	if adminID == userID {
+		return nil, apierror.BadRequest("You cannot impersonate yourself")
+	}
+
+	user, err := s.findUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user.Role == consts.ROLE_ADMIN {
+		return nil, apierror.Forbidden("Admins cannot be impersonated")
+	}
+
+	client := clientFrom(ctx)
+	var token string
+	err = s.store.Transaction(func(tx repository.Store) error {
+		session := models.Session{
+			UserID:         user.ID,
+			Device:         "Impersonation",
+			IP:             client.IP,
+			UserAgent:      client.UserAgent,
+			ImpersonatorID: &adminID,
+		}
+		if err := tx.Sessions().Create(&session); err != nil {
+			return err
+		}
+
+		if err := recordAudit(tx, ctx, adminID, consts.AUDIT_USER_IMPERSONATE, consts.AUDIT_TARGET_USER, user.ID, models.AuditDetails{
+			"session_id": session.ID,
+		}); err != nil {
+			return err
+		}
+
+		var err error
+		token, err = utils.GenerateImpersonationJWT(user.ID, session.ID, user.Role, adminID)
+		return err
+	})
+	if err != nil {
+		return nil, apierror.Internal("Could not start impersonation")
+	}
+
+	userResponse := utils.ConvertToUserResponse(user)
+	return &LoginResult{
+		Token:     token,
+		ExpiresIn: int(utils.AccessTokenTTL().Seconds()),
+		User:      &userResponse,
+	}, nil
+}
+
+// findUser returns the user an action targets, or a not found error.
+func (s *AdminService) findUser(userID uint) (*models.User, error) {
+	user, err := s.store.Users().Get(userID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apierror.NotFound("User not found")
+	}
+	if err != nil {
+		return nil, apierror.Internal("Database error")
+	}
+	return user, nil
+}
+
+// isSuspended reports whether the user is banned or suspended right now.
+func isSuspended(user *models.User) bool {
+	if user.SuspendedAt == nil {
+		return false
+	}
+	return user.SuspendedUntil == nil || time.Now().Before(*user.SuspendedUntil)
+}
+
+// suspendedError is the error returned to a suspended user, with the reason and end of the suspension.
+func suspendedError(user *models.User) *apierror.Error {
+	return apierror.Forbidden("Account suspended").WithCode(apierror.CodeAccountSuspended).WithDetails(map[string]interface{}{
+		"reason":          user.SuspensionReason,
+		"suspended_until": user.SuspendedUntil,
+	})
+}
+
+// outranks reports whether a user with role may act against a user with target, which
+// requires a strictly higher role.
+func outranks(role, target string) bool {
+	return utils.HasRole(role, target) && !utils.HasRole(target, role)
+}
+
+func adminUserResponse(user *models.User) models.AdminUserResponse {
+	return models.AdminUserResponse{
+		UserResponse:      utils.ConvertToUserResponse(user),
+		LockedUntil:       user.LockedUntil,
+		OTPFailedAttempts: user.OTPFailedAttempts,
+		OTPLockedUntil:    user.OTPLockedUntil,
+		OTPCodeFailures:   user.OTPCodeFailures,
+		SuspendedAt:       user.SuspendedAt,
+		SuspendedUntil:    user.SuspendedUntil,
+		SuspensionReason:  user.SuspensionReason,
+		Suspended:         isSuspended(user),
+	}
+}
@@ -0,0 +1,70 @@
+package services
+
+import (
+	"context"
+	"strconv"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
+)
+
+// recordAudit writes an admin or moderator action to the audit log. It takes the transaction
+// of the action, so an action is never applied without its audit entry.
+func recordAudit(tx repository.Store, ctx context.Context, actorID uint, action, targetType string, targetID uint, details models.AuditDetails) error {
+	entry := models.AuditLog{
+		ActorID:    &actorID,
+		Action:     action,
+		TargetType: targetType,
+		TargetID:   targetID,
+		Details:    details,
+		IP:         clientFrom(ctx).IP,
+	}
+	return tx.Audit().Create(&entry)
+}
+
+// AuditLogList is the list spec of the audit log, newest entries first.
+var AuditLogList = utils.ListSpec{
+	Sorts: []utils.SortField{
+		{Name: "created", Column: "created_at", Kind: utils.SortTime, Desc: true},
+	},
+	Filters:  []string{"actor_id", "action", "target_type", "target_id"},
+	IDColumn: "id",
+}
+
+/*
+The AuditLogs method lists audit log entries.
+The entries can be filtered by actor_id, action, target_type and target_id.
+It returns the page of entries, the total count and the cursor of the next page.
+*/
+func (s *AdminService) AuditLogs(page utils.Page) ([]models.AuditLog, int64, *string, error) {
+	filter := repository.AuditFilter{
+		Action:     page.Filters["action"],
+		TargetType: page.Filters["target_type"],
+	}
+	ids := []struct {
+		name string
+		id   *uint
+	}{{"actor_id", &filter.ActorID}, {"target_id", &filter.TargetID}}
+	for _, f := range ids {
+		if value, ok := page.Filters[f.name]; ok {
+			id, err := strconv.ParseUint(value, 10, 64)
+			if err != nil || id == 0 {
+				return nil, 0, nil, apierror.BadRequest("Invalid " + f.name)
+			}
+			*f.id = uint(id)
+		}
+	}
+
+	entries, total, err := s.store.Audit().List(filter, page)
+	if err != nil {
+		return nil, 0, nil, listError(err, "Failed to retrieve audit log")
+	}
+
+	entries, next := pageItems(page, entries, func(entry *models.AuditLog) (interface{}, uint) {
+		return entry.CreatedAt, entry.ID
+	})
+
+	return entries, total, next, nil
+}
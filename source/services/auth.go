@@ -1,137 +1,131 @@
 package services
 
 import (
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/consts"
 	"cnep-backend/pkg/utils"
-	"cnep-backend/source/database"
 	"cnep-backend/source/models"
-	"github.com/gofiber/fiber/v2"
+	"cnep-backend/source/repository"
+	"context"
+	"errors"
 
 	"golang.org/x/crypto/bcrypt"
-	"gorm.io/gorm"
 	"log"
 	"time"
 )
 
-// Checks if the email exists in the database
-func CheckEmailExistence(c *fiber.Ctx, email string) error {
-	var user models.User
+// LoginResult is the outcome of a successful login. It either holds a session, or a partial
+// token to exchange at the two-factor verify endpoint when TwoFactorRequired is set.
+type LoginResult struct {
+	TwoFactorRequired bool                 `json:"two_factor_required,omitempty"`
+	TwoFactorToken    string               `json:"two_factor_token,omitempty"`
+	Message           string               `json:"message,omitempty"`
+	Token             string               `json:"token,omitempty"`
+	RefreshToken      string               `json:"refresh_token,omitempty"`
+	ExpiresIn         int                  `json:"expires_in"`
+	User              *models.UserResponse `json:"user,omitempty"`
+}
 
-	// Ensure database connection is established
-	if database.DB == nil {
-		log.Panic("Database not connected")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database not connected"})
-	}
+// AuthService signs users up and in with their email and password, and resets forgotten passwords.
+type AuthService struct {
+	store repository.Store
+}
+
+func NewAuthService(store repository.Store) *AuthService {
+	return &AuthService{store: store}
+}
 
+// Checks if the email exists in the database
+func (s *AuthService) EmailExists(email string) (bool, error) {
+	email = utils.NormalizeEmail(email)
 	if !utils.IsValidEmail(email) {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid email format"})
+		return false, apierror.BadRequest("Invalid email format")
 	}
 
-	result := database.DB.Where("email = ?", email).First(&user)
-
-	if result.Error == nil {
-		// User exists
-		return c.JSON(fiber.Map{"exists": true})
-	} else if result.Error == gorm.ErrRecordNotFound {
-		// User does not exist
-		return c.JSON(fiber.Map{"exists": false})
-	} else {
-		// Database error
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database error"})
+	exists, err := s.store.Users().EmailExists(email)
+	if err != nil {
+		return false, apierror.Internal("Database error")
 	}
+	return exists, nil
 }
 
 // Registers a new user in the database
-func RegisterService(c *fiber.Ctx, email, password string) error {
-	// Ensure database connection is established
-	if database.DB == nil {
-		log.Panic("Database not connected")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database not connected"})
-	}
-
+func (s *AuthService) Register(ctx context.Context, email, password string) error {
+	email = utils.NormalizeEmail(email)
 	if !utils.IsValidEmail(email) {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid email format"})
+		return apierror.BadRequest("Invalid email format")
 	}
 
 	if !utils.IsValidPassword(password) {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Password does not meet complexity requirements"})
+		return apierror.BadRequest("Password does not meet complexity requirements")
 	}
 
 	hashedPassword, err := utils.HashPassword(password)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not hash password"})
+		return apierror.Internal("Could not hash password")
+	}
+
+	username, err := utils.GenerateUsername(email)
+	if err != nil {
+		return apierror.Internal("Could not create user")
 	}
 
 	var user models.User
 	user.Password = hashedPassword
 	user.Email = email
+	user.Username = username
+	user.Locale = clientFrom(ctx).Locale
 
-	// Generate OTP
-	otp := utils.GenerateOTP()                    // Implement this function in your utilss package
-	otpExpiry := time.Now().Add(15 * time.Minute) // Expiry time for OTP is 15 minutes
-
-	user.OTP = otp
-	user.OTPExpiry = otpExpiry
+	now := time.Now()
+	user.OTPLastSentAt = &now
+	user.OTPSendWindowStart = &now
+	user.OTPSendCount = 1
 	user.IsVerified = false
 
-	if err := database.DB.Create(&user).Error; err != nil {
-		if utils.IsDuplicateEntryError(err) {
-			return c.Status(fiber.StatusConflict).JSON(fiber.Map{"error": "Email already exists"})
+	// Create the user together with its signup OTP
+	var otp string
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Users().Create(&user); err != nil {
+			return err
 		}
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create user"})
-	}
-	log.Println("OTP sent to user ", user.Email, "with OTP ", otp)
-
-	// Send OTP via email
-	// if err := utils.SendOTPEmail(user.Email, otp); err != nil {
-	// 	return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not send OTP email"})
-	// }
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message": "User registered. Please verify your email with the OTP sent.",
+		var err error
+		otp, err = issueCode(tx, user.ID, consts.OTP_PURPOSE_SIGNUP, "")
+		return err
 	})
-}
-
-// Logs in a user with the provided email and password
-func LoginService(c *fiber.Ctx, email, password string) error {
-	// Ensure database connection is established
-	if database.DB == nil {
-		log.Panic("Database not connected")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database not connected"})
+	if err != nil {
+		if errors.Is(err, repository.ErrDuplicate) {
+			return apierror.Conflict("Email already exists")
+		}
+		return apierror.Internal("Could not create user")
+	}
+	// Queue the OTP email, a mail server failure must not fail the signup
+	if err := utils.SendOTPEmail(user.Email, otp, consts.OTP_PURPOSE_SIGNUP, user.Locale); err != nil {
+		log.Printf("Error sending OTP email to user %d: %v", user.ID, err)
 	}
 
+	return nil
+}
+
+// Logs in a user with the provided email and password.
+// Suspended users are refused, and users with two-factor authentication enabled get a partial token instead of a session.
+func (s *AuthService) Login(ctx context.Context, email, password string) (*LoginResult, error) {
+	email = utils.NormalizeEmail(email)
 	if !utils.IsValidEmail(email) || !utils.IsValidPassword(password) {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{
-			"error": "Invalid email or password",
-		})
+		return nil, apierror.BadRequest("Invalid email or password")
 	}
-	var user models.User
-	if err := database.DB.Where("email = ?", email).First(&user).Error; err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid email or password",
-		})
+	user, err := s.store.Users().GetByEmail(email)
+	if err != nil {
+		return nil, apierror.Unauthorized("Invalid email or password")
 	}
 
 	if !user.IsVerified {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Email not verified",
-		})
+		return nil, apierror.Unauthorized("Email not verified").WithCode(apierror.CodeEmailNotVerified)
 	}
 
 	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{
-			"error": "Invalid email or password",
-		})
+		return nil, apierror.Unauthorized("Invalid email or password")
 	}
 
-	token, err := utils.GenerateJWT(user.ID)
-	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{
-			"error": "Could not generate token",
-		})
-	}
-
-	return c.JSON(fiber.Map{
-		"token": token,
-		"user":  utils.ConvertToUserResponse(&user),
-	})
+	return loginResult(s.store, ctx, user)
 }
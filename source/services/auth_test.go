@@ -0,0 +1,111 @@
+package services_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/source/apitest"
+)
+
+func TestRegister(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		status   int
+	}{
+		{"valid", "new@example.com", apitest.Password, 0},
+		{"normalized email", "  New@Example.COM ", apitest.Password, 0},
+		{"invalid email", "not-an-email", apitest.Password, http.StatusBadRequest},
+		{"weak password", "new@example.com", "password", http.StatusBadRequest},
+		{"existing email", "user@example.com", apitest.Password, http.StatusConflict},
+		{"existing email in another case", "USER@example.com", apitest.Password, http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, store, mail := newServices(t)
+			createUser(t, store, "user@example.com")
+
+			expectStatus(t, svc.Auth.Register(context.Background(), tt.email, tt.password), tt.status)
+			if tt.status != 0 {
+				return
+			}
+
+			user, err := store.Users().GetByEmail("new@example.com")
+			if err != nil {
+				t.Fatalf("expected the user to be stored with the normalized email: %v", err)
+			}
+			if user.IsVerified || user.Username == "" || user.Password == tt.password {
+				t.Fatalf("expected an unverified user with a username and a hashed password, got %+v", user)
+			}
+			if user.OTPSendCount != 1 {
+				t.Fatalf("expected the signup email to count against the OTP limit, got %d", user.OTPSendCount)
+			}
+			mail.OTP(t, "new@example.com")
+		})
+	}
+}
+
+func TestLogin(t *testing.T) {
+	tests := []struct {
+		name     string
+		email    string
+		password string
+		status   int
+		code     string
+	}{
+		{"valid", "user@example.com", apitest.Password, 0, ""},
+		{"normalized email", " USER@example.com", apitest.Password, 0, ""},
+		{"wrong password", "user@example.com", "Wrong@1234", http.StatusUnauthorized, apierror.CodeUnauthorized},
+		{"unknown email", "unknown@example.com", apitest.Password, http.StatusUnauthorized, apierror.CodeUnauthorized},
+		{"unverified email", "unverified@example.com", apitest.Password, http.StatusUnauthorized, apierror.CodeEmailNotVerified},
+		{"invalid email", "not-an-email", apitest.Password, http.StatusBadRequest, apierror.CodeBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, store, _ := newServices(t)
+			createUser(t, store, "user@example.com")
+			unverified := createUser(t, store, "unverified@example.com")
+			if err := store.Users().Update(unverified.ID, map[string]interface{}{"is_verified": false}); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := svc.Auth.Login(context.Background(), tt.email, tt.password)
+			expectStatus(t, err, tt.status)
+			if tt.status != 0 {
+				if code := err.(*apierror.Error).Code; code != tt.code {
+					t.Fatalf("expected code %s, got %s", tt.code, code)
+				}
+				return
+			}
+
+			if result.Token == "" || result.RefreshToken == "" || result.User == nil || result.User.Email != "user@example.com" {
+				t.Fatalf("expected a session for user@example.com, got %+v", result)
+			}
+		})
+	}
+}
+
+func TestEmailExists(t *testing.T) {
+	svc, store, _ := newServices(t)
+	createUser(t, store, "user@example.com")
+
+	for email, want := range map[string]bool{
+		"user@example.com":    true,
+		"User@Example.com ":   true,
+		"other@example.com":   false,
+		"user@example.com.au": false,
+	} {
+		exists, err := svc.Auth.EmailExists(email)
+		if err != nil || exists != want {
+			t.Fatalf("expected %q to exist: %v, got %v (%v)", email, want, exists, err)
+		}
+	}
+
+	_, err := svc.Auth.EmailExists("not-an-email")
+	expectStatus(t, err, http.StatusBadRequest)
+}
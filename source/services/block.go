@@ -0,0 +1,106 @@
+package services
+
+import (
+	"errors"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
+)
+
+// BlockService lets users block each other.
+type BlockService struct {
+	store repository.Store
+}
+
+func NewBlockService(store repository.Store) *BlockService {
+	return &BlockService{store: store}
+}
+
+/*
+The Block method blocks a user for the given user.
+
+Steps:
+ 1. Checks the user is not blocking themselves and the blocked user exists.
+ 2. Stores the block. Blocking a user twice is not an error.
+ 3. Cancels any pending or accepted partner link between the two users, in either direction.
+
+Once blocked, neither user can send the other partner requests, feedback or messages,
+their posts and comments are hidden from each other, and their profiles are not found.
+*/
+func (s *BlockService) Block(userID, blockedID uint) error {
+	if userID == blockedID {
+		return apierror.BadRequest("You cannot block yourself")
+	}
+
+	exists, err := s.store.Users().Exists(blockedID)
+	if err != nil {
+		return apierror.Internal("Database error")
+	}
+	if !exists {
+		return apierror.NotFound("User not found")
+	}
+
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Blocks().Create(userID, blockedID); err != nil {
+			return err
+		}
+
+		return tx.Partners().Unlink(userID, blockedID)
+	})
+	if err != nil {
+		return apierror.Internal("Could not block user")
+	}
+
+	return nil
+}
+
+/*
+The Unblock method removes a block made by the given user. Partner links cancelled by the block are not restored.
+*/
+func (s *BlockService) Unblock(userID, blockedID uint) error {
+	err := s.store.Blocks().Delete(userID, blockedID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apierror.NotFound("User is not blocked")
+	}
+	if err != nil {
+		return apierror.Internal("Could not unblock user")
+	}
+
+	return nil
+}
+
+// BlocksList is the list spec of the users blocked by a user, by default the most recent blocks first.
+var BlocksList = utils.ListSpec{
+	Sorts: []utils.SortField{
+		{Name: "blocked", Column: "user_blocks.created_at", Kind: utils.SortTime, Desc: true},
+		{Name: "name", Column: "users.name", Kind: utils.SortString},
+	},
+	IDColumn: "users.id",
+}
+
+/*
+The List method lists the users blocked by the given user.
+It returns the page of users and the cursor of the next page.
+*/
+func (s *BlockService) List(userID uint, page utils.Page) ([]models.BlockedUser, *string, error) {
+	users, err := s.store.Blocks().List(userID, page)
+	if err != nil {
+		return nil, nil, listError(err, "Failed to retrieve blocked users")
+	}
+
+	users, next := pageItems(page, users, func(user *models.BlockedUser) (interface{}, uint) {
+		if page.Sort.Name == "name" {
+			return user.Name, user.ID
+		}
+		return user.BlockedAt, user.ID
+	})
+
+	return users, next, nil
+}
+
+// IsBlocked reports whether either user has blocked the other.
+func (s *BlockService) IsBlocked(userID, otherID uint) (bool, error) {
+	return s.store.Blocks().Between(userID, otherID)
+}
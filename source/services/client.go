@@ -0,0 +1,24 @@
+package services
+
+import "context"
+
+// Client describes who made a request. Sessions and the audit log record it.
+type Client struct {
+	IP        string
+	UserAgent string
+	Device    string
+	Locale    string
+}
+
+type clientKey struct{}
+
+// WithClient returns a context carrying the client of the request.
+func WithClient(ctx context.Context, client Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// clientFrom returns the client of the request, empty outside of HTTP requests.
+func clientFrom(ctx context.Context) Client {
+	client, _ := ctx.Value(clientKey{}).(Client)
+	return client
+}
@@ -0,0 +1,121 @@
+package services
+
+import (
+	"errors"
+	"log"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/consts"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/repository"
+)
+
+/*
+The RequestEmailChange method starts changing the email of a user.
+Here's a breakdown of what it does:
+
+Steps:
+ 1. Checks the new email format and the current password of the user.
+ 2. Checks that no other account uses the new email.
+ 3. Enforces the resend cooldown and the daily limit of OTP emails.
+ 4. Issues an OTP for the new email that expires in 15 minutes.
+ 5. Sends the OTP to the new email.
+
+The email itself is only changed once ConfirmEmailChange verifies the OTP.
+*/
+func (s *AccountService) RequestEmailChange(userID uint, newEmail, password string) error {
+	newEmail = utils.NormalizeEmail(newEmail)
+	if !utils.IsValidEmail(newEmail) {
+		return apierror.BadRequest("Invalid email format")
+	}
+
+	user, err := s.store.Users().Get(userID)
+	if err != nil {
+		return apierror.NotFound("User not found")
+	}
+
+	if !utils.CheckPasswordHash(password, user.Password) {
+		return apierror.Unauthorized("Incorrect password")
+	}
+
+	if newEmail == user.Email {
+		return apierror.BadRequest("New email is the same as the current email")
+	}
+
+	exists, err := s.store.Users().EmailExists(newEmail)
+	if err != nil {
+		return apierror.Internal("Database error")
+	}
+	if exists {
+		return apierror.Conflict("Email already exists")
+	}
+
+	var otp string
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := claimOTPSend(tx, user.ID, otpResendCooldown()); err != nil {
+			return err
+		}
+
+		var err error
+		otp, err = issueCode(tx, user.ID, consts.OTP_PURPOSE_EMAIL_CHANGE, newEmail)
+		return err
+	})
+	if err == errOTPThrottled {
+		return errOTPThrottled
+	}
+	if err != nil {
+		return apierror.Internal("Could not request email change")
+	}
+
+	if err := utils.SendOTPEmail(newEmail, otp, consts.OTP_PURPOSE_EMAIL_CHANGE, user.Locale); err != nil {
+		return apierror.Internal("Could not send OTP email")
+	}
+
+	return nil
+}
+
+/*
+The ConfirmEmailChange method swaps the email of the user to the pending email once the OTP
+sent to it is verified, and notifies the previous address about the change.
+If another account took the email in the meantime, it returns a conflict and leaves the email unchanged.
+*/
+func (s *AccountService) ConfirmEmailChange(userID uint, otp string) (string, error) {
+	if len(otp) != 8 {
+		return "", apierror.BadRequest("Invalid OTP format")
+	}
+
+	user, err := s.store.Users().Get(userID)
+	if err != nil {
+		return "", apierror.NotFound("User not found")
+	}
+
+	code, ok := verifyCode(s.store, user, consts.OTP_PURPOSE_EMAIL_CHANGE, otp)
+	if !ok {
+		return "", errInvalidOTP
+	}
+
+	oldEmail := user.Email
+	newEmail := code.Target
+
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Codes().Use(code.ID); err != nil {
+			return err
+		}
+		return tx.Users().Update(user.ID, map[string]interface{}{"email": newEmail})
+	})
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return "", errInvalidOTP
+		}
+		if errors.Is(err, repository.ErrDuplicate) {
+			return "", apierror.Conflict("Email already exists")
+		}
+		return "", apierror.Internal("Could not change email")
+	}
+
+	if err := utils.SendEmailChangedNotice(oldEmail, newEmail, user.Locale); err != nil {
+		log.Printf("Error notifying user %d about the email change: %v", user.ID, err)
+	}
+
+	return newEmail, nil
+}
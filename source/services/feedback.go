@@ -1,25 +1,35 @@
 package services
 
 import (
-	"cnep-backend/pkg/consts"
-	"cnep-backend/source/database"
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/utils"
 	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
 
-	"github.com/gofiber/fiber/v2"
-	"log"
+	"strconv"
 )
 
-func AddFeedback(c *fiber.Ctx, senderID uint, receiverID uint, content string, rating uint8) error {
+// FeedbackService stores the feedback users give each other.
+type FeedbackService struct {
+	store repository.Store
+}
+
+func NewFeedbackService(store repository.Store) *FeedbackService {
+	return &FeedbackService{store: store}
+}
+
+func (s *FeedbackService) Add(senderID uint, receiverID uint, content string, rating uint8) (*models.Feedback, error) {
 	var feedback models.Feedback
 
-	// Ensure database connection is established
-	if database.DB == nil {
-		log.Panic("Database not connected")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database not connected"})
+	if content == "" || senderID == receiverID || rating < 1 || rating > 5 {
+		return nil, apierror.BadRequest("Invalid request data")
 	}
 
-	if content == "" || senderID == receiverID || rating < 1 || rating > 5 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request data"})
+	// Blocked users look like they do not exist to each other
+	if blocked, err := s.store.Blocks().Between(senderID, receiverID); err != nil {
+		return nil, apierror.Internal("Could not create feedback")
+	} else if blocked {
+		return nil, apierror.NotFound("User not found")
 	}
 
 	feedback.SenderID = senderID
@@ -27,38 +37,65 @@ func AddFeedback(c *fiber.Ctx, senderID uint, receiverID uint, content string, r
 	feedback.Content = content
 	feedback.Rating = rating
 
-	if err := database.DB.Create(&feedback).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create feedback"})
+	if err := s.store.Feedback().Create(&feedback); err != nil {
+		return nil, apierror.Internal("Could not create feedback")
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"message":  "Feedback created successfully",
-		"feedback": feedback,
-	})
+	return &feedback, nil
 }
 
-func GetFeedbackByUserID(c *fiber.Ctx, userID uint) error {
-	var feedbacks []models.FeedbackSender
+// FeedbackList is the list spec of the feedback received by a user, by default the newest first.
+// It can be filtered on a `rating` from 1 to 5.
+var FeedbackList = utils.ListSpec{
+	Sorts: []utils.SortField{
+		{Name: "created", Column: "feedbacks.created_at", Kind: utils.SortTime, Desc: true},
+		{Name: "rating", Column: "feedbacks.rating", Kind: utils.SortInt, Desc: true},
+	},
+	Filters:  []string{"rating"},
+	IDColumn: "feedbacks.id",
+}
 
-	// Ensure database connection is established
-	if database.DB == nil {
-		log.Panic("Database not connected")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database not connected"})
+// List lists the feedback received by the given user, leaving out feedback from users blocked by or blocking the viewer.
+// Sender emails are only shown when the privacy settings of the sender allow the viewer to see them.
+// It returns the page of feedback and the cursor of the next page.
+func (s *FeedbackService) List(viewerID, userID uint, page utils.Page) ([]models.FeedbackWithSender, *string, error) {
+	var rating int
+	if value, ok := page.Filters["rating"]; ok {
+		var err error
+		rating, err = strconv.Atoi(value)
+		if err != nil || rating < 1 || rating > 5 {
+			return nil, nil, apierror.BadRequest("Rating must be between 1 and 5")
+		}
 	}
 
-	err := database.DB.Table(consts.FEEDBACK_TABLE).
-		Select("feedbacks.id as feedback_id, feedbacks.content, feedbacks.rating, feedbacks.sender_id, users.name as sender_name, users.email as sender_email, users.rating as sender_rating, feedbacks.created_at, feedbacks.updated_at").
-		Joins("JOIN users ON feedbacks.sender_id = users.id").
-		Where("feedbacks.receiver_id = ?", userID).
-		Scan(&feedbacks).Error
+	feedbacks, err := s.store.Feedback().ListReceived(viewerID, userID, rating, page)
+	if err != nil {
+		return nil, nil, listError(err, "Could not fetch feedback")
+	}
 
+	feedbacks, next := pageItems(page, feedbacks, func(feedback *models.FeedbackSender) (interface{}, uint) {
+		if page.Sort.Name == "rating" {
+			return feedback.Rating, feedback.FeedbackID
+		}
+		return feedback.CreatedAt, feedback.FeedbackID
+	})
+
+	senderIDs := make([]uint, 0, len(feedbacks))
+	for _, feedback := range feedbacks {
+		senderIDs = append(senderIDs, feedback.SenderID)
+	}
+	partners, err := acceptedPartners(s.store, viewerID, senderIDs)
 	if err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Feedback not found"})
+		return nil, nil, apierror.Internal("Could not fetch feedback")
 	}
 
-	var nestedFeedbacks []models.FeedbackWithSender
+	nestedFeedbacks := make([]models.FeedbackWithSender, 0, len(feedbacks))
 
 	for _, feedback := range feedbacks {
+		if !canSee(feedback.SenderEmailVisibility, feedback.SenderID == viewerID, partners[feedback.SenderID]) {
+			feedback.SenderEmail = ""
+		}
+
 		feedback := models.FeedbackWithSender{
 			FeedbackID: feedback.FeedbackID,
 			Content:    feedback.Content,
@@ -75,8 +112,5 @@ func GetFeedbackByUserID(c *fiber.Ctx, userID uint) error {
 		nestedFeedbacks = append(nestedFeedbacks, feedback)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"status":    "success",
-		"feedbacks": nestedFeedbacks,
-	})
+	return nestedFeedbacks, next, nil
 }
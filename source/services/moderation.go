@@ -0,0 +1,318 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/consts"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
+)
+
+// ModerationService handles user reports and the moderation cases they are grouped in.
+type ModerationService struct {
+	store repository.Store
+}
+
+func NewModerationService(store repository.Store) *ModerationService {
+	return &ModerationService{store: store}
+}
+
+var reportReasons = map[string]bool{
+	consts.REPORT_REASON_SPAM:           true,
+	consts.REPORT_REASON_HARASSMENT:     true,
+	consts.REPORT_REASON_HATE:           true,
+	consts.REPORT_REASON_VIOLENCE:       true,
+	consts.REPORT_REASON_NUDITY:         true,
+	consts.REPORT_REASON_SCAM:           true,
+	consts.REPORT_REASON_MISINFORMATION: true,
+	consts.REPORT_REASON_IMPERSONATION:  true,
+	consts.REPORT_REASON_OTHER:          true,
+}
+
+const maxReportDetails = 1000
+
+/*
+The Report method files a user's report on a post, comment, message, business page or user.
+
+Steps:
+ 1. Checks the target type and the reason category.
+ 2. Checks the item exists and was not written by the reporter. Messages can only be reported by their participants.
+ 3. Adds the report to the open moderation case of the item, opening a case if there is none.
+ 4. Refuses a second report from the same user on the same case, so one user cannot inflate the count.
+
+Returns:
+
+	An error if the report is not valid.
+*/
+func (s *ModerationService) Report(reporterID uint, targetType string, targetID uint, reason, details string) error {
+	if _, ok := repository.ReportTargets[targetType]; !ok {
+		return apierror.BadRequest("Invalid target type")
+	}
+
+	if !reportReasons[reason] {
+		return apierror.BadRequest("Invalid reason")
+	}
+
+	details = strings.TrimSpace(details)
+	if len(details) > maxReportDetails {
+		return apierror.BadRequest("Details are too long")
+	}
+
+	authorID, err := s.store.Moderation().Author(targetType, targetID, reporterID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apierror.NotFound("Reported item not found")
+	}
+	if err != nil {
+		return apierror.Internal("Database error")
+	}
+
+	if authorID == reporterID {
+		return apierror.BadRequest("You cannot report yourself or your own content")
+	}
+
+	err = s.store.Transaction(func(tx repository.Store) error {
+		caseID, err := tx.Moderation().OpenCase(targetType, targetID, authorID)
+		if err != nil {
+			return err
+		}
+
+		report := models.Report{
+			CaseID:     caseID,
+			ReporterID: reporterID,
+			Reason:     reason,
+			Details:    details,
+		}
+		return tx.Moderation().CreateReport(&report)
+	})
+	if errors.Is(err, repository.ErrDuplicate) {
+		return apierror.Conflict("You have already reported this")
+	}
+	if err != nil {
+		return apierror.Internal("Could not submit report")
+	}
+
+	return nil
+}
+
+/*
+The ModerationQueueList function returns the list spec of the moderation queue for the given case status.
+Open cases are listed by number of reports by default, resolved cases newest first.
+*/
+func ModerationQueueList(status string) utils.ListSpec {
+	created := utils.SortField{Name: "created", Column: "created_at", Kind: utils.SortTime}
+	reports := utils.SortField{Name: "reports", Column: "report_count", Kind: utils.SortInt, Desc: true}
+
+	spec := utils.ListSpec{
+		Sorts:    []utils.SortField{reports, created},
+		Filters:  []string{"status", "target_type"},
+		IDColumn: "id",
+	}
+	if status == consts.CASE_STATUS_RESOLVED {
+		resolved := utils.SortField{Name: "resolved", Column: "resolved_at", Kind: utils.SortTime, Desc: true}
+		spec.Sorts = []utils.SortField{resolved, reports, created}
+	}
+	return spec
+}
+
+// Queue lists moderation cases for moderators, filtered on status and target_type.
+// It returns the page of cases, the total count and the cursor of the next page.
+func (s *ModerationService) Queue(page utils.Page) ([]models.ModerationCase, int64, *string, error) {
+	filter := repository.CaseFilter{Status: page.Filters["status"], TargetType: page.Filters["target_type"]}
+	if filter.Status == "" {
+		filter.Status = consts.CASE_STATUS_OPEN
+	}
+	if filter.Status != consts.CASE_STATUS_OPEN && filter.Status != consts.CASE_STATUS_RESOLVED {
+		return nil, 0, nil, apierror.BadRequest("Invalid status")
+	}
+	if _, ok := repository.ReportTargets[filter.TargetType]; filter.TargetType != "" && !ok {
+		return nil, 0, nil, apierror.BadRequest("Invalid target type")
+	}
+
+	cases, total, err := s.store.Moderation().ListCases(filter, page)
+	if err != nil {
+		return nil, 0, nil, listError(err, "Failed to retrieve moderation queue")
+	}
+
+	cases, next := pageItems(page, cases, func(moderationCase *models.ModerationCase) (interface{}, uint) {
+		switch page.Sort.Name {
+		case "reports":
+			return moderationCase.ReportCount, moderationCase.ID
+		case "resolved":
+			return *moderationCase.ResolvedAt, moderationCase.ID
+		}
+		return moderationCase.CreatedAt, moderationCase.ID
+	})
+
+	return cases, total, next, nil
+}
+
+/*
+The Case method returns a case with its reports and the reported item as it is now.
+*/
+func (s *ModerationService) Case(caseID uint) (*models.ModerationCaseDetail, error) {
+	moderationCase, err := s.store.Moderation().GetCase(caseID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apierror.NotFound("Case not found")
+	}
+	if err != nil {
+		return nil, apierror.Internal("Database error")
+	}
+
+	reports, err := s.store.Moderation().Reports(moderationCase.ID)
+	if err != nil {
+		return nil, apierror.Internal("Database error")
+	}
+
+	// Users are shown through UserResponse so password hashes and secrets never reach the client
+	var content interface{}
+	if moderationCase.TargetType == consts.REPORT_TARGET_USER {
+		if user, err := s.store.Users().Get(moderationCase.TargetID); err == nil {
+			content = adminUserResponse(user)
+		}
+	} else {
+		if item, err := s.store.Moderation().Content(moderationCase.TargetType, moderationCase.TargetID); err == nil {
+			content = item
+		}
+	}
+
+	return &models.ModerationCaseDetail{
+		Case:    *moderationCase,
+		Reports: reports,
+		Content: content,
+	}, nil
+}
+
+/*
+The Dismiss method closes a case without acting on the reported item.
+*/
+func (s *ModerationService) Dismiss(ctx context.Context, actorID, caseID uint, note string) error {
+	return resolveCase(s.store, ctx, actorID, caseID, consts.CASE_RESOLUTION_DISMISSED, consts.AUDIT_CASE_DISMISS, note,
+		func(tx repository.Store, moderationCase *models.ModerationCase) error {
+			return nil
+		})
+}
+
+/*
+The Hide method hides the reported item and closes the case.
+Hidden items are left out of feeds and listings but kept for appeals. Users cannot be hidden, they are suspended instead.
+*/
+func (s *ModerationService) Hide(ctx context.Context, actorID, caseID uint, note string) error {
+	return resolveCase(s.store, ctx, actorID, caseID, consts.CASE_RESOLUTION_HIDDEN, consts.AUDIT_CASE_HIDE, note,
+		func(tx repository.Store, moderationCase *models.ModerationCase) error {
+			return hideTarget(tx, moderationCase)
+		})
+}
+
+/*
+The SuspendAuthor method escalates a case by suspending the author of the reported item, and closes the case.
+
+Steps:
+ 1. Requires a reason. Only admins may omit the end of the suspension, which bans the author.
+ 2. Checks the moderator outranks the author.
+ 3. Suspends the author and, if hideContent is set, also hides the reported item.
+*/
+func (s *ModerationService) SuspendAuthor(ctx context.Context, actorID uint, actorRole string, caseID uint, note, reason string, until *time.Time, hideContent bool) error {
+	reason = strings.TrimSpace(reason)
+	if reason == "" {
+		return apierror.BadRequest("A reason is required")
+	}
+
+	if until == nil && !utils.HasRole(actorRole, consts.ROLE_ADMIN) {
+		return apierror.Forbidden("Only admins can ban users")
+	}
+
+	if until != nil && !until.After(time.Now()) {
+		return apierror.BadRequest("Suspension end must be in the future")
+	}
+
+	return resolveCase(s.store, ctx, actorID, caseID, consts.CASE_RESOLUTION_SUSPENDED, consts.AUDIT_CASE_SUSPEND, note,
+		func(tx repository.Store, moderationCase *models.ModerationCase) error {
+			if moderationCase.AuthorID == nil {
+				return apierror.BadRequest("The author no longer exists")
+			}
+
+			author, err := tx.Users().Get(*moderationCase.AuthorID)
+			if err != nil {
+				return err
+			}
+
+			if !outranks(actorRole, author.Role) {
+				return apierror.Forbidden("You cannot suspend this user")
+			}
+
+			if hideContent && repository.ReportTargets[moderationCase.TargetType].Hideable {
+				if err := hideTarget(tx, moderationCase); err != nil {
+					return err
+				}
+			}
+
+			return suspendUser(tx, ctx, actorID, author, reason, until)
+		})
+}
+
+// resolveCase closes an open case with the given resolution after apply has acted on it,
+// and writes the audit entry, all in one transaction. apply may return an *apierror.Error to
+// reject the action with that error.
+func resolveCase(store repository.Store, ctx context.Context, actorID, caseID uint, resolution, auditAction, note string,
+	apply func(tx repository.Store, moderationCase *models.ModerationCase) error) error {
+	err := store.Transaction(func(tx repository.Store) error {
+		// Lock the case so two moderators cannot resolve it at the same time
+		moderationCase, err := tx.Moderation().LockCase(caseID)
+		if err != nil {
+			return err
+		}
+
+		if moderationCase.Status != consts.CASE_STATUS_OPEN {
+			return apierror.Conflict("Case is already resolved")
+		}
+
+		if err := apply(tx, moderationCase); err != nil {
+			return err
+		}
+
+		now := time.Now()
+		if err := tx.Moderation().UpdateCase(moderationCase.ID, map[string]interface{}{
+			"status":      consts.CASE_STATUS_RESOLVED,
+			"resolution":  resolution,
+			"note":        strings.TrimSpace(note),
+			"resolved_by": actorID,
+			"resolved_at": now,
+			"updated_at":  now,
+		}); err != nil {
+			return err
+		}
+
+		return recordAudit(tx, ctx, actorID, auditAction, consts.AUDIT_TARGET_CASE, moderationCase.ID, models.AuditDetails{
+			"target_type": moderationCase.TargetType,
+			"target_id":   moderationCase.TargetID,
+			"note":        strings.TrimSpace(note),
+		})
+	})
+
+	var apiErr *apierror.Error
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	if errors.Is(err, repository.ErrNotFound) {
+		return apierror.NotFound("Case not found")
+	}
+	if err != nil {
+		return apierror.Internal("Could not resolve case")
+	}
+
+	return nil
+}
+
+// hideTarget hides the reported item of a case.
+func hideTarget(tx repository.Store, moderationCase *models.ModerationCase) error {
+	if !repository.ReportTargets[moderationCase.TargetType].Hideable {
+		return apierror.BadRequest("This item cannot be hidden")
+	}
+
+	return tx.Moderation().Hide(moderationCase.TargetType, moderationCase.TargetID)
+}
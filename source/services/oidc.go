@@ -0,0 +1,209 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/lib"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
+)
+
+// OAuthStateTTL is how long a client has to come back from the provider.
+const OAuthStateTTL = 10 * time.Minute
+
+// OIDCService signs users in with external OpenID Connect providers.
+type OIDCService struct {
+	store repository.Store
+}
+
+func NewOIDCService(store repository.Store) *OIDCService {
+	return &OIDCService{store: store}
+}
+
+// OIDCLogin is a login started with a provider. The client opens the authorization URL
+// and comes back with the state.
+type OIDCLogin struct {
+	AuthorizationURL string `json:"authorization_url"`
+	State            string `json:"state"`
+}
+
+/*
+The Start method starts a login with an external OpenID Connect provider.
+
+Steps:
+ 1. Looks up the provider by name.
+ 2. Generates a random state, nonce and PKCE code verifier.
+ 3. Stores the state hash, verifier and nonce with a 10 minute expiry.
+ 4. Builds the provider authorization URL with the state, nonce and S256 code challenge.
+
+Returns:
+
+	The authorization URL the client must open, or an error if the provider is unknown.
+*/
+func (s *OIDCService) Start(providerName string) (*OIDCLogin, error) {
+	provider, err := lib.GetProvider(providerName)
+	if err != nil {
+		return nil, apierror.NotFound("Unknown login provider")
+	}
+
+	state, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, apierror.Internal("Could not start login")
+	}
+	nonce, err := utils.RandomToken(32)
+	if err != nil {
+		return nil, apierror.Internal("Could not start login")
+	}
+	verifier, err := utils.RandomToken(48)
+	if err != nil {
+		return nil, apierror.Internal("Could not start login")
+	}
+
+	authURL, err := provider.AuthCodeURL(state, nonce, utils.PKCEChallenge(verifier))
+	if err != nil {
+		log.Printf("Error building %s authorization URL: %v", providerName, err)
+		return nil, apierror.BadGateway("Login provider is unavailable")
+	}
+
+	oauthState := models.OAuthState{
+		StateHash:    utils.HashToken(state),
+		Provider:     providerName,
+		CodeVerifier: verifier,
+		Nonce:        nonce,
+		ExpiresAt:    time.Now().Add(OAuthStateTTL),
+	}
+	// Drop abandoned logins while we are here
+	if err := s.store.Identities().DeleteExpiredStates(); err != nil {
+		log.Printf("Error deleting expired login states: %v", err)
+	}
+
+	if err := s.store.Identities().CreateState(&oauthState); err != nil {
+		return nil, apierror.Internal("Could not start login")
+	}
+
+	return &OIDCLogin{AuthorizationURL: authURL, State: state}, nil
+}
+
+/*
+The Complete method finishes a login started by Start.
+
+Steps:
+ 1. Consumes the stored state, so a state can only be used once and only before it expires.
+ 2. Exchanges the code with the PKCE verifier and verifies the returned ID token.
+ 3. Finds the user linked to the provider account.
+ 4. Otherwise links the provider account to the user with the same email, if the provider verified that email.
+ 5. Otherwise creates a new verified user for the email.
+ 6. Starts a session for the user, or asks for the second factor if two-factor authentication is enabled.
+
+Returns:
+
+	The token pair and user, the same as a password login.
+*/
+func (s *OIDCService) Complete(ctx context.Context, providerName, code, state string) (*LoginResult, error) {
+	provider, err := lib.GetProvider(providerName)
+	if err != nil {
+		return nil, apierror.NotFound("Unknown login provider")
+	}
+
+	if code == "" || state == "" {
+		return nil, apierror.BadRequest("Missing code or state")
+	}
+
+	oauthState, err := s.store.Identities().TakeState(utils.HashToken(state), providerName)
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, apierror.BadRequest("Invalid or expired login state")
+	}
+	if err != nil {
+		return nil, apierror.Internal("Could not complete login")
+	}
+
+	identity, err := provider.Exchange(ctx, code, oauthState.CodeVerifier, oauthState.Nonce)
+	if err != nil {
+		log.Printf("Error completing %s login: %v", providerName, err)
+		return nil, apierror.Unauthorized("Could not verify login with provider")
+	}
+
+	user, err := s.findOrLinkUser(ctx, identity)
+	if errors.Is(err, errEmailNotVerified) {
+		return nil, apierror.Forbidden("Email is not verified by the login provider")
+	}
+	if err != nil {
+		return nil, apierror.Internal("Could not complete login")
+	}
+
+	return loginResult(s.store, ctx, user)
+}
+
+var errEmailNotVerified = errors.New("email not verified by provider")
+
+// findOrLinkUser returns the user for an external identity, linking or creating the user
+// when the identity is new. Accounts are only matched by email when the provider verified it,
+// otherwise anyone could take over an account by registering its email at a provider.
+func (s *OIDCService) findOrLinkUser(ctx context.Context, identity *lib.ExternalIdentity) (*models.User, error) {
+	linked, err := s.store.Identities().Find(identity.Provider, identity.Subject)
+	if err == nil {
+		return s.store.Users().Get(linked.UserID)
+	}
+	if !errors.Is(err, repository.ErrNotFound) {
+		return nil, err
+	}
+
+	email := utils.NormalizeEmail(identity.Email)
+	if !identity.EmailVerified || !utils.IsValidEmail(email) {
+		return nil, errEmailNotVerified
+	}
+
+	var user *models.User
+	err = s.store.Transaction(func(tx repository.Store) error {
+		var err error
+		user, err = tx.Users().GetByEmailFold(email)
+		if errors.Is(err, repository.ErrNotFound) {
+			// Accounts created through a provider have no password until the user sets one
+			// with the password reset flow
+			var username string
+			username, err = utils.GenerateUsername(email)
+			if err != nil {
+				return err
+			}
+			user = &models.User{
+				Name:       identity.Name,
+				Username:   username,
+				Email:      email,
+				Avatar:     identity.Picture,
+				Locale:     clientFrom(ctx).Locale,
+				IsVerified: true,
+			}
+			err = tx.Users().Create(user)
+		} else if err == nil && !user.IsVerified {
+			// The provider proved ownership of the email, which is what the signup OTP checks.
+			// The password of the unverified signup is dropped, since whoever chose it never proved
+			// they own the email and could otherwise log in to the account once it is verified
+			user.IsVerified = true
+			user.Password = ""
+			err = tx.Users().Update(user.ID, map[string]interface{}{
+				"is_verified": true,
+				"password":    "",
+			})
+		}
+		if err != nil {
+			return err
+		}
+
+		return tx.Identities().Create(&models.UserIdentity{
+			UserID:   user.ID,
+			Provider: identity.Provider,
+			Subject:  identity.Subject,
+			Email:    identity.Email,
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -1,124 +1,236 @@
 package services
 
 import (
-	"cnep-backend/pkg/utils"
-	"cnep-backend/source/database"
-	"cnep-backend/source/models"
+	"context"
+	"errors"
 	"log"
 	"time"
 
-	"github.com/gofiber/fiber/v2"
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/consts"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/config"
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
+)
+
+var (
+	errInvalidOTP   = apierror.BadRequest("Invalid or expired OTP")
+	errOTPThrottled = apierror.TooManyRequests("Please wait before requesting another code")
 )
 
 /*
-The OTPRegenerate function is a handler function that regenerates the OTP for a user.
+The RegenerateOTP method regenerates the OTP for a user.
 Here's a breakdown of what it does:
 
 Steps:
- 1. Retrieves the email parameter from the request body.
- 2. Checks if the email parameter is empty or not.
- 3. Checks if the email parameter is a valid email format.
- 4. Retrieves the user with the given email from the database.
- 5. Checks if the user exists in the database.
- 6. Generates a new OTP for the user.
- 7. Updates the user's OTP and OTP expiry time in the database.
- 8. Sends an email with the new OTP to the user.
- 9. Returns a JSON response with a success message.
+ 1. Checks if the email parameter is a valid email format.
+ 2. Retrieves the unverified user with the given email from the database.
+ 3. Counts the email against the resend cooldown and the daily limit of OTP emails for the user.
+ 4. Generates a new OTP for the user, replacing the previous one.
+ 5. Sends an email with the new OTP to the user.
 
 Returns:
 
-	A JSON response with a success message if the OTP is regenerated, or an error message if the OTP cannot be regenerated.
+	No error whether or not an OTP was sent, so it cannot be used to find out
+	which emails have an account. Only an invalid email format is reported.
 */
-func OTPRegenerate(c *fiber.Ctx, email string) error {
-	var user models.User
-
-	// Ensure database connection is established
-	if database.DB == nil {
-		log.Panic("Database not connected")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database not connected"})
-	}
-
+func (s *AuthService) RegenerateOTP(email string) error {
+	email = utils.NormalizeEmail(email)
 	if !utils.IsValidEmail(email) {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid email format"})
+		return apierror.BadRequest("Invalid email format")
 	}
 
-	if err := database.DB.Where("email = ?", email).First(&user).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	user, err := s.store.Users().GetByEmail(email)
+	if err != nil {
+		return nil
 	}
 
-	otp := utils.GenerateOTP()
-	otpExpiry := time.Now().Add(15 * time.Minute)
+	if user.IsVerified {
+		return nil
+	}
 
-	user.OTP = otp
-	user.OTPExpiry = otpExpiry
+	var otp string
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := claimOTPSend(tx, user.ID, otpResendCooldown()); err != nil {
+			return err
+		}
 
-	if err := database.DB.Save(user).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update OTP"})
+		var err error
+		otp, err = issueCode(tx, user.ID, consts.OTP_PURPOSE_SIGNUP, "")
+		return err
+	})
+	if err == errOTPThrottled {
+		return nil
+	}
+	if err != nil {
+		return apierror.Internal("Could not update OTP")
 	}
 
-	if err := utils.SendOTPEmail(user.Email, otp); err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not send OTP email"})
+	if err := utils.SendOTPEmail(user.Email, otp, consts.OTP_PURPOSE_SIGNUP, user.Locale); err != nil {
+		log.Printf("Error sending OTP email to user %d: %v", user.ID, err)
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "New OTP sent. Please verify your email with the new OTP.",
-	})
+	return nil
 }
 
 /*
-The ValidateOTP function is a handler function that verifies the OTP sent to the user's email.
+The ValidateOTP method verifies the OTP sent to the user's email.
 Here's a breakdown of what it does:
 
 Steps:
 
- 1. Retrieves the email and OTP parameters from the request body.
- 2. Checks if the email and OTP parameters are valid email and OTP formats.
- 3. Retrieves the user with the given email from the database.
- 4. Checks if the user exists in the database.
- 5. Checks if the OTP is valid for the user.
- 6. If the OTP is valid, it updates the user's OTP and OTP expiry time in the database.
- 7. If the OTP is not valid, it returns a JSON response with an error message.
+ 1. Checks if the email and OTP parameters are valid email and OTP formats.
+ 2. Retrieves the user with the given email from the database.
+ 3. Checks that the user exists and is not verified yet.
+ 4. Checks if the OTP is valid for the user. Every attempt is counted on the code, which
+    stops accepting guesses once OTP_MAX_ATTEMPTS is reached, and wrong codes are counted
+    on the user, locking its codes for OTP_LOCK_MINUTES after OTP_MAX_FAILURES of them.
+ 5. If the OTP is valid, it marks the user as verified and the OTP as used.
+ 6. Starts a session for the user, unless the account is suspended.
 
 Returns:
 
-	A JSON response with a success message if the OTP is verified, or an error message if the OTP is invalid or expired.
+	The tokens of the new session, or an error if the OTP is invalid or expired.
 */
-func ValidateOTP(c *fiber.Ctx, otp string, email string) error {
-	var user models.User
+func (s *AuthService) ValidateOTP(ctx context.Context, otp string, email string) (*LoginResult, error) {
+	email = utils.NormalizeEmail(email)
+	if !utils.IsValidEmail(email) || len(otp) != 8 {
+		return nil, apierror.BadRequest("Invalid email or OTP format")
+	}
 
-	// Ensure database connection is established
-	if database.DB == nil {
-		log.Panic("Database not connected")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database not connected"})
+	// Unknown and verified users get the same answer as a wrong code, so the endpoint tells
+	// nothing about which emails have an account
+	user, err := s.store.Users().GetByEmail(email)
+	if err != nil || user.IsVerified {
+		return nil, errInvalidOTP
 	}
 
-	if !utils.IsValidEmail(email) || len(otp) != 8 {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid email or OTP format"})
+	code, ok := verifyCode(s.store, user, consts.OTP_PURPOSE_SIGNUP, otp)
+	if !ok {
+		return nil, errInvalidOTP
 	}
 
-	if err := database.DB.Where("email = ?", email).First(&user).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Codes().Use(code.ID); err != nil {
+			return err
+		}
+		return tx.Users().Update(user.ID, map[string]interface{}{"is_verified": true})
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return nil, errInvalidOTP
+	}
+	if err != nil {
+		return nil, apierror.Internal("Could not verify user")
+	}
+
+	if isSuspended(user) {
+		return nil, suspendedError(user)
 	}
 
-	if user.OTP != otp || time.Now().After(user.OTPExpiry) {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid or expired OTP"})
+	tokens, err := createSession(s.store, ctx, user.ID)
+	if err != nil {
+		return nil, apierror.Internal("Could not generate token")
 	}
 
-	user.IsVerified = true
-	user.OTP = ""
-	user.OTPExpiry = time.Time{}
+	return &LoginResult{
+		Message:      "Email verified successfully",
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+	}, nil
+}
+
+// otpTTL is how long an emailed code stays valid.
+const otpTTL = 15 * time.Minute
 
-	if err := database.DB.Save(&user).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not verify user"})
+// issueCode replaces any unused code of the purpose with a new one and returns the plaintext
+// code to be emailed. Only its hash is stored.
+func issueCode(tx repository.Store, userID uint, purpose, target string) (string, error) {
+	if err := tx.Codes().DeleteUnused(userID, purpose); err != nil {
+		return "", err
 	}
 
-	token, err := utils.GenerateJWT(user.ID)
+	otp := utils.GenerateOTP()
+	code := models.OneTimeCode{
+		UserID:    userID,
+		Purpose:   purpose,
+		CodeHash:  utils.HashOTP(otp),
+		Target:    target,
+		ExpiresAt: time.Now().Add(otpTTL),
+	}
+	if err := tx.Codes().Create(&code); err != nil {
+		return "", err
+	}
+
+	return otp, nil
+}
+
+// verifyCode checks otp against the active code of the purpose in constant time.
+// Every attempt is counted on the code, which stops accepting guesses after OTP_MAX_ATTEMPTS.
+// Wrong codes are also counted on the user, across resends, and OTP_MAX_FAILURES of them lock
+// the emailed codes of the user for OTP_LOCK_MINUTES. The lock does not stop password logins.
+// Redeem the returned code with Codes().Use, which fails if it was used in the meantime.
+func verifyCode(store repository.Store, user *models.User, purpose, otp string) (*models.OneTimeCode, bool) {
+	if isOTPLocked(user) {
+		return nil, false
+	}
+
+	code, err := store.Codes().Active(user.ID, purpose)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not generate token"})
+		return nil, false
 	}
 
-	return c.JSON(fiber.Map{
-		"message": "Email verified successfully",
-		"token":   token,
-	})
+	cfg := config.New()
+	allowed, err := store.Codes().Attempt(code.ID, cfg.OTPMaxAttempts)
+	if err != nil {
+		log.Printf("Error counting OTP attempt for user %d: %v", user.ID, err)
+		return nil, false
+	}
+	if !allowed {
+		return nil, false
+	}
+
+	if !utils.CheckOTPHash(otp, code.CodeHash) {
+		lockUntil := time.Now().Add(time.Duration(cfg.OTPLockMinutes) * time.Minute)
+		if err := store.Users().RecordOTPFailure(user.ID, cfg.OTPMaxFailures, lockUntil); err != nil {
+			log.Printf("Error recording failed OTP attempt for user %d: %v", user.ID, err)
+		}
+		return nil, false
+	}
+
+	if user.OTPCodeFailures > 0 || user.OTPLockedUntil != nil {
+		if err := store.Users().Update(user.ID, map[string]interface{}{
+			"otp_code_failures": 0,
+			"otp_locked_until":  nil,
+		}); err != nil {
+			log.Printf("Error resetting failed OTP attempts of user %d: %v", user.ID, err)
+		}
+	}
+
+	return code, true
+}
+
+// isOTPLocked reports whether the emailed codes of the user are locked after too many wrong ones.
+func isOTPLocked(user *models.User) bool {
+	return user.OTPLockedUntil != nil && time.Now().Before(*user.OTPLockedUntil)
+}
+
+// otpResendCooldown is the OTP_RESEND_COOLDOWN to wait between two OTP emails to a user.
+func otpResendCooldown() time.Duration {
+	return time.Duration(config.New().OTPResendCooldown) * time.Second
+}
+
+// claimOTPSend counts an OTP email to the user, enforcing the cooldown between emails and
+// at most OTP_DAILY_LIMIT emails a day. The limits are checked and counted in one statement,
+// so concurrent requests cannot all slip past them. It returns errOTPThrottled when the email must not be sent.
+func claimOTPSend(tx repository.Store, userID uint, cooldown time.Duration) error {
+	claimed, err := tx.Users().ClaimOTPSend(userID, time.Now(), cooldown, config.New().OTPDailyLimit)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		return errOTPThrottled
+	}
+	return nil
 }
@@ -0,0 +1,200 @@
+package services_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"cnep-backend/source/apitest"
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository/repotest"
+	"cnep-backend/source/services"
+)
+
+// createUnverified stores an unverified user and emails it a signup code.
+func createUnverified(t *testing.T, svc *services.Services, store *repotest.Store, email string) *models.User {
+	t.Helper()
+
+	user := createUser(t, store, email)
+	if err := store.Users().Update(user.ID, map[string]interface{}{"is_verified": false}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.Auth.RegenerateOTP(email); err != nil {
+		t.Fatal(err)
+	}
+	return user
+}
+
+func TestValidateOTP(t *testing.T) {
+	tests := []struct {
+		name   string
+		email  string
+		otp    func(code string) string
+		status int
+	}{
+		{"right code", "new@example.com", func(code string) string { return code }, 0},
+		{"right code, email in another case", "NEW@example.com", func(code string) string { return code }, 0},
+		{"wrong code", "new@example.com", func(string) string { return "AAAA0000" }, http.StatusBadRequest},
+		{"malformed code", "new@example.com", func(string) string { return "short" }, http.StatusBadRequest},
+		{"code of another user", "user@example.com", func(code string) string { return code }, http.StatusBadRequest},
+		{"unknown email", "unknown@example.com", func(code string) string { return code }, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, store, mail := newServices(t)
+			createUser(t, store, "user@example.com")
+			user := createUnverified(t, svc, store, "new@example.com")
+
+			result, err := svc.Auth.ValidateOTP(context.Background(), tt.otp(mail.OTP(t, "new@example.com")), tt.email)
+			expectStatus(t, err, tt.status)
+
+			stored, _ := store.User(user.ID)
+			if stored.IsVerified != (tt.status == 0) {
+				t.Fatalf("expected the user to be verified only with the right code, got %v", stored.IsVerified)
+			}
+			if tt.status == 0 && (result.Token == "" || result.RefreshToken == "") {
+				t.Fatalf("expected the verification to start a session, got %+v", result)
+			}
+		})
+	}
+}
+
+func TestValidateOTPUsesCodeOnce(t *testing.T) {
+	svc, store, mail := newServices(t)
+	createUnverified(t, svc, store, "new@example.com")
+	code := mail.OTP(t, "new@example.com")
+
+	_, err := svc.Auth.ValidateOTP(context.Background(), code, "new@example.com")
+	expectStatus(t, err, 0)
+	_, err = svc.Auth.ValidateOTP(context.Background(), code, "new@example.com")
+	expectStatus(t, err, http.StatusBadRequest)
+}
+
+func TestValidateOTPLimitsGuessesPerCode(t *testing.T) {
+	t.Setenv("OTP_MAX_ATTEMPTS", "2")
+	t.Setenv("OTP_RESEND_COOLDOWN", "0")
+
+	svc, store, mail := newServices(t)
+	user := createUnverified(t, svc, store, "new@example.com")
+
+	for i := 0; i < 2; i++ {
+		_, err := svc.Auth.ValidateOTP(context.Background(), "AAAA0000", "new@example.com")
+		expectStatus(t, err, http.StatusBadRequest)
+	}
+
+	// The code took its guesses, even the right one is refused now
+	_, err := svc.Auth.ValidateOTP(context.Background(), mail.OTP(t, "new@example.com"), "new@example.com")
+	expectStatus(t, err, http.StatusBadRequest)
+
+	// Only the code is spent, two wrong codes do not lock the account and a new code works
+	if stored, _ := store.User(user.ID); stored.LockedUntil != nil || stored.OTPLockedUntil != nil {
+		t.Fatalf("expected wrong codes not to lock the account, got %+v", stored)
+	}
+	if err := svc.Auth.RegenerateOTP("new@example.com"); err != nil {
+		t.Fatal(err)
+	}
+	_, err = svc.Auth.ValidateOTP(context.Background(), mail.OTP(t, "new@example.com"), "new@example.com")
+	expectStatus(t, err, 0)
+}
+
+func TestValidateOTPLockHoldsAcrossResends(t *testing.T) {
+	t.Setenv("OTP_MAX_ATTEMPTS", "3")
+	t.Setenv("OTP_MAX_FAILURES", "4")
+	t.Setenv("OTP_RESEND_COOLDOWN", "0")
+
+	svc, store, mail := newServices(t)
+	user := createUnverified(t, svc, store, "new@example.com")
+
+	// Two wrong guesses on each of two codes, so no code runs out of guesses on its own
+	for range 2 {
+		for range 2 {
+			_, err := svc.Auth.ValidateOTP(context.Background(), "AAAA0000", "new@example.com")
+			expectStatus(t, err, http.StatusBadRequest)
+		}
+		if err := svc.Auth.RegenerateOTP("new@example.com"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	stored, _ := store.User(user.ID)
+	if stored.OTPLockedUntil == nil {
+		t.Fatalf("expected four wrong codes to lock the codes of the user, got %+v", stored)
+	}
+
+	// The code sent after the lock is refused too
+	_, err := svc.Auth.ValidateOTP(context.Background(), mail.OTP(t, "new@example.com"), "new@example.com")
+	expectStatus(t, err, http.StatusBadRequest)
+	if stored, _ := store.User(user.ID); stored.IsVerified {
+		t.Fatal("expected a locked user not to be verified")
+	}
+
+	// Once the lock is over the code works and the count starts over
+	if err := store.Users().Update(user.ID, map[string]interface{}{"otp_locked_until": time.Now().Add(-time.Minute)}); err != nil {
+		t.Fatal(err)
+	}
+	_, err = svc.Auth.ValidateOTP(context.Background(), mail.OTP(t, "new@example.com"), "new@example.com")
+	expectStatus(t, err, 0)
+	if stored, _ := store.User(user.ID); stored.OTPCodeFailures != 0 || stored.OTPLockedUntil != nil {
+		t.Fatalf("expected the right code to reset the failures, got %+v", stored)
+	}
+}
+
+func TestValidateOTPCannotLockOthersOut(t *testing.T) {
+	t.Setenv("OTP_MAX_ATTEMPTS", "2")
+
+	svc, store, _ := newServices(t)
+	user := createUser(t, store, "user@example.com")
+
+	for i := 0; i < 5; i++ {
+		_, err := svc.Auth.ValidateOTP(context.Background(), "AAAA0000", "user@example.com")
+		expectStatus(t, err, http.StatusBadRequest)
+	}
+
+	if stored, _ := store.User(user.ID); stored.LockedUntil != nil || stored.OTPFailedAttempts != 0 {
+		t.Fatalf("expected guesses for a verified user not to be counted, got %+v", stored)
+	}
+	_, err := svc.Auth.Login(context.Background(), "user@example.com", apitest.Password)
+	expectStatus(t, err, 0)
+}
+
+func TestRegenerateOTP(t *testing.T) {
+	tests := []struct {
+		name       string
+		cooldown   string
+		dailyLimit string
+		email      string
+		// emails expected after the signup email and one resend
+		sent int
+	}{
+		{"resend after the cooldown", "0", "10", "new@example.com", 2},
+		{"resend during the cooldown", "60", "10", "new@example.com", 1},
+		{"resend over the daily limit", "0", "1", "new@example.com", 1},
+		{"verified user", "0", "10", "user@example.com", 0},
+		{"unknown email", "0", "10", "unknown@example.com", 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("OTP_RESEND_COOLDOWN", tt.cooldown)
+			t.Setenv("OTP_DAILY_LIMIT", tt.dailyLimit)
+
+			svc, store, mail := newServices(t)
+			createUser(t, store, "user@example.com")
+			createUnverified(t, svc, store, "new@example.com")
+
+			// Throttled, unknown and verified emails get the same answer as a sent code
+			expectStatus(t, svc.Auth.RegenerateOTP(tt.email), 0)
+
+			if sent := len(mail.To(tt.email)); sent != tt.sent {
+				t.Fatalf("expected %d emails to %s, got %d", tt.sent, tt.email, sent)
+			}
+		})
+	}
+
+	t.Run("invalid email", func(t *testing.T) {
+		svc, _, _ := newServices(t)
+		expectStatus(t, svc.Auth.RegenerateOTP("not-an-email"), http.StatusBadRequest)
+	})
+}
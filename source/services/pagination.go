@@ -0,0 +1,32 @@
+package services
+
+import (
+	"errors"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/utils"
+)
+
+// pageItems trims the extra item fetched by a repository list and returns the cursor of the next page,
+// or nil on the last page. key returns the sort value and the ID of an item.
+func pageItems[T any](page utils.Page, items []T, key func(item *T) (interface{}, uint)) ([]T, *string) {
+	if items == nil {
+		items = []T{}
+	}
+	if len(items) <= page.Limit {
+		return items, nil
+	}
+
+	items = items[:page.Limit]
+	cursor := page.NextCursor(key(&items[page.Limit-1]))
+	return items, &cursor
+}
+
+// listError maps the error of a repository list to an API error. An invalid cursor is the
+// client's fault, any other error is reported with message.
+func listError(err error, message string) error {
+	if errors.Is(err, utils.ErrInvalidCursor) {
+		return apierror.BadRequest("Invalid cursor")
+	}
+	return apierror.Internal(message)
+}
@@ -1,182 +1,306 @@
 package services
 
 import (
+	"cnep-backend/pkg/apierror"
 	"cnep-backend/pkg/consts"
-	"cnep-backend/source/database"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/config"
 	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
 
-	"github.com/gofiber/fiber/v2"
-	"log"
+	"errors"
+	"time"
 )
 
-func AddPartner(c *fiber.Ctx, senderID, receiverID uint) error {
-	var partner models.Partner
+// PartnerService manages partner requests and partnerships, and suggests new partners.
+type PartnerService struct {
+	store repository.Store
+}
 
-	// Ensure database connection is established
-	if database.DB == nil {
-		log.Panic("Database not connected")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database not connected"})
-	}
+func NewPartnerService(store repository.Store) *PartnerService {
+	return &PartnerService{store: store}
+}
 
+var (
+	errAlreadyPartners    = apierror.Conflict("You are already partners")
+	errPartnerRequestSent = apierror.Conflict("Partner request already sent")
+	errPartnerCooldown    = apierror.TooManyRequests("Please wait before sending another partner request")
+)
+
+/*
+The Request method sends a partner request from the sender to the receiver.
+Here's a breakdown of what it does:
+
+Steps:
+ 1. Checks the receiver exists and neither user has blocked the other.
+ 2. Locks the partner row of the pair, a pair of users has at most one row whichever of them sent the request.
+ 3. When the receiver already sent a pending request to the sender, that request is accepted instead.
+ 4. When the receiver declined an earlier request of the sender, the request can only be sent again
+    after PARTNER_REQUEST_COOLDOWN days. A user who declined a request can send their own request at any time.
+ 5. Otherwise creates the pending request, or turns the declined row into a new pending request.
+
+Returns:
+
+	Whether a reverse request was accepted rather than a new request created.
+	A conflict error when the users are already partners or the request was already sent,
+	and a too many requests error with retry_after in seconds while the cooldown runs.
+*/
+func (s *PartnerService) Request(senderID, receiverID uint) (bool, error) {
 	if senderID == receiverID {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid request data"})
+		return false, apierror.BadRequest("Invalid request data")
 	}
 
-	partner.SenderID = senderID
-	partner.ReceiverID = receiverID
-	partner.Status = consts.PARTNER_STATUS_PENDING
+	exists, err := s.store.Users().Exists(receiverID)
+	if err != nil {
+		return false, apierror.Internal("Could not create partner")
+	}
 
-	if err := database.DB.Table(consts.PARTNERS_TABLE).Create(&partner).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not create partner"})
+	// Blocked users look like they do not exist to each other
+	blocked, err := s.store.Blocks().Between(senderID, receiverID)
+	if err != nil {
+		return false, apierror.Internal("Could not create partner")
+	}
+	if !exists || blocked {
+		return false, apierror.NotFound("User not found")
 	}
 
-	return c.Status(fiber.StatusCreated).JSON(fiber.Map{
-		"status":  "ok",
-		"message": "Partner Request Created",
-	})
-}
+	var accepted bool
+	var retryAfter time.Duration
+	err = s.store.Transaction(func(tx repository.Store) error {
+		partner, err := tx.Partners().LockPair(senderID, receiverID)
+		if errors.Is(err, repository.ErrNotFound) {
+			return tx.Partners().Create(&models.Partner{
+				SenderID:   senderID,
+				ReceiverID: receiverID,
+				Status:     consts.PARTNER_STATUS_PENDING,
+			})
+		}
+		if err != nil {
+			return err
+		}
 
-func UpdatePartnerStatus(c *fiber.Ctx, userID, partnerID uint, accepted bool) error {
-	var partner models.Partner
+		switch partner.Status {
+		case consts.PARTNER_STATUS_ACCEPTED:
+			return errAlreadyPartners
+		case consts.PARTNER_STATUS_PENDING:
+			if partner.SenderID == senderID {
+				return errPartnerRequestSent
+			}
+			// The receiver asked first, so both users want to be partners
+			accepted = true
+			return tx.Partners().Update(partner.ID, map[string]interface{}{
+				"status":     consts.PARTNER_STATUS_ACCEPTED,
+				"updated_at": time.Now(),
+			})
+		}
 
-	// Ensure database connection is established
-	if database.DB == nil {
-		log.Panic("Database not connected")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database not connected"})
-	}
+		if retryAfter = partnerCooldown(partner, senderID); retryAfter > 0 {
+			return errPartnerCooldown
+		}
 
-	if err := database.DB.Table(consts.PARTNERS_TABLE).
-		Where("receiver_id = ? AND sender_id = ?", userID, partnerID).
-		First(&partner).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User or Partner not found"})
+		now := time.Now()
+		return tx.Partners().Update(partner.ID, map[string]interface{}{
+			"sender_id":   senderID,
+			"receiver_id": receiverID,
+			"status":      consts.PARTNER_STATUS_PENDING,
+			"sent_at":     now,
+			"updated_at":  now,
+		})
+	})
+	if err == errPartnerCooldown {
+		return false, errPartnerCooldown.WithDetails(map[string]interface{}{
+			"retry_after": int(retryAfter.Seconds()),
+		})
+	}
+	if e, ok := err.(*apierror.Error); ok {
+		return false, e
+	}
+	if err != nil {
+		// A concurrent request for the same pair won the race
+		if errors.Is(err, repository.ErrDuplicate) {
+			return false, errPartnerRequestSent
+		}
+		return false, apierror.Internal("Could not create partner")
 	}
 
+	return accepted, nil
+}
+
+// Respond accepts or declines the pending partner request sent to the user by the partner.
+func (s *PartnerService) Respond(userID, partnerID uint, accepted bool) error {
+	status := consts.PARTNER_STATUS_DECLINED
 	if accepted {
-		partner.Status = consts.PARTNER_STATUS_ACCEPTED
-	} else {
-		partner.Status = consts.PARTNER_STATUS_DECLINED
+		status = consts.PARTNER_STATUS_ACCEPTED
 	}
 
-	if err := database.DB.Table(consts.PARTNERS_TABLE).Save(&partner).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update partner status"})
+	err := s.store.Partners().Respond(partnerID, userID, status)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apierror.NotFound("Partner request not found")
+	}
+	if err != nil {
+		return apierror.Internal("Could not update partner status")
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"status":  "ok",
-		"message": "Partner status updated",
-	})
+	return nil
 }
 
-func CancelPartnerRequest(c *fiber.Ctx, userID, partnerID uint) error {
-	var partner models.Partner
-
-	// Ensure database connection is established
-	if database.DB == nil {
-		log.Panic("Database not connected")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database not connected"})
+// Cancel withdraws a pending partner request the user sent to the partner.
+func (s *PartnerService) Cancel(userID, partnerID uint) error {
+	err := s.store.Partners().DeletePending(userID, partnerID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apierror.NotFound("Partner request does not exist")
 	}
-
-	if err := database.DB.Table(consts.PARTNERS_TABLE).
-		Where("receiver_id = ? AND sender_id = ?", partnerID, userID).
-		First(&partner).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Partner request does not exist"})
+	if err != nil {
+		return apierror.Internal("Could not cancel partner request")
 	}
 
-	if err := database.DB.Table(consts.PARTNERS_TABLE).Delete(&partner).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not cancel partner request"})
+	return nil
+}
+
+// Remove ends the partnership between the user and the partner, whichever of them sent the request.
+func (s *PartnerService) Remove(userID, partnerID uint) error {
+	err := s.store.Partners().DeleteAccepted(userID, partnerID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return apierror.NotFound("Partner not found")
+	}
+	if err != nil {
+		return apierror.Internal("Could not remove partner")
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"status":  "ok",
-		"message": "Partner request cancelled",
-	})
+	return nil
 }
 
-func GetPartners(c *fiber.Ctx, userID uint) error {
-	var partners []models.Partner
-	var ids []uint
-	var users []models.UserResponse
+/*
+The Status method returns the relationship between the user and another user.
+
+Returns:
 
-	// Ensure database connection is established
-	if database.DB == nil {
-		log.Panic("Database not connected")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database not connected"})
+	relationship is none, partners, request_sent, request_received or declined,
+	declined meaning the other user declined the last request of the user.
+	can_request tells whether the user can send a partner request now,
+	and retry_after gives the seconds left of the cooldown after a decline.
+	Blocked and unknown users are not found.
+*/
+func (s *PartnerService) Status(userID, otherID uint) (*models.PartnerRelationship, error) {
+	if userID == otherID {
+		return nil, apierror.BadRequest("Invalid request data")
 	}
 
-	if err := database.DB.Table(consts.PARTNERS_TABLE).
-		Where("receiver_id = ? OR sender_id = ? AND status = ?", userID, userID, consts.PARTNER_STATUS_ACCEPTED).
-		Find(&partners).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "Partner request not found"})
+	exists, err := s.store.Users().Exists(otherID)
+	if err != nil {
+		return nil, apierror.Internal("Database error")
 	}
+	blocked, err := s.store.Blocks().Between(userID, otherID)
+	if err != nil {
+		return nil, apierror.Internal("Database error")
+	}
+	if !exists || blocked {
+		return nil, apierror.NotFound("User not found")
+	}
+
+	relationship := consts.PARTNER_RELATION_NONE
+	var retryAfter time.Duration
 
-	for _, partner := range partners {
-		if userID == partner.SenderID {
-			ids = append(ids, partner.ReceiverID)
-		} else {
-			ids = append(ids, partner.SenderID)
+	partner, err := s.store.Partners().FindPair(userID, otherID)
+	if err != nil && !errors.Is(err, repository.ErrNotFound) {
+		return nil, apierror.Internal("Database error")
+	}
+	if err == nil {
+		switch {
+		case partner.Status == consts.PARTNER_STATUS_ACCEPTED:
+			relationship = consts.PARTNER_RELATION_PARTNERS
+		case partner.Status == consts.PARTNER_STATUS_PENDING && partner.SenderID == userID:
+			relationship = consts.PARTNER_RELATION_REQUEST_SENT
+		case partner.Status == consts.PARTNER_STATUS_PENDING:
+			relationship = consts.PARTNER_RELATION_REQUEST_RECEIVED
+		case partner.SenderID == userID:
+			relationship = consts.PARTNER_RELATION_DECLINED
+			retryAfter = partnerCooldown(partner, userID)
 		}
 	}
 
-	// If no partners found, return an empty array
-	if len(ids) == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"status":   "empty array",
-			"partners": users,
-		})
+	return &models.PartnerRelationship{
+		Relationship: relationship,
+		CanRequest: relationship == consts.PARTNER_RELATION_NONE ||
+			relationship == consts.PARTNER_RELATION_REQUEST_RECEIVED ||
+			(relationship == consts.PARTNER_RELATION_DECLINED && retryAfter == 0),
+		RetryAfter: int(retryAfter.Seconds()),
+	}, nil
+}
+
+// partnerCooldown returns how long the user must wait before sending a new request
+// after their request was declined, or zero when they can send one now.
+func partnerCooldown(partner *models.Partner, userID uint) time.Duration {
+	if partner.Status != consts.PARTNER_STATUS_DECLINED || partner.SenderID != userID {
+		return 0
 	}
 
-	// Retrieve user information for the collected IDs
-	if err := database.DB.Table("users").Where("id IN ?", ids).Find(&users).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).
-			JSON(fiber.Map{"error": "Failed to retrieve user information"})
+	cooldown := time.Duration(config.New().PartnerRequestCooldown) * 24 * time.Hour
+	if left := time.Until(partner.UpdatedAt.Add(cooldown)); left > 0 {
+		return left
 	}
+	return 0
+}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"status":   "ok",
-		"partners": users,
-	})
+// PartnersList is the list spec of the partners of a user, by default the newest partnerships first.
+var PartnersList = utils.ListSpec{
+	Sorts: []utils.SortField{
+		{Name: "since", Column: "partners.updated_at", Kind: utils.SortTime, Desc: true},
+		{Name: "name", Column: "users.name", Kind: utils.SortString},
+	},
+	Filters:  []string{"q"},
+	IDColumn: "users.id",
 }
 
-func GetPendingPartners(c *fiber.Ctx, userID uint) error {
-	var partners []models.Partner
-	var ids []uint
-	var users []models.UserResponse
+// PartnerRequestsList is the list spec of incoming and outgoing partner requests, by default the newest requests first.
+var PartnerRequestsList = utils.ListSpec{
+	Sorts: []utils.SortField{
+		{Name: "since", Column: "partners.sent_at", Kind: utils.SortTime, Desc: true},
+		{Name: "name", Column: "users.name", Kind: utils.SortString},
+	},
+	Filters:  []string{"q"},
+	IDColumn: "users.id",
+}
 
-	// Ensure database connection is established
-	if database.DB == nil {
-		log.Panic("Database not connected")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database not connected"})
-	}
+// List lists the accepted partners of the user.
+func (s *PartnerService) List(userID uint, page utils.Page) ([]models.PartnerUser, *string, error) {
+	users, err := s.store.Partners().ListAccepted(userID, page.Filters["q"], page)
+	return s.partnerList(userID, page, users, err)
+}
 
-	if err := database.DB.Table(consts.PARTNERS_TABLE).
-		Where("receiver_id = ? AND status = ?", userID, consts.PARTNER_STATUS_PENDING).
-		Find(&partners).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"status":   "No pending partners found",
-			"partners": partners,
-		})
-	}
+// ListIncoming lists the users who sent the user a partner request that is still pending.
+func (s *PartnerService) ListIncoming(userID uint, page utils.Page) ([]models.PartnerUser, *string, error) {
+	users, err := s.store.Partners().ListIncoming(userID, page.Filters["q"], page)
+	return s.partnerList(userID, page, users, err)
+}
 
-	for _, partner := range partners {
-		ids = append(ids, partner.SenderID)
-	}
+// ListOutgoing lists the users the user sent a partner request to that are still pending.
+func (s *PartnerService) ListOutgoing(userID uint, page utils.Page) ([]models.PartnerUser, *string, error) {
+	users, err := s.store.Partners().ListOutgoing(userID, page.Filters["q"], page)
+	return s.partnerList(userID, page, users, err)
+}
 
-	// If no partners found, return an empty array
-	if len(ids) == 0 {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{
-			"status":   "empty array",
-			"partners": users,
-		})
+// partnerList finishes a page of a partner list, filtering the profiles by the privacy settings
+// of each user. It returns the page of users and the cursor of the next page.
+func (s *PartnerService) partnerList(viewerID uint, page utils.Page, users []models.PartnerUser, err error) ([]models.PartnerUser, *string, error) {
+	if err != nil {
+		return nil, nil, listError(err, "Failed to retrieve user information")
 	}
 
-	// Retrieve user information for the collected IDs
-	if err := database.DB.Table("users").Where("id IN ?", ids).Find(&users).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).
-			JSON(fiber.Map{"error": "Failed to retrieve user information"})
+	users, next := pageItems(page, users, func(user *models.PartnerUser) (interface{}, uint) {
+		if page.Sort.Name == "name" {
+			return user.Name, user.ID
+		}
+		return user.Since, user.ID
+	})
+
+	profiles := make([]*models.UserResponse, len(users))
+	for i := range users {
+		profiles[i] = &users[i].UserResponse
+	}
+	if err := filterProfiles(s.store, viewerID, profiles...); err != nil {
+		return nil, nil, apierror.Internal("Failed to retrieve user information")
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"status":   "ok",
-		"partners": users,
-	})
+	return users, next, nil
 }
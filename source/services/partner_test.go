@@ -0,0 +1,150 @@
+package services_test
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/consts"
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository/repotest"
+	"cnep-backend/source/services"
+)
+
+// partnerPair stores two users, alice and bob, and returns their IDs.
+func partnerPair(t *testing.T, store *repotest.Store) (uint, uint) {
+	t.Helper()
+
+	return createUser(t, store, "alice@example.com").ID, createUser(t, store, "bob@example.com").ID
+}
+
+// partnerSetup stores what a test case starts from between alice and bob.
+type partnerSetup func(t *testing.T, store *repotest.Store, alice, bob uint)
+
+// partnerRow returns a setup storing a partner row with the status, sent by alice or by bob.
+func partnerRow(fromAlice bool, status string, updated time.Time) partnerSetup {
+	return func(t *testing.T, store *repotest.Store, alice, bob uint) {
+		partner := models.Partner{SenderID: alice, ReceiverID: bob, Status: status, UpdatedAt: updated}
+		if !fromAlice {
+			partner.SenderID, partner.ReceiverID = bob, alice
+		}
+		if err := store.Partners().Create(&partner); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// pending returns a setup storing a pending request, sent by alice or by bob.
+func pending(fromAlice bool) partnerSetup {
+	return partnerRow(fromAlice, consts.PARTNER_STATUS_PENDING, time.Now())
+}
+
+func TestPartnerRequest(t *testing.T) {
+	weekAgo := time.Now().Add(-8 * 24 * time.Hour)
+
+	tests := []struct {
+		name     string
+		setup    partnerSetup
+		status   int
+		accepted bool
+		// relationship alice sees with bob after the request
+		relationship string
+	}{
+		{"new request", nil, 0, false, consts.PARTNER_RELATION_REQUEST_SENT},
+		{"request already sent", pending(true), http.StatusConflict, false, consts.PARTNER_RELATION_REQUEST_SENT},
+		{"reverse request accepts", pending(false), 0, true, consts.PARTNER_RELATION_PARTNERS},
+		{"already partners", partnerRow(true, consts.PARTNER_STATUS_ACCEPTED, time.Now()), http.StatusConflict, false, consts.PARTNER_RELATION_PARTNERS},
+		{"declined during the cooldown", partnerRow(true, consts.PARTNER_STATUS_DECLINED, time.Now()), http.StatusTooManyRequests, false, consts.PARTNER_RELATION_DECLINED},
+		{"declined after the cooldown", partnerRow(true, consts.PARTNER_STATUS_DECLINED, weekAgo), 0, false, consts.PARTNER_RELATION_REQUEST_SENT},
+		{"alice declined bob", partnerRow(false, consts.PARTNER_STATUS_DECLINED, time.Now()), 0, false, consts.PARTNER_RELATION_REQUEST_SENT},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, store, _ := newServices(t)
+			alice, bob := partnerPair(t, store)
+			if tt.setup != nil {
+				tt.setup(t, store, alice, bob)
+			}
+
+			accepted, err := svc.Partners.Request(alice, bob)
+			expectStatus(t, err, tt.status)
+			if accepted != tt.accepted {
+				t.Fatalf("expected accepted %v, got %v", tt.accepted, accepted)
+			}
+			if tt.status == http.StatusTooManyRequests {
+				if retryAfter, _ := err.(*apierror.Error).Details["retry_after"].(int); retryAfter <= 0 {
+					t.Fatalf("expected retry_after in the details, got %+v", err.(*apierror.Error).Details)
+				}
+			}
+
+			status, err := svc.Partners.Status(alice, bob)
+			expectStatus(t, err, 0)
+			if status.Relationship != tt.relationship {
+				t.Fatalf("expected relationship %s, got %s", tt.relationship, status.Relationship)
+			}
+		})
+	}
+}
+
+func TestPartnerRequestRefused(t *testing.T) {
+	svc, store, _ := newServices(t)
+	alice, bob := partnerPair(t, store)
+
+	_, err := svc.Partners.Request(alice, alice)
+	expectStatus(t, err, http.StatusBadRequest)
+	_, err = svc.Partners.Request(alice, 999)
+	expectStatus(t, err, http.StatusNotFound)
+
+	// Blocked users look like they do not exist, whoever blocked whom
+	if err := store.Blocks().Create(bob, alice); err != nil {
+		t.Fatal(err)
+	}
+	_, err = svc.Partners.Request(alice, bob)
+	expectStatus(t, err, http.StatusNotFound)
+	_, err = svc.Partners.Status(alice, bob)
+	expectStatus(t, err, http.StatusNotFound)
+}
+
+func TestPartnerAnswers(t *testing.T) {
+	tests := []struct {
+		name   string
+		setup  partnerSetup
+		answer func(svc *services.PartnerService, alice, bob uint) error
+		status int
+		// relationship alice sees with bob after the answer
+		relationship string
+	}{
+		{"accept", pending(true), func(svc *services.PartnerService, alice, bob uint) error { return svc.Respond(bob, alice, true) },
+			0, consts.PARTNER_RELATION_PARTNERS},
+		{"decline", pending(true), func(svc *services.PartnerService, alice, bob uint) error { return svc.Respond(bob, alice, false) },
+			0, consts.PARTNER_RELATION_DECLINED},
+		{"sender cannot accept", pending(true), func(svc *services.PartnerService, alice, bob uint) error { return svc.Respond(alice, bob, true) },
+			http.StatusNotFound, consts.PARTNER_RELATION_REQUEST_SENT},
+		{"cancel", pending(true), func(svc *services.PartnerService, alice, bob uint) error { return svc.Cancel(alice, bob) },
+			0, consts.PARTNER_RELATION_NONE},
+		{"receiver cannot cancel", pending(true), func(svc *services.PartnerService, alice, bob uint) error { return svc.Cancel(bob, alice) },
+			http.StatusNotFound, consts.PARTNER_RELATION_REQUEST_SENT},
+		{"remove", partnerRow(true, consts.PARTNER_STATUS_ACCEPTED, time.Now()), func(svc *services.PartnerService, alice, bob uint) error { return svc.Remove(bob, alice) },
+			0, consts.PARTNER_RELATION_NONE},
+		{"remove a pending request", pending(true), func(svc *services.PartnerService, alice, bob uint) error { return svc.Remove(alice, bob) },
+			http.StatusNotFound, consts.PARTNER_RELATION_REQUEST_SENT},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, store, _ := newServices(t)
+			alice, bob := partnerPair(t, store)
+			tt.setup(t, store, alice, bob)
+
+			expectStatus(t, tt.answer(svc.Partners, alice, bob), tt.status)
+
+			status, err := svc.Partners.Status(alice, bob)
+			expectStatus(t, err, 0)
+			if status.Relationship != tt.relationship {
+				t.Fatalf("expected relationship %s, got %s", tt.relationship, status.Relationship)
+			}
+		})
+	}
+}
@@ -0,0 +1,115 @@
+package services
+
+import (
+	"errors"
+	"log"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/consts"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/repository"
+)
+
+/*
+The RequestPasswordReset method sends a password reset code to the given email.
+Here's a breakdown of what it does:
+
+Steps:
+ 1. Checks if the email parameter is a valid email format.
+ 2. Retrieves the user with the given email from the database.
+ 3. Counts the email against the resend cooldown and the daily limit of OTP emails for the user.
+ 4. Generates a reset OTP, separate from the signup OTP, and stores it with a 15 minute expiry.
+ 5. Sends the reset OTP with the password reset template.
+
+Returns:
+
+	No error whether or not an account exists for the email, so accounts cannot be discovered.
+*/
+func (s *AuthService) RequestPasswordReset(email string) error {
+	email = utils.NormalizeEmail(email)
+	if !utils.IsValidEmail(email) {
+		return apierror.BadRequest("Invalid email format")
+	}
+
+	user, err := s.store.Users().GetByEmail(email)
+	if err != nil {
+		return nil
+	}
+
+	var otp string
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := claimOTPSend(tx, user.ID, otpResendCooldown()); err != nil {
+			return err
+		}
+
+		var err error
+		otp, err = issueCode(tx, user.ID, consts.OTP_PURPOSE_RESET, "")
+		return err
+	})
+	if err == errOTPThrottled {
+		return nil
+	}
+	if err != nil {
+		return apierror.Internal("Could not create reset code")
+	}
+
+	if err := utils.SendOTPEmail(user.Email, otp, consts.OTP_PURPOSE_RESET, user.Locale); err != nil {
+		log.Printf("Error sending password reset email to user %d: %v", user.ID, err)
+	}
+
+	return nil
+}
+
+/*
+The ResetPassword method sets a new password using a reset code sent by RequestPasswordReset.
+The code can be used only once, only before it expires and only for OTP_MAX_ATTEMPTS guesses,
+and OTP_MAX_FAILURES wrong codes, across resends, lock the reset for OTP_LOCK_MINUTES.
+Completing a reset signs the user out of every session.
+*/
+func (s *AuthService) ResetPassword(email, otp, newPassword string) error {
+	email = utils.NormalizeEmail(email)
+	if !utils.IsValidEmail(email) || len(otp) != 8 {
+		return apierror.BadRequest("Invalid email or OTP format")
+	}
+
+	if !utils.IsValidPassword(newPassword) {
+		return apierror.BadRequest("Password does not meet complexity requirements")
+	}
+
+	user, err := s.store.Users().GetByEmail(email)
+	if err != nil {
+		return errInvalidOTP
+	}
+
+	code, ok := verifyCode(s.store, user, consts.OTP_PURPOSE_RESET, otp)
+	if !ok {
+		return errInvalidOTP
+	}
+
+	hashedPassword, err := utils.HashPassword(newPassword)
+	if err != nil {
+		return apierror.Internal("Could not hash password")
+	}
+
+	err = s.store.Transaction(func(tx repository.Store) error {
+		// Consume the code first so it cannot be used twice
+		if err := tx.Codes().Use(code.ID); err != nil {
+			return err
+		}
+
+		if err := tx.Users().Update(user.ID, map[string]interface{}{"password": hashedPassword}); err != nil {
+			return err
+		}
+
+		_, err := tx.Sessions().RevokeOthers(user.ID, 0)
+		return err
+	})
+	if errors.Is(err, repository.ErrNotFound) {
+		return errInvalidOTP
+	}
+	if err != nil {
+		return apierror.Internal("Could not reset password")
+	}
+
+	return nil
+}
@@ -0,0 +1,136 @@
+package services
+
+import (
+	"errors"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/consts"
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
+)
+
+// GetPrivacySettings returns the profile privacy settings of the given user.
+func (s *UserService) GetPrivacySettings(userID uint) (*models.PrivacySettings, error) {
+	settings, err := s.store.Users().Privacy(userID)
+	if err != nil {
+		return nil, apierror.NotFound("User not found")
+	}
+
+	return settings, nil
+}
+
+/*
+The UpdatePrivacySettings method changes the profile privacy settings of the given user.
+Only the settings given are changed, each must be public, partners or private.
+It returns the settings after the change.
+*/
+func (s *UserService) UpdatePrivacySettings(userID uint, email, phone, address *string) (*models.PrivacySettings, error) {
+	updates := map[string]interface{}{}
+	for column, value := range map[string]*string{
+		"email_visibility":   email,
+		"phone_visibility":   phone,
+		"address_visibility": address,
+	} {
+		if value == nil {
+			continue
+		}
+		if !isValidVisibility(*value) {
+			return nil, apierror.BadRequest("Visibility must be public, partners or private")
+		}
+		updates[column] = *value
+	}
+
+	if len(updates) == 0 {
+		return nil, apierror.BadRequest("No valid fields to update")
+	}
+
+	if err := s.store.Users().Update(userID, updates); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apierror.NotFound("User not found")
+		}
+		return nil, apierror.Internal("Could not update privacy settings")
+	}
+
+	return s.GetPrivacySettings(userID)
+}
+
+func isValidVisibility(visibility string) bool {
+	switch visibility {
+	case consts.VISIBILITY_PUBLIC, consts.VISIBILITY_PARTNERS, consts.VISIBILITY_PRIVATE:
+		return true
+	}
+	return false
+}
+
+// canSee reports whether a field with the given visibility is shown to a viewer.
+func canSee(visibility string, self, partner bool) bool {
+	switch {
+	case self:
+		return true
+	case visibility == consts.VISIBILITY_PUBLIC:
+		return true
+	case visibility == consts.VISIBILITY_PARTNERS:
+		return partner
+	}
+	return false
+}
+
+// acceptedPartners returns which of the given users are accepted partners of the viewer.
+func acceptedPartners(store repository.Store, viewerID uint, userIDs []uint) (map[uint]bool, error) {
+	partners, err := store.Partners().Accepted(viewerID, userIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[uint]bool, len(partners))
+	for _, partner := range partners {
+		if partner.SenderID == viewerID {
+			result[partner.ReceiverID] = true
+		} else {
+			result[partner.SenderID] = true
+		}
+	}
+	return result, nil
+}
+
+/*
+The filterProfiles function removes the contact fields the viewer is not allowed to see from the given profiles.
+A user always sees their own profile in full, accepted partners see the public and partners-only fields,
+and everyone else sees the public fields only. The location, the locale, the role and whether
+two-factor authentication is enabled are only ever shown to the user.
+*/
+func filterProfiles(store repository.Store, viewerID uint, users ...*models.UserResponse) error {
+	if len(users) == 0 {
+		return nil
+	}
+
+	ids := make([]uint, 0, len(users))
+	for _, user := range users {
+		ids = append(ids, user.ID)
+	}
+
+	partners, err := acceptedPartners(store, viewerID, ids)
+	if err != nil {
+		return err
+	}
+
+	for _, user := range users {
+		self := user.ID == viewerID
+		if !canSee(user.Privacy.EmailVisibility, self, partners[user.ID]) {
+			user.Email = ""
+		}
+		if !canSee(user.Privacy.PhoneVisibility, self, partners[user.ID]) {
+			user.Phone = ""
+		}
+		if !canSee(user.Privacy.AddressVisibility, self, partners[user.ID]) {
+			user.Address = ""
+		}
+		if !self {
+			user.Latitude, user.Longitude = nil, nil
+			user.Locale = ""
+			user.Role = ""
+			user.TwoFactorEnabled = false
+		}
+	}
+	return nil
+}
@@ -1,54 +1,62 @@
 package services
 
 import (
-	"cnep-backend/pkg/consts"
+	"cnep-backend/pkg/apierror"
 	"cnep-backend/pkg/utils"
-	"cnep-backend/source/database"
-	"cnep-backend/source/models"
-
-	"github.com/gofiber/fiber/v2"
-	"log"
+	"cnep-backend/source/repository"
 )
 
-/*
-The ChangePassword function is a handler function that allows users to change their password.
-It takes the user ID, the old password, and the new password as parameters.
-If the user is not found or any other error occurs, it returns an appropriate error message.
-The function returns a JSON response with the updated user profile.
-*/
-func ChangePassword(c *fiber.Ctx, userId uint, oldPassword string, newPassword string) error {
-	var user models.User
+// AccountService changes the sign-in details of a user, their password and their email.
+type AccountService struct {
+	store repository.Store
+}
 
-	// Ensure database connection is established
-	if database.DB == nil {
-		log.Panic("Database not connected")
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Database not connected"})
-	}
+func NewAccountService(store repository.Store) *AccountService {
+	return &AccountService{store: store}
+}
 
+/*
+The ChangePassword method allows users to change their password.
+It takes the user ID, the current session ID, the old password, and the new password as parameters.
+If logoutOthers is set, every other session of the user is signed out.
+If the user is not found or any other error occurs, it returns an appropriate error.
+The function returns the number of sessions that were signed out.
+*/
+func (s *AccountService) ChangePassword(userId uint, sessionID uint, oldPassword string, newPassword string, logoutOthers bool) (int64, error) {
 	if !utils.IsValidPassword(oldPassword) || !utils.IsValidPassword(newPassword) {
-		return c.Status(fiber.StatusBadRequest).JSON(fiber.Map{"error": "Invalid password format"})
+		return 0, apierror.BadRequest("Invalid password format")
 	}
 
-	if err := database.DB.Table(consts.USERS_TABLE).Where("id = ?", userId).First(&user).Error; err != nil {
-		return c.Status(fiber.StatusNotFound).JSON(fiber.Map{"error": "User not found"})
+	user, err := s.store.Users().Get(userId)
+	if err != nil {
+		return 0, apierror.NotFound("User not found")
 	}
 
-	if !utils.CheckPasswordHash(user.Password, oldPassword) {
-		return c.Status(fiber.StatusUnauthorized).JSON(fiber.Map{"error": "Incorrect old password"})
+	if !utils.CheckPasswordHash(oldPassword, user.Password) {
+		return 0, apierror.Unauthorized("Incorrect old password")
 	}
 
 	hashedPassword, err := utils.HashPassword(newPassword)
 	if err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not hash password"})
+		return 0, apierror.Internal("Could not hash password")
 	}
 
-	user.Password = hashedPassword
-
-	if err := database.DB.Table(consts.USERS_TABLE).Save(&user).Error; err != nil {
-		return c.Status(fiber.StatusInternalServerError).JSON(fiber.Map{"error": "Could not update password"})
+	var revoked int64
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Users().Update(user.ID, map[string]interface{}{"password": hashedPassword}); err != nil {
+			return err
+		}
+
+		if logoutOthers {
+			var err error
+			revoked, err = tx.Sessions().RevokeOthers(user.ID, sessionID)
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, apierror.Internal("Could not update password")
 	}
 
-	return c.Status(fiber.StatusOK).JSON(fiber.Map{
-		"message": "Password successfully updated",
-	})
+	return revoked, nil
 }
@@ -0,0 +1,38 @@
+package services
+
+import "cnep-backend/source/repository"
+
+// Services holds every service of the application. They are built on one store,
+// so the handlers, the websocket hub and background jobs all share the same data access.
+type Services struct {
+	Auth       *AuthService
+	OIDC       *OIDCService
+	TwoFactor  *TwoFactorService
+	Sessions   *SessionService
+	Account    *AccountService
+	Users      *UserService
+	Uploads    *UploadService
+	Feedback   *FeedbackService
+	Blocks     *BlockService
+	Partners   *PartnerService
+	Admin      *AdminService
+	Moderation *ModerationService
+}
+
+// New builds every service on the given store.
+func New(store repository.Store) *Services {
+	return &Services{
+		Auth:       NewAuthService(store),
+		OIDC:       NewOIDCService(store),
+		TwoFactor:  NewTwoFactorService(store),
+		Sessions:   NewSessionService(store),
+		Account:    NewAccountService(store),
+		Users:      NewUserService(store),
+		Uploads:    NewUploadService(store),
+		Feedback:   NewFeedbackService(store),
+		Blocks:     NewBlockService(store),
+		Partners:   NewPartnerService(store),
+		Admin:      NewAdminService(store),
+		Moderation: NewModerationService(store),
+	}
+}
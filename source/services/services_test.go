@@ -0,0 +1,68 @@
+package services_test
+
+import (
+	"errors"
+	"testing"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/lib"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/apitest"
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository/repotest"
+	"cnep-backend/source/services"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// newServices builds the services on an empty in-memory store, with a mailer keeping the emails in memory.
+func newServices(t *testing.T) (*services.Services, *repotest.Store, *apitest.Mailbox) {
+	t.Helper()
+
+	mail := &apitest.Mailbox{}
+	lib.SetMailer(mail)
+
+	store := repotest.New()
+	return services.New(store), store, mail
+}
+
+// createUser stores a verified user with the email and apitest.Password.
+func createUser(t *testing.T, store *repotest.Store, email string) *models.User {
+	t.Helper()
+
+	// The lowest cost keeps the tests fast, login accepts a hash of any cost
+	password, err := bcrypt.GenerateFromPassword([]byte(apitest.Password), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+	username, err := utils.GenerateUsername(email)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	user := &models.User{Email: email, Username: username, Password: string(password), IsVerified: true}
+	if err := store.Users().Create(user); err != nil {
+		t.Fatalf("could not create user %s: %v", email, err)
+	}
+	return user
+}
+
+// expectStatus fails the test unless err is an API error with the status, or nil when status is 0.
+func expectStatus(t *testing.T, err error, status int) {
+	t.Helper()
+
+	if status == 0 {
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		return
+	}
+
+	var apiErr *apierror.Error
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected an error with status %d, got %v", status, err)
+	}
+	if apiErr.Status != status {
+		t.Fatalf("expected status %d, got %d (%s)", status, apiErr.Status, apiErr.Message)
+	}
+}
@@ -0,0 +1,268 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
+)
+
+// TokenPair is returned to the client after a successful login or refresh.
+type TokenPair struct {
+	AccessToken  string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
+
+// sessionTouchInterval limits how often the last seen time of a session is written.
+const sessionTouchInterval = 5 * time.Minute
+
+// createSession starts a new session for the user, recording the device of the request client,
+// and issues its first token pair.
+func createSession(store repository.Store, ctx context.Context, userID uint) (*TokenPair, error) {
+	var pair *TokenPair
+	client := clientFrom(ctx)
+
+	err := store.Transaction(func(tx repository.Store) error {
+		session := models.Session{
+			UserID:     userID,
+			Device:     client.Device,
+			IP:         client.IP,
+			UserAgent:  client.UserAgent,
+			LastSeenAt: time.Now(),
+		}
+		if err := tx.Sessions().Create(&session); err != nil {
+			return err
+		}
+
+		var err error
+		pair, err = issueTokens(tx, userID, session.ID)
+		return err
+	})
+
+	return pair, err
+}
+
+// issueTokens stores a new refresh token for the session and signs a matching access token.
+func issueTokens(tx repository.Store, userID, sessionID uint) (*TokenPair, error) {
+	refreshToken, hash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	record := models.RefreshToken{
+		SessionID: sessionID,
+		TokenHash: hash,
+		ExpiresAt: time.Now().Add(utils.RefreshTokenTTL()),
+	}
+	if err := tx.Sessions().CreateRefreshToken(&record); err != nil {
+		return nil, err
+	}
+
+	// Read the role on every issue, so role changes apply from the next refresh
+	role, err := tx.Users().Role(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := utils.GenerateJWT(userID, sessionID, role)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(utils.AccessTokenTTL().Seconds()),
+	}, nil
+}
+
+// ErrAccountSuspended is returned by Validate for sessions of suspended or banned users.
+var ErrAccountSuspended = errors.New("account suspended")
+
+// SessionService manages the sessions of signed in users and the refresh tokens that keep them alive.
+type SessionService struct {
+	store repository.Store
+}
+
+func NewSessionService(store repository.Store) *SessionService {
+	return &SessionService{store: store}
+}
+
+// Validate reports whether the session exists, belongs to the user and is not revoked.
+// It returns ErrAccountSuspended if the user is suspended. It also records the session as recently seen.
+func (s *SessionService) Validate(userID, sessionID uint) (bool, error) {
+	session, err := s.store.Sessions().GetWithUser(userID, sessionID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	if session.RevokedAt != nil {
+		return false, nil
+	}
+
+	if isSuspended(&models.User{SuspendedAt: session.SuspendedAt, SuspendedUntil: session.SuspendedUntil}) {
+		return false, ErrAccountSuspended
+	}
+
+	if time.Since(session.LastSeenAt) > sessionTouchInterval {
+		if err := s.store.Sessions().Update(session.ID, map[string]interface{}{
+			"last_seen_at": time.Now(),
+		}); err != nil {
+			log.Printf("Error updating last seen of session %d: %v", session.ID, err)
+		}
+	}
+
+	return true, nil
+}
+
+// List lists the active sessions of the user, marking the one the request was made with.
+func (s *SessionService) List(userID, currentSessionID uint) ([]models.SessionResponse, error) {
+	sessions, err := s.store.Sessions().ListActive(userID)
+	if err != nil {
+		return nil, apierror.Internal("Could not fetch sessions")
+	}
+
+	response := make([]models.SessionResponse, 0, len(sessions))
+	for _, session := range sessions {
+		response = append(response, models.SessionResponse{
+			ID:         session.ID,
+			Device:     session.Device,
+			IP:         session.IP,
+			UserAgent:  session.UserAgent,
+			LastSeenAt: session.LastSeenAt,
+			CreatedAt:  session.CreatedAt,
+			Current:    session.ID == currentSessionID,
+		})
+	}
+
+	return response, nil
+}
+
+// Revoke signs the user out of a single session.
+func (s *SessionService) Revoke(userID, sessionID uint) error {
+	session, err := s.store.Sessions().GetActive(userID, sessionID)
+	if err != nil {
+		return apierror.NotFound("Session not found")
+	}
+
+	if err := s.store.Sessions().Revoke(session.ID); err != nil {
+		return apierror.Internal("Could not revoke session")
+	}
+
+	return nil
+}
+
+// RevokeOthers signs the user out everywhere except the current session.
+// It returns the number of sessions revoked.
+func (s *SessionService) RevokeOthers(userID, currentSessionID uint) (int64, error) {
+	revoked, err := s.store.Sessions().RevokeOthers(userID, currentSessionID)
+	if err != nil {
+		return 0, apierror.Internal("Could not revoke sessions")
+	}
+
+	return revoked, nil
+}
+
+/*
+The Refresh method exchanges a refresh token for a new access token and a new refresh token.
+Here's a breakdown of what it does:
+
+Steps:
+ 1. Looks up the refresh token by its hash.
+ 2. Rejects the request if the token is expired or its session is revoked.
+ 3. If the token was already exchanged before, it is being reused (most likely stolen),
+    so the whole session is revoked and the request is rejected.
+ 4. Marks the token as used and issues a new token pair for the same session.
+
+Returns:
+
+	The new token pair, or an error if the refresh token is not valid.
+*/
+func (s *SessionService) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	if refreshToken == "" {
+		return nil, apierror.BadRequest("Refresh token not provided")
+	}
+
+	token, err := s.store.Sessions().GetRefreshToken(utils.HashToken(refreshToken))
+	if err != nil {
+		return nil, apierror.Unauthorized("Invalid refresh token")
+	}
+
+	session, err := s.store.Sessions().Get(token.SessionID)
+	if err != nil {
+		return nil, apierror.Unauthorized("Invalid refresh token")
+	}
+
+	if session.RevokedAt != nil || time.Now().After(token.ExpiresAt) {
+		return nil, apierror.Unauthorized("Refresh token expired or revoked")
+	}
+
+	var pair *TokenPair
+	reused := false
+
+	err = s.store.Transaction(func(tx repository.Store) error {
+		// Only one request can ever mark the token as used
+		used, err := tx.Sessions().UseRefreshToken(token.ID)
+		if err != nil {
+			return err
+		}
+
+		if !used {
+			reused = true
+			return nil
+		}
+
+		if err := tx.Sessions().Update(session.ID, map[string]interface{}{
+			"ip":           clientFrom(ctx).IP,
+			"last_seen_at": time.Now(),
+		}); err != nil {
+			return err
+		}
+
+		pair, err = issueTokens(tx, session.UserID, session.ID)
+		return err
+	})
+	if err != nil {
+		return nil, apierror.Internal("Could not refresh token")
+	}
+
+	if reused {
+		if err := s.store.Sessions().Revoke(session.ID); err != nil {
+			log.Printf("Error revoking session %d after refresh token reuse: %v", session.ID, err)
+		}
+		log.Printf("Refresh token reuse detected for user %d, session %d revoked", session.UserID, session.ID)
+		return nil, apierror.Unauthorized("Refresh token reuse detected")
+	}
+
+	return pair, nil
+}
+
+/*
+The Logout method revokes the session the given refresh token belongs to.
+Access tokens of the session are rejected by the AuthMiddleware from then on.
+*/
+func (s *SessionService) Logout(refreshToken string) error {
+	if refreshToken == "" {
+		return apierror.BadRequest("Refresh token not provided")
+	}
+
+	token, err := s.store.Sessions().GetRefreshToken(utils.HashToken(refreshToken))
+	if err != nil {
+		return apierror.Unauthorized("Invalid refresh token")
+	}
+
+	if err := s.store.Sessions().Revoke(token.SessionID); err != nil {
+		return apierror.Internal("Could not log out")
+	}
+
+	return nil
+}
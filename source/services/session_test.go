@@ -0,0 +1,175 @@
+package services_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/apitest"
+	"cnep-backend/source/repository/repotest"
+	"cnep-backend/source/services"
+)
+
+// login signs the user in and returns the tokens with the ID of the new session.
+func login(t *testing.T, svc *services.Services, email string) (*services.LoginResult, uint) {
+	t.Helper()
+
+	result, err := svc.Auth.Login(context.Background(), email, apitest.Password)
+	if err != nil {
+		t.Fatalf("could not log in %s: %v", email, err)
+	}
+
+	claims, err := utils.ValidateJWT(result.Token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return result, claims.SessionID
+}
+
+func TestRefresh(t *testing.T) {
+	tests := []struct {
+		name string
+		// prepare returns the refresh token to exchange
+		prepare func(t *testing.T, svc *services.Services, store *repotest.Store, token string, sessionID uint) string
+		status  int
+	}{
+		{"valid token", nil, 0},
+		{"no token", func(*testing.T, *services.Services, *repotest.Store, string, uint) string { return "" },
+			http.StatusBadRequest},
+		{"unknown token", func(*testing.T, *services.Services, *repotest.Store, string, uint) string { return "unknown" },
+			http.StatusUnauthorized},
+		{"revoked session", func(t *testing.T, _ *services.Services, store *repotest.Store, token string, sessionID uint) string {
+			if err := store.Sessions().Revoke(sessionID); err != nil {
+				t.Fatal(err)
+			}
+			return token
+		}, http.StatusUnauthorized},
+		{"used token", func(t *testing.T, svc *services.Services, _ *repotest.Store, token string, _ uint) string {
+			if _, err := svc.Sessions.Refresh(context.Background(), token); err != nil {
+				t.Fatal(err)
+			}
+			return token
+		}, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, store, _ := newServices(t)
+			createUser(t, store, "user@example.com")
+			result, sessionID := login(t, svc, "user@example.com")
+
+			token := result.RefreshToken
+			if tt.prepare != nil {
+				token = tt.prepare(t, svc, store, token, sessionID)
+			}
+
+			pair, err := svc.Sessions.Refresh(context.Background(), token)
+			expectStatus(t, err, tt.status)
+			if tt.status != 0 {
+				return
+			}
+
+			if pair.AccessToken == "" || pair.RefreshToken == "" || pair.RefreshToken == token {
+				t.Fatalf("expected a new token pair, got %+v", pair)
+			}
+			if claims, err := utils.ValidateJWT(pair.AccessToken); err != nil || claims.SessionID != sessionID {
+				t.Fatalf("expected an access token for session %d, got %+v (%v)", sessionID, claims, err)
+			}
+		})
+	}
+}
+
+func TestRefreshReuseRevokesSession(t *testing.T) {
+	svc, store, _ := newServices(t)
+	user := createUser(t, store, "user@example.com")
+	result, sessionID := login(t, svc, "user@example.com")
+
+	pair, err := svc.Sessions.Refresh(context.Background(), result.RefreshToken)
+	expectStatus(t, err, 0)
+
+	// The first token is presented again, most likely by whoever stole it
+	_, err = svc.Sessions.Refresh(context.Background(), result.RefreshToken)
+	expectStatus(t, err, http.StatusUnauthorized)
+
+	// Which signs the legitimate client out as well
+	_, err = svc.Sessions.Refresh(context.Background(), pair.RefreshToken)
+	expectStatus(t, err, http.StatusUnauthorized)
+	if valid, err := svc.Sessions.Validate(user.ID, sessionID); valid || err != nil {
+		t.Fatalf("expected the session to be revoked, got %v (%v)", valid, err)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	svc, store, _ := newServices(t)
+	user := createUser(t, store, "user@example.com")
+	result, sessionID := login(t, svc, "user@example.com")
+	_, otherID := login(t, svc, "user@example.com")
+
+	expectStatus(t, svc.Sessions.Logout(""), http.StatusBadRequest)
+	expectStatus(t, svc.Sessions.Logout("unknown"), http.StatusUnauthorized)
+	expectStatus(t, svc.Sessions.Logout(result.RefreshToken), 0)
+
+	// Only the session of the token is signed out
+	for id, want := range map[uint]bool{sessionID: false, otherID: true} {
+		if valid, err := svc.Sessions.Validate(user.ID, id); valid != want || err != nil {
+			t.Fatalf("expected session %d to be valid: %v, got %v (%v)", id, want, valid, err)
+		}
+	}
+	_, err := svc.Sessions.Refresh(context.Background(), result.RefreshToken)
+	expectStatus(t, err, http.StatusUnauthorized)
+}
+
+func TestValidateSession(t *testing.T) {
+	svc, store, _ := newServices(t)
+	user := createUser(t, store, "user@example.com")
+	other := createUser(t, store, "other@example.com")
+	_, sessionID := login(t, svc, "user@example.com")
+
+	if valid, err := svc.Sessions.Validate(user.ID, sessionID); !valid || err != nil {
+		t.Fatalf("expected the session to be valid, got %v (%v)", valid, err)
+	}
+	if valid, err := svc.Sessions.Validate(other.ID, sessionID); valid || err != nil {
+		t.Fatalf("expected the session not to be valid for another user, got %v (%v)", valid, err)
+	}
+
+	until := time.Now().Add(time.Hour)
+	if err := store.Users().Update(user.ID, map[string]interface{}{"suspended_at": time.Now(), "suspended_until": until}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := svc.Sessions.Validate(user.ID, sessionID); !errors.Is(err, services.ErrAccountSuspended) {
+		t.Fatalf("expected the session of a suspended user to be refused, got %v", err)
+	}
+}
+
+func TestRevokeSessions(t *testing.T) {
+	svc, store, _ := newServices(t)
+	user := createUser(t, store, "user@example.com")
+	other := createUser(t, store, "other@example.com")
+	_, current := login(t, svc, "user@example.com")
+	_, second := login(t, svc, "user@example.com")
+	_, third := login(t, svc, "user@example.com")
+	_, othersSession := login(t, svc, "other@example.com")
+
+	// A user cannot revoke the session of another user
+	expectStatus(t, svc.Sessions.Revoke(user.ID, othersSession), http.StatusNotFound)
+	expectStatus(t, svc.Sessions.Revoke(user.ID, second), 0)
+	expectStatus(t, svc.Sessions.Revoke(user.ID, second), http.StatusNotFound)
+
+	revoked, err := svc.Sessions.RevokeOthers(user.ID, current)
+	if err != nil || revoked != 1 {
+		t.Fatalf("expected one more session to be revoked, got %d (%v)", revoked, err)
+	}
+
+	sessions, err := svc.Sessions.List(user.ID, current)
+	if err != nil || len(sessions) != 1 || sessions[0].ID != current || !sessions[0].Current {
+		t.Fatalf("expected only the current session to be left, got %+v (%v)", sessions, err)
+	}
+	for id, owner := range map[uint]uint{third: user.ID, othersSession: other.ID} {
+		if valid, _ := svc.Sessions.Validate(owner, id); valid != (id == othersSession) {
+			t.Fatalf("expected only the session of the other user to be left valid, session %d is %v", id, valid)
+		}
+	}
+}
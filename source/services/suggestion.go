@@ -0,0 +1,62 @@
+package services
+
+import (
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
+)
+
+// suggestionScoring weighs the signals a partner suggestion is scored on.
+// Users further away than the radius are not suggested for being nearby.
+var suggestionScoring = repository.SuggestionScoring{
+	MutualWeight: 3.0,
+	TopicWeight:  2.0,
+	HelpWeight:   4.0,
+	NearbyWeight: 5.0,
+	RadiusKm:     25.0,
+}
+
+// SuggestionsList is the list spec of partner suggestions, best scored first.
+var SuggestionsList = utils.ListSpec{
+	Sorts: []utils.SortField{
+		{Name: "score", Column: "score", Kind: utils.SortFloat, Desc: true},
+	},
+	IDColumn: "id",
+}
+
+/*
+The Suggestions method suggests users the given user may want to partner with.
+Here's a breakdown of what it does:
+
+Steps:
+ 1. Counts for every other user the accepted partners they have in common with the user,
+    the topics they share and how often the two helped each other.
+ 2. Measures the distance between the two when both set a location.
+ 3. Leaves out existing partners, pending and declined requests in either direction,
+    blocked users, and unverified or suspended accounts.
+ 4. Scores every user on these signals and returns a page of the best scored users.
+
+Returns:
+
+	The suggestions with their mutual partner count, the total number of suggestions
+	and the cursor of the next page.
+*/
+func (s *PartnerService) Suggestions(userID uint, page utils.Page) ([]models.PartnerSuggestion, int64, *string, error) {
+	suggestions, err := s.store.Partners().Suggestions(userID, suggestionScoring, page)
+	if err != nil {
+		return nil, 0, nil, listError(err, "Could not fetch suggestions")
+	}
+
+	suggestions, next := pageItems(page, suggestions, func(suggestion *models.PartnerSuggestion) (interface{}, uint) {
+		return suggestion.Score, suggestion.ID
+	})
+
+	var total int64
+	for i := range suggestions {
+		suggestion := &suggestions[i]
+		suggestion.Nearby = suggestion.DistanceKm != nil && *suggestion.DistanceKm < suggestionScoring.RadiusKm
+		total = suggestion.Total
+	}
+
+	return suggestions, total, next, nil
+}
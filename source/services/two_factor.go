@@ -0,0 +1,362 @@
+package services
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/config"
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
+)
+
+const (
+	// totpIssuer is the account name shown in authenticator apps
+	totpIssuer        = "CNEP"
+	recoveryCodeCount = 10
+)
+
+var (
+	errTwoFactorNotEnabled = apierror.BadRequest("Two-factor authentication is not enabled")
+	errAccountLocked       = apierror.TooManyRequests("Too many failed attempts. Please try again later.").
+				WithCode(apierror.CodeAccountLocked)
+)
+
+// TwoFactorService enrolls users in TOTP two-factor authentication and checks the second factor at login.
+type TwoFactorService struct {
+	store repository.Store
+}
+
+func NewTwoFactorService(store repository.Store) *TwoFactorService {
+	return &TwoFactorService{store: store}
+}
+
+// TwoFactorSetup is the secret of a TOTP enrollment, to be added to an authenticator app.
+type TwoFactorSetup struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+}
+
+/*
+The Setup method starts TOTP enrollment for the user.
+
+Steps:
+ 1. Checks that two-factor authentication is not already enabled.
+ 2. Generates a new TOTP secret and stores it encrypted, replacing any unfinished enrollment.
+ 3. Builds the otpauth:// provisioning URI for the authenticator app.
+
+Returns:
+
+	The secret and the provisioning URI to show as a QR code.
+	Two-factor authentication is only enabled once Confirm accepts a code.
+*/
+func (s *TwoFactorService) Setup(userID uint) (*TwoFactorSetup, error) {
+	user, err := s.store.Users().Get(userID)
+	if err != nil {
+		return nil, apierror.NotFound("User not found")
+	}
+
+	if user.TwoFactorEnabled {
+		return nil, apierror.Conflict("Two-factor authentication is already enabled")
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return nil, apierror.Internal("Could not generate secret")
+	}
+
+	encrypted, err := utils.EncryptSecret(secret)
+	if err != nil {
+		return nil, apierror.Internal("Could not generate secret")
+	}
+
+	if err := s.store.Users().Update(user.ID, map[string]interface{}{
+		"totp_secret":    encrypted,
+		"totp_last_step": 0,
+	}); err != nil {
+		return nil, apierror.Internal("Could not start two-factor setup")
+	}
+
+	return &TwoFactorSetup{
+		Secret:     secret,
+		OTPAuthURL: utils.TOTPProvisioningURI(secret, totpIssuer, user.Email),
+	}, nil
+}
+
+/*
+The Confirm method finishes TOTP enrollment with a code from the authenticator app.
+Confirming proves the app was set up correctly before logins start to depend on it.
+
+Returns:
+
+	The recovery codes. They are shown only this once.
+*/
+func (s *TwoFactorService) Confirm(userID uint, code string) ([]string, error) {
+	user, err := s.store.Users().Get(userID)
+	if err != nil {
+		return nil, apierror.NotFound("User not found")
+	}
+
+	if user.TwoFactorEnabled {
+		return nil, apierror.Conflict("Two-factor authentication is already enabled")
+	}
+
+	if user.TOTPSecret == "" {
+		return nil, apierror.BadRequest("Two-factor setup has not been started")
+	}
+
+	secret, err := utils.DecryptSecret(user.TOTPSecret)
+	if err != nil {
+		return nil, apierror.Internal("Could not read two-factor secret")
+	}
+
+	step, ok := utils.ValidateTOTP(secret, code, time.Now())
+	if !ok {
+		return nil, apierror.BadRequest("Invalid two-factor code")
+	}
+
+	var recoveryCodes []string
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Users().Update(user.ID, map[string]interface{}{
+			"two_factor_enabled": true,
+			"totp_last_step":     step,
+		}); err != nil {
+			return err
+		}
+
+		var err error
+		recoveryCodes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, apierror.Internal("Could not enable two-factor authentication")
+	}
+
+	return recoveryCodes, nil
+}
+
+/*
+The Disable method turns two-factor authentication off after the user re-enters their password.
+The secret and all recovery codes are removed.
+*/
+func (s *TwoFactorService) Disable(userID uint, password string) error {
+	user, err := s.store.Users().Get(userID)
+	if err != nil {
+		return apierror.NotFound("User not found")
+	}
+
+	if !user.TwoFactorEnabled {
+		return errTwoFactorNotEnabled
+	}
+
+	if !utils.CheckPasswordHash(password, user.Password) {
+		return apierror.Unauthorized("Incorrect password")
+	}
+
+	err = s.store.Transaction(func(tx repository.Store) error {
+		if err := tx.Users().Update(user.ID, map[string]interface{}{
+			"two_factor_enabled": false,
+			"totp_secret":        "",
+			"totp_last_step":     0,
+		}); err != nil {
+			return err
+		}
+
+		return tx.RecoveryCodes().DeleteAll(user.ID)
+	})
+	if err != nil {
+		return apierror.Internal("Could not disable two-factor authentication")
+	}
+
+	return nil
+}
+
+/*
+The RegenerateRecoveryCodes method replaces the user's recovery codes after the user re-enters their password.
+Codes issued before stop working.
+*/
+func (s *TwoFactorService) RegenerateRecoveryCodes(userID uint, password string) ([]string, error) {
+	user, err := s.store.Users().Get(userID)
+	if err != nil {
+		return nil, apierror.NotFound("User not found")
+	}
+
+	if !user.TwoFactorEnabled {
+		return nil, errTwoFactorNotEnabled
+	}
+
+	if !utils.CheckPasswordHash(password, user.Password) {
+		return nil, apierror.Unauthorized("Incorrect password")
+	}
+
+	var recoveryCodes []string
+	err = s.store.Transaction(func(tx repository.Store) error {
+		var err error
+		recoveryCodes, err = replaceRecoveryCodes(tx, user.ID)
+		return err
+	})
+	if err != nil {
+		return nil, apierror.Internal("Could not generate recovery codes")
+	}
+
+	return recoveryCodes, nil
+}
+
+/*
+The VerifyLogin method finishes a login of a user with two-factor authentication enabled.
+
+Steps:
+ 1. Validates the partial token returned by the password step.
+ 2. Checks that the account is not locked.
+ 3. Accepts either a TOTP code, which cannot be reused, or an unused recovery code.
+ 4. Wrong codes count towards the account lock. Only a caller who knows the password gets this
+    far, so the lock cannot be used to keep others out of their account.
+ 5. Starts a session for the user.
+
+Returns:
+
+	The token pair and user, the same as a login without two-factor authentication.
+*/
+func (s *TwoFactorService) VerifyLogin(ctx context.Context, twoFactorToken, code string) (*LoginResult, error) {
+	claims, err := utils.ValidateTwoFactorToken(twoFactorToken)
+	if err != nil {
+		return nil, apierror.Unauthorized("Invalid or expired two-factor token")
+	}
+
+	user, err := s.store.Users().Get(claims.UserID)
+	if err != nil {
+		return nil, apierror.Unauthorized("Invalid or expired two-factor token")
+	}
+
+	if isLocked(user) {
+		return nil, errAccountLocked
+	}
+
+	if isSuspended(user) {
+		return nil, suspendedError(user)
+	}
+
+	if !user.TwoFactorEnabled {
+		return nil, errTwoFactorNotEnabled
+	}
+
+	if !checkSecondFactor(s.store, user, code) {
+		registerTwoFactorFailure(s.store, user.ID)
+		return nil, apierror.Unauthorized("Invalid two-factor code")
+	}
+
+	if err := s.store.Users().Update(user.ID, map[string]interface{}{
+		"otp_failed_attempts": 0,
+		"locked_until":        nil,
+	}); err != nil {
+		log.Printf("Error resetting failed attempts of user %d: %v", user.ID, err)
+	}
+
+	return sessionResult(s.store, ctx, user)
+}
+
+// isLocked reports whether the account is locked after too many wrong two-factor codes.
+func isLocked(user *models.User) bool {
+	return user.LockedUntil != nil && time.Now().Before(*user.LockedUntil)
+}
+
+// registerTwoFactorFailure records a wrong two-factor code and locks the account for
+// OTP_LOCK_MINUTES once OTP_MAX_ATTEMPTS is reached.
+func registerTwoFactorFailure(store repository.Store, userID uint) {
+	cfg := config.New()
+	lockUntil := time.Now().Add(time.Duration(cfg.OTPLockMinutes) * time.Minute)
+
+	if err := store.Users().RecordTwoFactorFailure(userID, cfg.OTPMaxAttempts, lockUntil); err != nil {
+		log.Printf("Error recording failed two-factor attempt for user %d: %v", userID, err)
+	}
+}
+
+// loginResult finishes a successful first factor login. Suspended users are turned away,
+// users with two-factor authentication get a partial token to exchange at the verify endpoint,
+// and everyone else gets a session.
+func loginResult(store repository.Store, ctx context.Context, user *models.User) (*LoginResult, error) {
+	if isSuspended(user) {
+		return nil, suspendedError(user)
+	}
+
+	if user.TwoFactorEnabled {
+		token, err := utils.GenerateTwoFactorToken(user.ID)
+		if err != nil {
+			return nil, apierror.Internal("Could not generate token")
+		}
+
+		return &LoginResult{
+			TwoFactorRequired: true,
+			TwoFactorToken:    token,
+			ExpiresIn:         int(utils.TwoFactorTokenTTL.Seconds()),
+		}, nil
+	}
+
+	return sessionResult(store, ctx, user)
+}
+
+// sessionResult starts a session for the user and returns its tokens with the user.
+func sessionResult(store repository.Store, ctx context.Context, user *models.User) (*LoginResult, error) {
+	tokens, err := createSession(store, ctx, user.ID)
+	if err != nil {
+		return nil, apierror.Internal("Could not generate token")
+	}
+
+	userResponse := utils.ConvertToUserResponse(user)
+	return &LoginResult{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         &userResponse,
+	}, nil
+}
+
+// checkSecondFactor accepts a TOTP code or a recovery code. A TOTP code is only accepted for a
+// time step later than the last one used, and a recovery code is marked used, so neither can be replayed.
+func checkSecondFactor(store repository.Store, user *models.User, code string) bool {
+	secret, err := utils.DecryptSecret(user.TOTPSecret)
+	if err != nil {
+		log.Printf("Error reading two-factor secret of user %d: %v", user.ID, err)
+		return false
+	}
+
+	if step, ok := utils.ValidateTOTP(secret, code, time.Now()); ok {
+		advanced, err := store.Users().AdvanceTOTPStep(user.ID, step)
+		return err == nil && advanced
+	}
+
+	codes, err := store.RecoveryCodes().Unused(user.ID)
+	if err != nil {
+		return false
+	}
+
+	code = utils.NormalizeRecoveryCode(code)
+	for _, recovery := range codes {
+		if utils.CheckOTPHash(code, recovery.CodeHash) {
+			used, err := store.RecoveryCodes().Use(recovery.ID)
+			return err == nil && used
+		}
+	}
+
+	return false
+}
+
+// replaceRecoveryCodes deletes the user's recovery codes and returns a new set in plaintext.
+func replaceRecoveryCodes(tx repository.Store, userID uint) ([]string, error) {
+	if err := tx.RecoveryCodes().DeleteAll(userID); err != nil {
+		return nil, err
+	}
+
+	codes := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	rows := make([]models.RecoveryCode, len(codes))
+	for i, code := range codes {
+		rows[i] = models.RecoveryCode{UserID: userID, CodeHash: utils.HashOTP(code)}
+	}
+
+	if err := tx.RecoveryCodes().Create(rows); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
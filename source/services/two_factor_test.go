@@ -0,0 +1,111 @@
+package services_test
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"cnep-backend/source/apitest"
+	"cnep-backend/source/services"
+)
+
+// totp returns the code of an authenticator app for the secret at the given time (RFC 6238).
+func totp(t *testing.T, secret string, at time.Time) string {
+	t.Helper()
+
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	msg := make([]byte, 8)
+	binary.BigEndian.PutUint64(msg, uint64(at.Unix()/30))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg)
+	sum := mac.Sum(nil)
+
+	offset := sum[len(sum)-1] & 0x0f
+	return fmt.Sprintf("%06d", (binary.BigEndian.Uint32(sum[offset:])&0x7fffffff)%1000000)
+}
+
+// enableTwoFactor enrolls the user with the code of the current time step.
+// It returns the secret, the code the setup was confirmed with and the recovery codes.
+func enableTwoFactor(t *testing.T, svc *services.Services, userID uint) (string, string, []string) {
+	t.Helper()
+
+	setup, err := svc.TwoFactor.Setup(userID)
+	expectStatus(t, err, 0)
+	code := totp(t, setup.Secret, time.Now())
+	recoveryCodes, err := svc.TwoFactor.Confirm(userID, code)
+	expectStatus(t, err, 0)
+	return setup.Secret, code, recoveryCodes
+}
+
+// twoFactorToken logs the user in with the password and returns the partial token of the second step.
+func twoFactorToken(t *testing.T, svc *services.Services, email string) string {
+	t.Helper()
+
+	result, err := svc.Auth.Login(context.Background(), email, apitest.Password)
+	expectStatus(t, err, 0)
+	if !result.TwoFactorRequired || result.TwoFactorToken == "" || result.Token != "" {
+		t.Fatalf("expected a two-factor token instead of a session, got %+v", result)
+	}
+	return result.TwoFactorToken
+}
+
+func TestTwoFactorLoginRefusesReplayedCodes(t *testing.T) {
+	svc, store, _ := newServices(t)
+	user := createUser(t, store, "user@example.com")
+	secret, confirmed, _ := enableTwoFactor(t, svc, user.ID)
+
+	// The code used to confirm the setup cannot log in again
+	_, err := svc.TwoFactor.VerifyLogin(context.Background(), twoFactorToken(t, svc, user.Email), confirmed)
+	expectStatus(t, err, http.StatusUnauthorized)
+
+	// The code of the next step is accepted once, within the clock skew
+	next := totp(t, secret, time.Now().Add(30*time.Second))
+	result, err := svc.TwoFactor.VerifyLogin(context.Background(), twoFactorToken(t, svc, user.Email), next)
+	expectStatus(t, err, 0)
+	if result.Token == "" || result.RefreshToken == "" {
+		t.Fatalf("expected a session, got %+v", result)
+	}
+	_, err = svc.TwoFactor.VerifyLogin(context.Background(), twoFactorToken(t, svc, user.Email), next)
+	expectStatus(t, err, http.StatusUnauthorized)
+
+	// An earlier step than the last one used is refused even though it is in the window
+	_, err = svc.TwoFactor.VerifyLogin(context.Background(), twoFactorToken(t, svc, user.Email), totp(t, secret, time.Now().Add(-30*time.Second)))
+	expectStatus(t, err, http.StatusUnauthorized)
+}
+
+func TestTwoFactorRecoveryCodes(t *testing.T) {
+	svc, store, _ := newServices(t)
+	user := createUser(t, store, "user@example.com")
+	_, _, recoveryCodes := enableTwoFactor(t, svc, user.ID)
+	if len(recoveryCodes) == 0 {
+		t.Fatal("expected recovery codes")
+	}
+
+	tests := []struct {
+		name   string
+		code   string
+		status int
+	}{
+		{"unknown code", "aaaaa-aaaaa", http.StatusUnauthorized},
+		{"recovery code", recoveryCodes[0], 0},
+		{"used recovery code", recoveryCodes[0], http.StatusUnauthorized},
+		{"code typed without the dash", recoveryCodes[1][:5] + recoveryCodes[1][6:], 0},
+	}
+	// The cases run in order, each depends on the codes used before
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.TwoFactor.VerifyLogin(context.Background(), twoFactorToken(t, svc, user.Email), tt.code)
+			expectStatus(t, err, tt.status)
+		})
+	}
+}
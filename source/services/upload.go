@@ -0,0 +1,264 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"strings"
+
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/consts"
+	"cnep-backend/pkg/lib"
+	"cnep-backend/pkg/utils"
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
+)
+
+// UploadService stores uploaded media, avatars and the images of business pages and products.
+type UploadService struct {
+	store repository.Store
+}
+
+func NewUploadService(store repository.Store) *UploadService {
+	return &UploadService{store: store}
+}
+
+/*
+The UploadMedia method validates an uploaded file for the given purpose and stores it
+in the configured storage backend. Images are stripped of metadata and stored in every
+standard size, videos are stored as uploaded.
+The returned URLs can be stored on the matching model fields, such as the media of a post.
+Business logos, covers and product images are stored on their page or product by
+UploadBusinessImage and AddProductImage instead.
+It returns the URLs of the stored variants and the content type of the stored file.
+*/
+func (s *UploadService) UploadMedia(ctx context.Context, userID uint, purpose string, file *multipart.FileHeader) (*models.ImageVariants, string, error) {
+	variants, contentType, err := storeUpload(ctx, userID, purpose, file)
+	if err != nil {
+		return nil, "", uploadError(err)
+	}
+
+	return &variants, contentType, nil
+}
+
+/*
+The UploadAvatar method stores a new avatar for the user and updates the user profile
+with the full size URL and the URLs of every size variant.
+The previous avatar is removed from storage if it was uploaded through this service.
+*/
+func (s *UploadService) UploadAvatar(ctx context.Context, userID uint, file *multipart.FileHeader) (*models.ImageVariants, error) {
+	user, err := s.store.Users().Get(userID)
+	if err != nil {
+		return nil, apierror.NotFound("User not found")
+	}
+
+	variants, _, err := storeUpload(ctx, userID, consts.UPLOAD_PURPOSE_AVATAR, file)
+	if err != nil {
+		return nil, uploadError(err)
+	}
+
+	if err := s.store.Users().Update(userID, map[string]interface{}{
+		"avatar":          variants.Full,
+		"avatar_variants": variants,
+	}); err != nil {
+		deleteStored(ctx, variants.URLs())
+		return nil, apierror.Internal("Could not update avatar")
+	}
+
+	deleteStored(ctx, append(user.AvatarVariants.URLs(), user.Avatar))
+
+	return &variants, nil
+}
+
+// maxProductImages is the number of images a product can have.
+const maxProductImages = 10
+
+/*
+The UploadBusinessImage method stores a new logo or cover image, as given by purpose, for a
+business page of the user and updates the page with the full size URL and the URLs of every size variant.
+The previous image is removed from storage if it was uploaded through this service.
+Pages of other users are reported as not found.
+*/
+func (s *UploadService) UploadBusinessImage(ctx context.Context, userID, pageID uint, purpose string, file *multipart.FileHeader) (*models.ImageVariants, error) {
+	var column string
+	switch purpose {
+	case consts.UPLOAD_PURPOSE_LOGO:
+		column = "logo"
+	case consts.UPLOAD_PURPOSE_COVER:
+		column = "cover_image"
+	default:
+		return nil, apierror.BadRequest("Invalid upload purpose")
+	}
+
+	page, err := s.store.Businesses().GetPage(pageID)
+	if err != nil || page.OwnerID != userID {
+		return nil, apierror.NotFound("Business page not found")
+	}
+
+	variants, _, err := storeUpload(ctx, userID, purpose, file)
+	if err != nil {
+		return nil, uploadError(err)
+	}
+
+	if err := s.store.Businesses().UpdatePage(pageID, map[string]interface{}{
+		column:               variants.Full,
+		column + "_variants": variants,
+	}); err != nil {
+		deleteStored(ctx, variants.URLs())
+		return nil, apierror.Internal("Could not update business page")
+	}
+
+	previous, previousVariants := page.Logo, page.LogoVariants
+	if purpose == consts.UPLOAD_PURPOSE_COVER {
+		previous, previousVariants = page.CoverImage, page.CoverImageVariants
+	}
+	deleteStored(ctx, append(previousVariants.URLs(), previous))
+
+	return &variants, nil
+}
+
+/*
+The AddProductImage method stores a new image for a product of a business page of the user
+and appends its full size URL and the URLs of every size variant to the product.
+Products of other users are reported as not found.
+A product has at most maxProductImages images.
+*/
+func (s *UploadService) AddProductImage(ctx context.Context, userID, productID uint, file *multipart.FileHeader) (*models.ImageVariants, error) {
+	product, err := s.store.Businesses().GetProduct(productID)
+	if err != nil {
+		return nil, apierror.NotFound("Product not found")
+	}
+	page, err := s.store.Businesses().GetPage(product.BusinessPageID)
+	if err != nil || page.OwnerID != userID {
+		return nil, apierror.NotFound("Product not found")
+	}
+	if len(product.Images) >= maxProductImages {
+		return nil, apierror.BadRequest(fmt.Sprintf("A product can have at most %d images", maxProductImages))
+	}
+
+	variants, _, err := storeUpload(ctx, userID, consts.UPLOAD_PURPOSE_PRODUCT, file)
+	if err != nil {
+		return nil, uploadError(err)
+	}
+
+	added, err := s.store.Businesses().AddProductImage(productID, variants.Full, variants, maxProductImages)
+	if err != nil || !added {
+		deleteStored(ctx, variants.URLs())
+		if err == nil {
+			// Another upload took the last place while this one was stored
+			return nil, apierror.BadRequest(fmt.Sprintf("A product can have at most %d images", maxProductImages))
+		}
+		return nil, apierror.Internal("Could not update product")
+	}
+
+	return &variants, nil
+}
+
+// storeUpload validates the file against the rule for purpose and writes it to storage.
+// Images are processed into every size variant, other media is stored as the full variant only.
+func storeUpload(ctx context.Context, userID uint, purpose string, file *multipart.FileHeader) (models.ImageVariants, string, error) {
+	var variants models.ImageVariants
+
+	rule, ok := utils.UploadRules[purpose]
+	if !ok {
+		return variants, "", apierror.BadRequest("Invalid upload purpose")
+	}
+
+	if file.Size > rule.MaxSize {
+		return variants, "", utils.ErrFileTooLarge
+	}
+
+	f, err := file.Open()
+	if err != nil {
+		return variants, "", apierror.BadRequest("Could not read file")
+	}
+	defer f.Close()
+
+	// Never trust the declared size, read at most one byte over the limit
+	data, err := io.ReadAll(io.LimitReader(f, rule.MaxSize+1))
+	if err != nil {
+		return variants, "", apierror.BadRequest("Could not read file")
+	}
+
+	contentType, ext, err := utils.InspectUpload(data, rule)
+	if err != nil {
+		return variants, "", err
+	}
+
+	name := fmt.Sprintf("%s/%d/%s", purpose, userID, randomName())
+
+	if !strings.HasPrefix(contentType, "image/") {
+		url, err := lib.GetStorage().Put(ctx, name+ext, bytes.NewReader(data), int64(len(data)), contentType)
+		if err != nil {
+			log.Printf("Error storing upload %s: %v", name+ext, err)
+			return variants, "", apierror.Internal("Could not store file")
+		}
+		variants.Full = url
+		return variants, contentType, nil
+	}
+
+	images, err := utils.ProcessImage(data, contentType)
+	if err != nil {
+		return variants, "", utils.ErrUnsupportedType
+	}
+
+	for _, img := range images {
+		key := name + "_" + img.Variant + img.Ext
+		url, err := lib.GetStorage().Put(ctx, key, bytes.NewReader(img.Data), int64(len(img.Data)), img.ContentType)
+		if err != nil {
+			log.Printf("Error storing upload %s: %v", key, err)
+			deleteStored(ctx, variants.URLs())
+			return models.ImageVariants{}, "", apierror.Internal("Could not store file")
+		}
+		variants.Set(img.Variant, url)
+		contentType = img.ContentType
+	}
+
+	return variants, contentType, nil
+}
+
+// deleteStored removes the given URLs from storage, skipping any that were not uploaded here.
+func deleteStored(ctx context.Context, urls []string) {
+	seen := make(map[string]bool)
+	for _, url := range urls {
+		key, ok := lib.KeyFromURL(url)
+		if !ok || url == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		if err := lib.GetStorage().Delete(ctx, key); err != nil {
+			log.Printf("Error deleting stored file %s: %v", key, err)
+		}
+	}
+}
+
+// uploadError maps the validation errors of an upload to API errors.
+func uploadError(err error) error {
+	switch err {
+	case utils.ErrFileTooLarge:
+		return apierror.PayloadTooLarge("File too large")
+	case utils.ErrUnsupportedType:
+		return apierror.UnsupportedMediaType("Unsupported file type")
+	case utils.ErrInvalidDimensions:
+		return apierror.BadRequest("Invalid image dimensions")
+	}
+
+	if apiErr, ok := err.(*apierror.Error); ok {
+		return apiErr
+	}
+	return apierror.Internal("Internal Server Error")
+}
+
+func randomName() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		log.Panic("crypto/rand failed: ", err)
+	}
+	return hex.EncodeToString(b)
+}
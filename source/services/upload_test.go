@@ -0,0 +1,225 @@
+package services_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"cnep-backend/pkg/consts"
+	"cnep-backend/pkg/lib"
+	"cnep-backend/source/config"
+	"cnep-backend/source/models"
+)
+
+// useLocalStorage stores uploads in a temporary directory and returns it.
+func useLocalStorage(t *testing.T) string {
+	t.Helper()
+
+	root := t.TempDir()
+	if err := lib.InitStorage(&config.Config{StorageDriver: "local", StoragePath: root, StorageBaseURL: "/uploads"}); err != nil {
+		t.Fatal(err)
+	}
+	return root
+}
+
+// stored reports whether the file behind an upload URL exists in the storage root.
+func stored(t *testing.T, root, url string) bool {
+	t.Helper()
+
+	key, ok := lib.KeyFromURL(url)
+	if !ok {
+		t.Fatalf("expected an upload URL, got %q", url)
+	}
+	_, err := os.Stat(filepath.Join(root, filepath.FromSlash(key)))
+	return err == nil
+}
+
+// pngUpload returns a multipart file holding a w by h PNG.
+func pngUpload(t *testing.T, w, h int) *multipart.FileHeader {
+	t.Helper()
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, err := writer.CreateFormFile("file", "image.png")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := png.Encode(part, image.NewNRGBA(image.Rect(0, 0, w, h))); err != nil {
+		t.Fatal(err)
+	}
+	writer.Close()
+
+	form, err := multipart.NewReader(&body, writer.Boundary()).ReadForm(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { form.RemoveAll() })
+	return form.File["file"][0]
+}
+
+// expectVariants fails the test unless every size variant of the image was stored.
+func expectVariants(t *testing.T, root string, variants models.ImageVariants) {
+	t.Helper()
+
+	if len(variants.URLs()) != 3 {
+		t.Fatalf("expected three variants, got %+v", variants)
+	}
+	for _, url := range variants.URLs() {
+		if !stored(t, root, url) {
+			t.Fatalf("expected %s to be stored", url)
+		}
+	}
+}
+
+func TestUploadBusinessImage(t *testing.T) {
+	tests := []struct {
+		purpose string
+		// the URL and the variants stored on the page
+		url      func(page *models.BusinessPage) string
+		variants func(page *models.BusinessPage) models.ImageVariants
+	}{
+		{consts.UPLOAD_PURPOSE_LOGO,
+			func(page *models.BusinessPage) string { return page.Logo },
+			func(page *models.BusinessPage) models.ImageVariants { return page.LogoVariants }},
+		{consts.UPLOAD_PURPOSE_COVER,
+			func(page *models.BusinessPage) string { return page.CoverImage },
+			func(page *models.BusinessPage) models.ImageVariants { return page.CoverImageVariants }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.purpose, func(t *testing.T) {
+			svc, store, _ := newServices(t)
+			root := useLocalStorage(t)
+			owner := createUser(t, store, "owner@example.com")
+			other := createUser(t, store, "other@example.com")
+
+			page := &models.BusinessPage{OwnerID: owner.ID, Name: "Bakery"}
+			if err := store.Businesses().CreatePage(page); err != nil {
+				t.Fatal(err)
+			}
+
+			first, err := svc.Uploads.UploadBusinessImage(context.Background(), owner.ID, page.ID, tt.purpose, pngUpload(t, 400, 200))
+			expectStatus(t, err, 0)
+			expectVariants(t, root, *first)
+
+			second, err := svc.Uploads.UploadBusinessImage(context.Background(), owner.ID, page.ID, tt.purpose, pngUpload(t, 400, 200))
+			expectStatus(t, err, 0)
+			expectVariants(t, root, *second)
+
+			saved, err := store.Businesses().GetPage(page.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.url(saved) != second.Full || tt.variants(saved) != *second {
+				t.Fatalf("expected the page to hold the new image %+v, got %q %+v", *second, tt.url(saved), tt.variants(saved))
+			}
+			// The replaced image is removed from storage
+			for _, url := range first.URLs() {
+				if stored(t, root, url) {
+					t.Fatalf("expected %s to be deleted", url)
+				}
+			}
+
+			_, err = svc.Uploads.UploadBusinessImage(context.Background(), other.ID, page.ID, tt.purpose, pngUpload(t, 400, 200))
+			expectStatus(t, err, http.StatusNotFound)
+			_, err = svc.Uploads.UploadBusinessImage(context.Background(), owner.ID, page.ID+100, tt.purpose, pngUpload(t, 400, 200))
+			expectStatus(t, err, http.StatusNotFound)
+		})
+	}
+
+	svc, store, _ := newServices(t)
+	useLocalStorage(t)
+	owner := createUser(t, store, "owner@example.com")
+	page := &models.BusinessPage{OwnerID: owner.ID, Name: "Bakery"}
+	if err := store.Businesses().CreatePage(page); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := svc.Uploads.UploadBusinessImage(context.Background(), owner.ID, page.ID, consts.UPLOAD_PURPOSE_AVATAR, pngUpload(t, 400, 200))
+	expectStatus(t, err, http.StatusBadRequest)
+	// A cover must be at least 400 by 150
+	_, err = svc.Uploads.UploadBusinessImage(context.Background(), owner.ID, page.ID, consts.UPLOAD_PURPOSE_COVER, pngUpload(t, 100, 100))
+	expectStatus(t, err, http.StatusBadRequest)
+}
+
+func TestAddProductImage(t *testing.T) {
+	svc, store, _ := newServices(t)
+	root := useLocalStorage(t)
+	owner := createUser(t, store, "owner@example.com")
+	other := createUser(t, store, "other@example.com")
+
+	page := &models.BusinessPage{OwnerID: owner.ID, Name: "Bakery"}
+	if err := store.Businesses().CreatePage(page); err != nil {
+		t.Fatal(err)
+	}
+	product := &models.Product{BusinessPageID: page.ID, Name: "Bread"}
+	if err := store.Businesses().CreateProduct(product); err != nil {
+		t.Fatal(err)
+	}
+
+	var added []models.ImageVariants
+	for range 10 {
+		variants, err := svc.Uploads.AddProductImage(context.Background(), owner.ID, product.ID, pngUpload(t, 200, 200))
+		expectStatus(t, err, 0)
+		expectVariants(t, root, *variants)
+		added = append(added, *variants)
+	}
+
+	saved, err := store.Businesses().GetProduct(product.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(saved.Images) != len(added) || len(saved.ImageVariants) != len(added) {
+		t.Fatalf("expected %d images, got %d with %d variants", len(added), len(saved.Images), len(saved.ImageVariants))
+	}
+	for i, variants := range added {
+		if saved.Images[i] != variants.Full || saved.ImageVariants[i] != variants {
+			t.Fatalf("image %d: expected %+v, got %q %+v", i, variants, saved.Images[i], saved.ImageVariants[i])
+		}
+	}
+
+	// The eleventh image is refused before it is stored
+	files, _ := filepath.Glob(filepath.Join(root, consts.UPLOAD_PURPOSE_PRODUCT, "*", "*"))
+	_, err = svc.Uploads.AddProductImage(context.Background(), owner.ID, product.ID, pngUpload(t, 200, 200))
+	expectStatus(t, err, http.StatusBadRequest)
+	if after, _ := filepath.Glob(filepath.Join(root, consts.UPLOAD_PURPOSE_PRODUCT, "*", "*")); len(after) != len(files) {
+		t.Fatalf("expected no file to be stored, got %s", strings.Join(after, ", "))
+	}
+
+	_, err = svc.Uploads.AddProductImage(context.Background(), other.ID, product.ID, pngUpload(t, 200, 200))
+	expectStatus(t, err, http.StatusNotFound)
+	_, err = svc.Uploads.AddProductImage(context.Background(), owner.ID, product.ID+100, pngUpload(t, 200, 200))
+	expectStatus(t, err, http.StatusNotFound)
+}
+
+func TestHiddenBusinessPageTakesNoUploads(t *testing.T) {
+	svc, store, _ := newServices(t)
+	useLocalStorage(t)
+	owner := createUser(t, store, "owner@example.com")
+
+	page := &models.BusinessPage{OwnerID: owner.ID, Name: "Bakery"}
+	if err := store.Businesses().CreatePage(page); err != nil {
+		t.Fatal(err)
+	}
+	product := &models.Product{BusinessPageID: page.ID, Name: "Bread"}
+	if err := store.Businesses().CreateProduct(product); err != nil {
+		t.Fatal(err)
+	}
+
+	// Hidden by a moderator, the page and its products are gone even for the owner
+	if err := store.Businesses().UpdatePage(page.ID, map[string]interface{}{"hidden_at": time.Now()}); err != nil {
+		t.Fatal(err)
+	}
+	_, err := svc.Uploads.UploadBusinessImage(context.Background(), owner.ID, page.ID, consts.UPLOAD_PURPOSE_LOGO, pngUpload(t, 400, 200))
+	expectStatus(t, err, http.StatusNotFound)
+	_, err = svc.Uploads.AddProductImage(context.Background(), owner.ID, product.ID, pngUpload(t, 200, 200))
+	expectStatus(t, err, http.StatusNotFound)
+}
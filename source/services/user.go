@@ -1,60 +1,74 @@
 package services
 
 import (
-	"cnep-backend/pkg/consts"
-	"cnep-backend/source/database"
+	"cnep-backend/pkg/apierror"
+	"cnep-backend/pkg/template"
 	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
 
-	"github.com/gofiber/fiber/v2"
+	"errors"
 	"github.com/lib/pq"
-	"gorm.io/gorm"
-	"log"
 )
 
+// UserService reads and updates user profiles and their privacy settings.
+type UserService struct {
+	store repository.Store
+}
+
+func NewUserService(store repository.Store) *UserService {
+	return &UserService{store: store}
+}
+
 /*
-The GetUserProfileByID function fetches the user profile for the specified user ID from the database.
-It takes the user ID as a parameter and returns a pointer to a UserResponse struct.
-If the user is not found or any other error occurs, it returns an appropriate error message.
+The GetProfile method fetches the user profile for the specified user ID from the database.
+It takes the ID of the viewing user and the user ID as parameters and returns a pointer to a UserResponse struct.
+If the user is not found, or either user has blocked the other, it returns a not found error.
+The email, phone and address are left out when the privacy settings of the user hide them from the viewer.
 */
-func GetUserProfileByID(userId uint) (*models.UserResponse, error) {
-	var user models.UserResponse
-	if database.DB == nil {
-		log.Fatal("Database not connected")
-		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database not connected")
+func (s *UserService) GetProfile(viewerID, userId uint) (*models.UserResponse, error) {
+	if viewerID != userId {
+		blocked, err := s.store.Blocks().Between(viewerID, userId)
+		if err != nil {
+			return nil, apierror.Internal("Failed to fetch user profile")
+		}
+		if blocked {
+			return nil, apierror.NotFound("User not found")
+		}
 	}
 
-	if err := database.DB.Table(consts.USERS_TABLE).First(&user, userId).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fiber.NewError(fiber.StatusNotFound, "User not found")
+	user, err := s.store.Users().Profile(userId)
+	if err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apierror.NotFound("User not found")
 		}
-		return nil, fiber.NewError(fiber.StatusInternalServerError, "Failed to fetch user profile")
+		return nil, apierror.Internal("Failed to fetch user profile")
+	}
+
+	if err := filterProfiles(s.store, viewerID, user); err != nil {
+		return nil, apierror.Internal("Failed to fetch user profile")
 	}
-	return &user, nil
+	return user, nil
 }
 
 /*
-The UpdateUserProfile function updates the user profile for the specified user ID in the database.
-It takes the user ID, the updated data, and the database connection as parameters.
+The UpdateProfile method updates the user profile for the specified user ID in the database.
+It takes the user ID and the updated data as parameters.
 If the user is not found or any other error occurs, it returns an appropriate error message.
 The function returns a JSON response with the updated user profile.
 */
-func UpdateUserProfile(userId uint, updateData map[string]interface{}) (*models.UserResponse, error) {
-	var user models.User
-	var userResponse models.UserResponse
-	if database.DB == nil {
-		log.Fatal("Database not connected")
-		return nil, fiber.NewError(fiber.StatusInternalServerError, "Database not connected")
-	}
-
+func (s *UserService) UpdateProfile(userId uint, updateData map[string]interface{}) (*models.UserResponse, error) {
 	// Define allowed fields for update
 	allowedFields := map[string]bool{
-		"name":                true,
-		"phone":               true,
-		"address":             true,
-		"designation":         true,
-		"rating":              false,
-		"badges":              false,
-		"topics":              false,
+		"name":        true,
+		"phone":       true,
+		"address":     true,
+		"designation": true,
+		"locale":      true,
+		"rating":      false,
+		"badges":      false,
+		"topics":      true,
+		"latitude":    true,
+		"longitude":   true,
 	}
 
 	// Filter out non-allowed fields and validate data
@@ -66,13 +80,29 @@ func UpdateUserProfile(userId uint, updateData map[string]interface{}) (*models.
 				if str, ok := value.(string); ok && str != "" {
 					filteredData[key] = str
 				}
+			case "locale":
+				str, ok := value.(string)
+				if !ok || !template.HasLocale(str) {
+					return nil, apierror.BadRequest("Unsupported locale")
+				}
+				filteredData[key] = str
 			case "rating":
 				if rating, ok := value.(float64); ok {
 					if rating > 5 || rating < 1 {
-						return nil, fiber.NewError(fiber.StatusBadRequest, "Rating must be between 1 and 5")
+						return nil, apierror.BadRequest("Rating must be between 1 and 5")
 					}
 					filteredData[key] = float32(rating)
 				}
+			case "latitude", "longitude":
+				limit := 90.0
+				if key == "longitude" {
+					limit = 180
+				}
+				coordinate, ok := value.(float64)
+				if !ok || coordinate < -limit || coordinate > limit {
+					return nil, apierror.BadRequest("Invalid location")
+				}
+				filteredData[key] = coordinate
 			case "badges", "topics":
 				if values, ok := value.([]interface{}); ok {
 					// Convert []interface{} to []int64
@@ -81,7 +111,16 @@ func UpdateUserProfile(userId uint, updateData map[string]interface{}) (*models.
 						if intBadge, ok := badge.(float64); ok {
 							intValues[i] = int64(intBadge)
 						} else {
-							return nil, fiber.NewError(fiber.StatusBadRequest, "Invalid badge or topic type")
+							return nil, apierror.BadRequest("Invalid badge or topic type")
+						}
+					}
+					if key == "topics" {
+						exist, err := s.store.Topics().Exist(intValues)
+						if err != nil {
+							return nil, apierror.Internal("Error checking topics")
+						}
+						if !exist {
+							return nil, apierror.BadRequest("Unknown topic")
 						}
 					}
 					filteredData[key] = pq.Int64Array(intValues)
@@ -92,27 +131,22 @@ func UpdateUserProfile(userId uint, updateData map[string]interface{}) (*models.
 
 	// Check if there are any valid fields to update
 	if len(filteredData) == 0 {
-		return nil, fiber.NewError(fiber.StatusBadRequest, "No valid fields to update")
-	}
-
-	if err := database.DB.Table(consts.USERS_TABLE).First(&user, userId).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
-			return nil, fiber.NewError(fiber.StatusNotFound, "User not found")
-		}
-		return nil, fiber.NewError(fiber.StatusInternalServerError, "Error fetching user profile")
+		return nil, apierror.BadRequest("No valid fields to update")
 	}
 
 	// Update only the allowed fields
-	if err := database.DB.Table(consts.USERS_TABLE).Model(&user).
-		Updates(filteredData).Error; err != nil {
-		return nil, fiber.NewError(fiber.StatusInternalServerError, "Error updating user profile")
+	if err := s.store.Users().Update(userId, filteredData); err != nil {
+		if errors.Is(err, repository.ErrNotFound) {
+			return nil, apierror.NotFound("User not found")
+		}
+		return nil, apierror.Internal("Error updating user profile")
 	}
 
 	// Fetch the updated user from the database
-	if err := database.DB.Table(consts.USERS_TABLE).
-		First(&userResponse, userId).Error; err != nil {
-		return nil, fiber.NewError(fiber.StatusInternalServerError, "Error fetching updated user profile")
+	userResponse, err := s.store.Users().Profile(userId)
+	if err != nil {
+		return nil, apierror.Internal("Error fetching updated user profile")
 	}
 
-	return &userResponse, nil
+	return userResponse, nil
 }
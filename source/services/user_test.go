@@ -0,0 +1,100 @@
+package services_test
+
+import (
+	"net/http"
+	"testing"
+
+	"cnep-backend/pkg/consts"
+	"cnep-backend/source/models"
+)
+
+func TestGetProfileHidesPrivateFields(t *testing.T) {
+	tests := []struct {
+		name   string
+		viewer string
+		// fields the viewer sees
+		email, private bool
+	}{
+		{"self", "user@example.com", true, true},
+		{"partner", "partner@example.com", true, false},
+		{"stranger", "stranger@example.com", false, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, store, _ := newServices(t)
+			user := createUser(t, store, "user@example.com")
+			partner := createUser(t, store, "partner@example.com")
+			createUser(t, store, "stranger@example.com")
+
+			latitude := 52.37
+			if err := store.Users().Update(user.ID, map[string]interface{}{
+				"locale":             "nl",
+				"two_factor_enabled": true,
+				"latitude":           latitude,
+				"longitude":          latitude,
+			}); err != nil {
+				t.Fatal(err)
+			}
+			if err := store.Partners().Create(&models.Partner{SenderID: user.ID, ReceiverID: partner.ID, Status: consts.PARTNER_STATUS_ACCEPTED}); err != nil {
+				t.Fatal(err)
+			}
+
+			viewer, err := store.Users().GetByEmail(tt.viewer)
+			if err != nil {
+				t.Fatal(err)
+			}
+			profile, err := svc.Users.GetProfile(viewer.ID, user.ID)
+			expectStatus(t, err, 0)
+
+			if (profile.Email != "") != tt.email {
+				t.Fatalf("expected the email to be shown: %v, got %q", tt.email, profile.Email)
+			}
+			shown := profile.Locale != "" || profile.Role != "" || profile.TwoFactorEnabled || profile.Latitude != nil || profile.Longitude != nil
+			hidden := profile.Locale == "" || profile.Role == "" || !profile.TwoFactorEnabled || profile.Latitude == nil || profile.Longitude == nil
+			if (tt.private && hidden) || (!tt.private && shown) {
+				t.Fatalf("expected the locale, role, two-factor state and location to be shown: %v, got %+v", tt.private, profile)
+			}
+		})
+	}
+}
+
+func TestUpdateProfileValidatesTopicsAndLocation(t *testing.T) {
+	svc, store, _ := newServices(t)
+	user := createUser(t, store, "user@example.com")
+
+	topic := &models.Topic{Title: "Education"}
+	if err := store.Topics().Create(topic); err != nil {
+		t.Fatal(err)
+	}
+	known := float64(topic.ID)
+
+	tests := []struct {
+		name   string
+		update map[string]interface{}
+		status int
+	}{
+		{"known topic", map[string]interface{}{"topics": []interface{}{known, known}}, 0},
+		{"unknown topic", map[string]interface{}{"topics": []interface{}{known, known + 100}}, http.StatusBadRequest},
+		{"location", map[string]interface{}{"latitude": -90.0, "longitude": 180.0}, 0},
+		{"latitude out of range", map[string]interface{}{"latitude": 90.5}, http.StatusBadRequest},
+		{"longitude out of range", map[string]interface{}{"longitude": -180.5}, http.StatusBadRequest},
+		{"location that is not a number", map[string]interface{}{"latitude": "52.37"}, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := svc.Users.UpdateProfile(user.ID, tt.update)
+			expectStatus(t, err, tt.status)
+		})
+	}
+
+	// Refused updates change nothing
+	saved, _ := store.User(user.ID)
+	if len(saved.Topics) != 2 || saved.Topics[0] != int64(topic.ID) {
+		t.Fatalf("expected the known topic to be stored, got %v", saved.Topics)
+	}
+	if saved.Latitude == nil || *saved.Latitude != -90 || saved.Longitude == nil || *saved.Longitude != 180 {
+		t.Fatalf("expected the location at the limits to be stored, got %v, %v", saved.Latitude, saved.Longitude)
+	}
+}
@@ -6,8 +6,9 @@ import (
 	"sync"
 
 	"cnep-backend/source/models"
+	"cnep-backend/source/repository"
+	"cnep-backend/source/services"
 	"github.com/gofiber/websocket/v2"
-	"gorm.io/gorm"
 )
 
 type Client struct {
@@ -26,17 +27,19 @@ type Hub struct {
 	register   chan *Client
 	unregister chan *Client
 	broadcast  chan *Message
-	db         *gorm.DB
+	messages   repository.MessageRepository
+	blocks     *services.BlockService
 	mu         sync.RWMutex
 }
 
-func NewHub(db *gorm.DB) *Hub {
+func NewHub(messages repository.MessageRepository, blocks *services.BlockService) *Hub {
 	return &Hub{
 		clients:    make(map[uint]*Client),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		broadcast:  make(chan *Message),
-		db:         db,
+		messages:   messages,
+		blocks:     blocks,
 	}
 }
 
@@ -78,7 +81,7 @@ func (h *Hub) handleMessage(message *Message) {
 			ReceiverID: message.ReceiverID,
 			Content:    message.Content,
 		}
-		if err := h.db.Create(&dbMessage).Error; err != nil {
+		if err := h.messages.Create(&dbMessage); err != nil {
 			log.Printf("Error saving message to database: %v", err)
 		}
 	}()
@@ -111,6 +114,17 @@ func (h *Hub) HandleWebSocket(c *websocket.Conn) {
 			}
 
 			message.SenderID = userID
+
+			// Drop messages between users who blocked each other, without telling the sender
+			blocked, err := h.blocks.IsBlocked(message.SenderID, message.ReceiverID)
+			if err != nil {
+				log.Printf("Error checking block between users %d and %d: %v", message.SenderID, message.ReceiverID, err)
+				continue
+			}
+			if blocked {
+				continue
+			}
+
 			h.broadcast <- &message
 		}
 	}
@@ -0,0 +1,114 @@
+package websocket
+
+import (
+	"net"
+	"strconv"
+	"testing"
+	"time"
+
+	"cnep-backend/source/models"
+	"cnep-backend/source/repository/repotest"
+	"cnep-backend/source/services"
+
+	client "github.com/fasthttp/websocket"
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/websocket/v2"
+)
+
+// serveHub serves the hub on a local port, authenticating connections by the user query
+// parameter, and returns the address to dial.
+func serveHub(t *testing.T, hub *Hub) string {
+	t.Helper()
+
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	app.Get("/ws", func(c *fiber.Ctx) error {
+		id, err := strconv.Atoi(c.Query("user"))
+		if err != nil {
+			return fiber.ErrUnauthorized
+		}
+		c.Locals("userID", uint(id))
+		return c.Next()
+	}, websocket.New(hub.HandleWebSocket))
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	go app.Listener(ln)
+	t.Cleanup(func() { app.Shutdown() })
+	return "ws://" + ln.Addr().String() + "/ws"
+}
+
+// connect opens a connection to the hub as the given user.
+func connect(t *testing.T, url string, userID uint) *client.Conn {
+	t.Helper()
+
+	conn, _, err := client.DefaultDialer.Dial(url+"?user="+strconv.Itoa(int(userID)), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// receive reads the next message sent to the connection.
+func receive(t *testing.T, conn *client.Conn) Message {
+	t.Helper()
+
+	var message Message
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	if err := conn.ReadJSON(&message); err != nil {
+		t.Fatal(err)
+	}
+	return message
+}
+
+func TestMessagesBetweenBlockedUsersAreDropped(t *testing.T) {
+	store := repotest.New()
+	svc := services.New(store)
+
+	alice := models.User{Email: "alice@example.com", Username: "alice"}
+	bob := models.User{Email: "bob@example.com", Username: "bob"}
+	carol := models.User{Email: "carol@example.com", Username: "carol"}
+	for _, user := range []*models.User{&alice, &bob, &carol} {
+		if err := store.Users().Create(user); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := svc.Blocks.Block(bob.ID, alice.ID); err != nil {
+		t.Fatal(err)
+	}
+
+	hub := NewHub(store.Messages(), svc.Blocks)
+	go hub.Run()
+	url := serveHub(t, hub)
+
+	aliceConn := connect(t, url, alice.ID)
+	bobConn := connect(t, url, bob.ID)
+	carolConn := connect(t, url, carol.ID)
+	// Wait until every user is registered, so no message misses its receiver
+	for _, conn := range []*client.Conn{aliceConn, bobConn, carolConn} {
+		if err := conn.WriteJSON(Message{ReceiverID: carol.ID, Content: "ready"}); err != nil {
+			t.Fatal(err)
+		}
+		receive(t, carolConn)
+	}
+
+	if err := aliceConn.WriteJSON(Message{ReceiverID: bob.ID, Content: "blocked"}); err != nil {
+		t.Fatal(err)
+	}
+	// Alice's messages are handled in order, so once Carol has the next one the first was dropped
+	if err := aliceConn.WriteJSON(Message{ReceiverID: carol.ID, Content: "after"}); err != nil {
+		t.Fatal(err)
+	}
+	if message := receive(t, carolConn); message.Content != "after" {
+		t.Fatalf("expected Carol to receive Alice's message, got %+v", message)
+	}
+
+	if err := carolConn.WriteJSON(Message{ReceiverID: bob.ID, Content: "not blocked"}); err != nil {
+		t.Fatal(err)
+	}
+	if message := receive(t, bobConn); message.SenderID != carol.ID || message.Content != "not blocked" {
+		t.Fatalf("expected Bob to receive only Carol's message, got %+v", message)
+	}
+}